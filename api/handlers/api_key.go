@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"vdt-dashboard-backend/api/middleware"
+	"vdt-dashboard-backend/models"
+	"vdt-dashboard-backend/services"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// APIKeyHandler handles API key HTTP requests
+type APIKeyHandler struct {
+	apiKeyService services.APIKeyService
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(apiKeyService services.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+	}
+}
+
+// CreateAPIKey handles POST /user/api-keys
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	var request models.CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindingError(c, err, "Invalid request data")
+		return
+	}
+
+	apiKey, err := h.apiKeyService.CreateAPIKey(userID, request)
+	if err != nil {
+		respondServiceError(c, err, "Failed to create API key")
+		return
+	}
+
+	c.JSON(http.StatusCreated, models.SuccessResponse("API key created, store it now as it can't be shown again", apiKey))
+}
+
+// ListAPIKeys handles GET /user/api-keys
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	apiKeys, err := h.apiKeyService.ListAPIKeys(userID)
+	if err != nil {
+		respondServiceError(c, err, "Failed to list API keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("API keys retrieved successfully", apiKeys))
+}
+
+// RevokeAPIKey handles DELETE /user/api-keys/:id
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid API key ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(id, userID); err != nil {
+		if errors.Is(err, services.ErrNotFound) {
+			c.JSON(http.StatusNotFound, middleware.ErrorResponse(c, "API key not found", models.ErrAPIKeyNotFound, err.Error()))
+			return
+		}
+		respondServiceError(c, err, "Failed to revoke API key")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("API key revoked successfully", gin.H{"id": id}))
+}
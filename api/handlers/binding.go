@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"vdt-dashboard-backend/api/middleware"
+	"vdt-dashboard-backend/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+)
+
+func init() {
+	// Report fields by the name clients send, not the Go struct field name
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(field reflect.StructField) string {
+			for _, tag := range []string{"json", "form"} {
+				name := strings.Split(field.Tag.Get(tag), ",")[0]
+				if name == "-" {
+					return ""
+				}
+				if name != "" {
+					return name
+				}
+			}
+			return field.Name
+		})
+	}
+}
+
+// respondBindingError responds with 400 and the per-field errors of a failed
+// request binding
+func respondBindingError(c *gin.Context, err error, message string) {
+	fieldErrors := bindingErrors(err)
+
+	code := models.ErrValidation
+	if len(fieldErrors) == 1 && fieldErrors[0].Code == models.ErrInvalidJSON {
+		code = models.ErrInvalidJSON
+	}
+
+	response := middleware.ErrorResponse(c, message, code, fmt.Sprintf("%d field(s) failed validation", len(fieldErrors)))
+	response.Data = models.ValidationResult{Valid: false, Errors: fieldErrors}
+	c.JSON(http.StatusBadRequest, response)
+}
+
+// bindingErrors converts a binding error into validation errors with the
+// field path, a readable message and a code
+func bindingErrors(err error) []models.ValidationError {
+	var validationErrs validator.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		result := make([]models.ValidationError, 0, len(validationErrs))
+		for _, fieldErr := range validationErrs {
+			result = append(result, fieldValidationError(fieldErr))
+		}
+		return result
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return []models.ValidationError{{
+			Field:   typeErr.Field,
+			Message: fmt.Sprintf("must be of type %s", typeErr.Type),
+			Code:    "INVALID_TYPE",
+		}}
+	}
+
+	var syntaxErr *json.SyntaxError
+	if errors.As(err, &syntaxErr) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return []models.ValidationError{{Field: "body", Message: "must be valid JSON", Code: models.ErrInvalidJSON}}
+	}
+
+	return []models.ValidationError{{Field: "request", Message: err.Error(), Code: models.ErrValidation}}
+}
+
+// fieldValidationError describes a single failed validation rule
+func fieldValidationError(fieldErr validator.FieldError) models.ValidationError {
+	// Drop the struct name, e.g. "CreateSchemaRequest.tables[0].name"
+	field := fieldErr.Namespace()
+	if i := strings.Index(field, "."); i >= 0 {
+		field = field[i+1:]
+	}
+
+	countable := fieldErr.Kind() == reflect.Slice || fieldErr.Kind() == reflect.Map
+	unit := "characters"
+	if countable {
+		unit = "items"
+	}
+
+	switch fieldErr.Tag() {
+	case "required":
+		return models.ValidationError{Field: field, Message: "is required", Code: models.ErrMissingRequiredField}
+	case "min":
+		if fieldErr.Kind() == reflect.String || countable {
+			return models.ValidationError{Field: field, Message: fmt.Sprintf("must contain at least %s %s", fieldErr.Param(), unit), Code: "TOO_SHORT"}
+		}
+		return models.ValidationError{Field: field, Message: fmt.Sprintf("must be at least %s", fieldErr.Param()), Code: "TOO_SMALL"}
+	case "max":
+		if fieldErr.Kind() == reflect.String || countable {
+			return models.ValidationError{Field: field, Message: fmt.Sprintf("must contain at most %s %s", fieldErr.Param(), unit), Code: "TOO_LONG"}
+		}
+		return models.ValidationError{Field: field, Message: fmt.Sprintf("must be at most %s", fieldErr.Param()), Code: "TOO_LARGE"}
+	case "oneof":
+		return models.ValidationError{Field: field, Message: fmt.Sprintf("must be one of: %s", strings.ReplaceAll(fieldErr.Param(), " ", ", ")), Code: "INVALID_VALUE"}
+	default:
+		return models.ValidationError{Field: field, Message: fmt.Sprintf("failed the '%s' rule", fieldErr.Tag()), Code: "INVALID_VALUE"}
+	}
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"net/http"
+	"time"
 
 	"vdt-dashboard-backend/api/middleware"
 	"vdt-dashboard-backend/models"
@@ -30,24 +31,24 @@ func (h *DatabaseHandler) GetDatabaseStatus(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse("Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
 		return
 	}
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse("User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
 		return
 	}
 
 	schema, err := h.schemaService.GetSchema(id, user.ID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse("Schema not found", models.ErrSchemaNotFound, err.Error()))
+		respondServiceError(c, err, "Schema not found")
 		return
 	}
 
 	status, err := h.databaseManagerService.GetDatabaseStatus(schema.DatabaseName)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse("Failed to get database status", models.ErrDatabaseError, err.Error()))
+		c.JSON(http.StatusInternalServerError, middleware.ErrorResponse(c, "Failed to get database status", models.ErrDatabaseError, middleware.ErrorDetails(c, err)))
 		return
 	}
 
@@ -61,34 +62,140 @@ func (h *DatabaseHandler) RegenerateDatabase(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse("Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
 		return
 	}
 
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse("User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
 		return
 	}
 
-	schema, err := h.schemaService.GetSchema(id, user.ID)
+	schema, err := h.schemaService.RegenerateDatabase(c.Request.Context(), id, user.ID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse("Schema not found", models.ErrSchemaNotFound, err.Error()))
+		respondServiceError(c, err, "Failed to regenerate database")
 		return
 	}
 
-	err = h.databaseManagerService.RegenerateDatabase(schema.SchemaDefinition, schema.DatabaseName)
+	response := gin.H{
+		"schemaId":          schema.ID,
+		"databaseName":      schema.DatabaseName,
+		"status":            schema.Status,
+		"lastRegeneratedAt": schema.LastRegeneratedAt,
+		"job":               schema.GenerationJob,
+	}
+
+	c.JSON(http.StatusAccepted, models.SuccessResponse("Database regeneration queued", response))
+}
+
+// GetGenerationJob handles GET /schemas/:id/database/job
+func (h *DatabaseHandler) GetGenerationJob(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse("Failed to regenerate database", models.ErrDatabaseError, err.Error()))
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	job, err := h.schemaService.GetGenerationJob(id, user.ID)
+	if err != nil {
+		respondServiceError(c, err, "Schema not found")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Generation job retrieved", job))
+}
+
+// RebuildForeignKeys handles POST /schemas/:id/database/foreign-keys/rebuild
+func (h *DatabaseHandler) RebuildForeignKeys(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	schema, err := h.schemaService.RebuildForeignKeys(id, user.ID)
+	if err != nil {
+		respondServiceError(c, err, "Failed to rebuild foreign keys")
 		return
 	}
 
 	response := gin.H{
-		"schemaId":      schema.ID,
-		"databaseName":  schema.DatabaseName,
-		"status":        "regenerated",
-		"regeneratedAt": "2024-01-01T12:30:00Z", // TODO: Use actual timestamp
+		"schemaId":        schema.ID,
+		"databaseName":    schema.DatabaseName,
+		"foreignKeyCount": len(schema.SchemaDefinition.ForeignKeys),
+		"rebuiltAt":       time.Now().UTC().Format(time.RFC3339),
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Foreign keys rebuilt successfully", response))
+}
+
+// GetDatabaseDrift handles GET /schemas/:id/database/drift
+func (h *DatabaseHandler) GetDatabaseDrift(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	schema, err := h.schemaService.GetSchema(id, user.ID)
+	if err != nil {
+		respondServiceError(c, err, "Schema not found")
+		return
+	}
+
+	report, err := h.databaseManagerService.DetectDrift(schema.SchemaDefinition, schema.DatabaseName)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, middleware.ErrorResponse(c, "Failed to inspect database", models.ErrDatabaseError, middleware.ErrorDetails(c, err)))
+		return
+	}
+
+	report.SchemaID = schema.ID
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Database drift checked", report))
+}
+
+// ListLiveTables handles GET /schemas/:id/database/tables
+func (h *DatabaseHandler) ListLiveTables(c *gin.Context) {
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	user, exists := middleware.GetUserFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	tables, err := h.schemaService.ListLiveTables(id, user.ID)
+	if err != nil {
+		respondServiceError(c, err, "Failed to list database tables")
+		return
 	}
 
-	c.JSON(http.StatusOK, models.SuccessResponse("Database regenerated successfully", response))
+	c.JSON(http.StatusOK, models.SuccessResponse("Database tables retrieved", tables))
 }
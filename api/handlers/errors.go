@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"vdt-dashboard-backend/api/middleware"
+	"vdt-dashboard-backend/models"
+	"vdt-dashboard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// statusClientClosedRequest is the non-standard status nginx logs for
+// requests the client abandoned before the response was written
+const statusClientClosedRequest = 499
+
+// respondServiceError maps an error returned by a service to an HTTP response.
+// Unexpected errors are logged in full and only detailed outside production.
+// Schema validation failures include the validation result as data.
+func respondServiceError(c *gin.Context, err error, message string) {
+	var validationErr *services.SchemaValidationError
+	if errors.As(err, &validationErr) {
+		response := middleware.ErrorResponse(c, message, models.ErrValidation, err.Error())
+		response.Data = validationErr.Result
+		c.JSON(http.StatusBadRequest, response)
+		return
+	}
+
+	status, code := http.StatusInternalServerError, models.ErrInternalError
+	switch {
+	case errors.Is(err, services.ErrNotFound):
+		status, code = http.StatusNotFound, models.ErrSchemaNotFound
+	case errors.Is(err, services.ErrForbidden):
+		status, code = http.StatusForbidden, models.ErrForbidden
+	case errors.Is(err, services.ErrDuplicate):
+		status, code = http.StatusConflict, models.ErrDuplicateName
+	case errors.Is(err, services.ErrValidation):
+		status, code = http.StatusBadRequest, models.ErrValidation
+	case errors.Is(err, services.ErrQuotaExceeded):
+		status, code = http.StatusTooManyRequests, models.ErrQuotaExceeded
+	case errors.Is(err, services.ErrTooLarge):
+		status, code = http.StatusRequestEntityTooLarge, models.ErrDefinitionTooLarge
+	case errors.Is(err, services.ErrGenerationInProgress):
+		status, code = http.StatusConflict, models.ErrGenerationInProgress
+	case errors.Is(err, services.ErrIdempotencyConflict):
+		status, code = http.StatusConflict, models.ErrIdempotencyKeyConflict
+	case errors.Is(err, services.ErrForeignKeyViolation):
+		status, code = http.StatusConflict, models.ErrForeignKeyError
+	case errors.Is(err, services.ErrQueryTimeout):
+		status, code = http.StatusRequestTimeout, models.ErrQueryTimeout
+	case errors.Is(err, services.ErrQueryCanceled):
+		status, code = statusClientClosedRequest, models.ErrQueryCanceled
+	case errors.Is(err, services.ErrDatabaseProvision):
+		code = models.ErrDatabaseCreationFailed
+	}
+
+	details := err.Error()
+	if status >= http.StatusInternalServerError {
+		details = middleware.ErrorDetails(c, err)
+	}
+
+	response := middleware.ErrorResponse(c, message, code, details)
+	// The failed statement lets clients point at the offending table
+	var statementErr *services.StatementError
+	if errors.As(err, &statementErr) {
+		response.Data = statementErr.Failure
+	}
+	c.JSON(status, response)
+}
@@ -1,24 +1,44 @@
 package handlers
 
 import (
+	"context"
 	"net/http"
 	"time"
 
+	"vdt-dashboard-backend/config"
 	"vdt-dashboard-backend/models"
+	"vdt-dashboard-backend/repositories"
+	"vdt-dashboard-backend/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 )
 
+// provisioningCheckTimeout bounds the maintenance database check
+const provisioningCheckTimeout = 3 * time.Second
+
+// connectionCheckTimeout bounds the pings of generated databases
+const connectionCheckTimeout = 5 * time.Second
+
+// startedAt is when the process started, for reporting uptime
+var startedAt = time.Now()
+
 // HealthHandler handles health check requests
 type HealthHandler struct {
-	db *gorm.DB
+	db              *gorm.DB
+	config          *config.Config
+	schemaRepo      repositories.SchemaRepository
+	databaseManager services.DatabaseManagerService
 }
 
 // NewHealthHandler creates a new health handler
-func NewHealthHandler(db *gorm.DB) *HealthHandler {
+func NewHealthHandler(db *gorm.DB, cfg *config.Config, schemaRepo repositories.SchemaRepository, databaseManager services.DatabaseManagerService) *HealthHandler {
 	return &HealthHandler{
-		db: db,
+		db:              db,
+		config:          cfg,
+		schemaRepo:      schemaRepo,
+		databaseManager: databaseManager,
 	}
 }
 
@@ -35,18 +55,160 @@ func (h *HealthHandler) HealthCheck(c *gin.Context) {
 		dbStatus = "connected"
 	}
 
+	// Check that schema databases can be provisioned. Reads keep working
+	// without it, so it degrades the service rather than failing it.
+	provisioningStatus := "ok"
+	ctx, cancel := context.WithTimeout(c.Request.Context(), provisioningCheckTimeout)
+	defer cancel()
+	if err := config.PingAdminDatabase(ctx, h.config); err != nil {
+		logrus.WithError(err).Warn("Provisioning health check failed")
+		provisioningStatus = "unavailable"
+	}
+
+	buildInfo := config.GetBuildInfo()
 	health := gin.H{
-		"status":    "healthy",
-		"timestamp": time.Now().Format(time.RFC3339),
-		"database":  dbStatus,
-		"version":   "1.0.0",
+		"status":       "healthy",
+		"timestamp":    time.Now().UTC().Format(time.RFC3339),
+		"database":     dbStatus,
+		"provisioning": provisioningStatus,
+		"version":      buildInfo.Version,
+		"commit":       buildInfo.Commit,
+		"buildTime":    buildInfo.BuildTime,
+		"readOnly":     h.config.ReadOnly,
 	}
 
 	statusCode := http.StatusOK
 	if dbStatus != "connected" {
 		health["status"] = "unhealthy"
 		statusCode = http.StatusServiceUnavailable
+	} else if provisioningStatus != "ok" {
+		health["status"] = "degraded"
 	}
 
 	c.JSON(statusCode, models.SuccessResponse("Service health check", health))
 }
+
+// DetailedHealthCheck handles GET /health/detailed. On top of the checks of
+// /health it reports the application database's connection pool, schema
+// counts by status, uptime and the reachability of the generated databases
+// with an open connection pool. It fails only when the application database
+// is unreachable; unreachable generated databases degrade it.
+func (h *HealthHandler) DetailedHealthCheck(c *gin.Context) {
+	database := gin.H{"status": "connected"}
+	sqlDB, err := h.db.DB()
+	if err != nil {
+		database["status"] = "disconnected"
+	} else if err := sqlDB.PingContext(c.Request.Context()); err != nil {
+		database["status"] = "unhealthy"
+	}
+	if sqlDB != nil {
+		stats := sqlDB.Stats()
+		database["pool"] = gin.H{
+			"maxOpenConnections": stats.MaxOpenConnections,
+			"openConnections":    stats.OpenConnections,
+			"inUse":              stats.InUse,
+			"idle":               stats.Idle,
+			"waitCount":          stats.WaitCount,
+			"waitDurationMs":     stats.WaitDuration.Milliseconds(),
+		}
+	}
+
+	uptime := time.Since(startedAt)
+	buildInfo := config.GetBuildInfo()
+	health := gin.H{
+		"status":        "healthy",
+		"timestamp":     time.Now().UTC().Format(time.RFC3339),
+		"startedAt":     startedAt.UTC().Format(time.RFC3339),
+		"uptimeSeconds": int64(uptime.Seconds()),
+		"database":      database,
+		"version":       buildInfo.Version,
+		"commit":        buildInfo.Commit,
+		"buildTime":     buildInfo.BuildTime,
+		"readOnly":      h.config.ReadOnly,
+	}
+
+	if database["status"] != "connected" {
+		health["status"] = "unhealthy"
+		c.JSON(http.StatusServiceUnavailable, models.SuccessResponse("Service health check", health))
+		return
+	}
+
+	counts, err := h.schemaRepo.CountByStatus()
+	if err != nil {
+		logrus.WithError(err).Warn("Failed to count schemas for health check")
+	} else {
+		schemas := gin.H{}
+		for status := range models.ValidSchemaStatuses {
+			schemas[string(status)] = counts[status]
+		}
+		health["schemas"] = schemas
+	}
+
+	degraded := false
+
+	provisioningStatus := "ok"
+	ctx, cancel := context.WithTimeout(c.Request.Context(), provisioningCheckTimeout)
+	defer cancel()
+	if err := config.PingAdminDatabase(ctx, h.config); err != nil {
+		logrus.WithError(err).Warn("Provisioning health check failed")
+		provisioningStatus = "unavailable"
+		degraded = true
+	}
+	health["provisioning"] = provisioningStatus
+
+	// Database names belong to users' schemas, so unreachable ones are
+	// logged rather than returned
+	ctx, cancel = context.WithTimeout(c.Request.Context(), connectionCheckTimeout)
+	defer cancel()
+	reachable, unreachable, openConnections := 0, 0, 0
+	for _, status := range h.databaseManager.CheckConnections(ctx) {
+		openConnections += status.OpenConnections
+		if status.Reachable {
+			reachable++
+			continue
+		}
+		unreachable++
+		logrus.WithField("database", status.DatabaseName).Warn("Generated database unreachable: " + status.Error)
+	}
+	if unreachable > 0 {
+		degraded = true
+	}
+	health["dynamicDatabases"] = gin.H{
+		"checked":         reachable + unreachable,
+		"reachable":       reachable,
+		"unreachable":     unreachable,
+		"openConnections": openConnections,
+	}
+
+	if degraded {
+		health["status"] = "degraded"
+	}
+	c.JSON(http.StatusOK, models.SuccessResponse("Service health check", health))
+}
+
+// Liveness handles GET /health/live. It answers as long as the process
+// serves requests and checks nothing else, so a database outage doesn't get
+// the process restarted.
+func (h *HealthHandler) Liveness(c *gin.Context) {
+	c.JSON(http.StatusOK, models.SuccessResponse("Service is alive", gin.H{
+		"status":    "alive",
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}))
+}
+
+// Readiness handles GET /health/ready. It returns 503 until the application
+// database has been connected to and its migrations have been applied.
+func (h *HealthHandler) Readiness(c *gin.Context) {
+	if !config.IsReady() {
+		c.JSON(http.StatusServiceUnavailable, models.SuccessResponse("Service is not ready", gin.H{
+			"status":    "not_ready",
+			"timestamp": time.Now().UTC().Format(time.RFC3339),
+		}))
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Service is ready", gin.H{
+		"status":    "ready",
+		"timestamp": time.Now().UTC().Format(time.RFC3339),
+	}))
+}
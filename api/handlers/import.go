@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"vdt-dashboard-backend/api/middleware"
+	"vdt-dashboard-backend/models"
+	"vdt-dashboard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ImportHandler handles requests building schema definitions from other
+// formats
+type ImportHandler struct {
+	importer services.SchemaImportService
+}
+
+// NewImportHandler creates a new import handler
+func NewImportHandler(importer services.SchemaImportService) *ImportHandler {
+	return &ImportHandler{
+		importer: importer,
+	}
+}
+
+// ImportJSONSchema handles POST /schemas/import/json-schema. The request body
+// is the JSON Schema document itself.
+func (h *ImportHandler) ImportJSONSchema(c *gin.Context) {
+	document, ok := readImportDocument(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.importer.ImportJSONSchema(document)
+	if err != nil {
+		respondServiceError(c, err, "Failed to import JSON Schema")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("JSON Schema imported successfully", result))
+}
+
+// ImportSQL handles POST /schemas/import/sql. The request body is the DDL
+// script itself.
+func (h *ImportHandler) ImportSQL(c *gin.Context) {
+	document, ok := readImportDocument(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.importer.ImportSQL(document)
+	if err != nil {
+		respondServiceError(c, err, "Failed to import SQL")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("SQL imported successfully", result))
+}
+
+// readImportDocument reads a request body of at most MaxDefinitionBytes,
+// writing the error response when it can't
+func readImportDocument(c *gin.Context) ([]byte, bool) {
+	document, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, models.MaxDefinitionBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, middleware.ErrorResponse(c, "Document too large", models.ErrDefinitionTooLarge,
+			fmt.Sprintf("Documents are limited to %d bytes", models.MaxDefinitionBytes)))
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid request data", models.ErrValidation, err.Error()))
+		return nil, false
+	}
+	if len(document) == 0 {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid request data", models.ErrValidation, "Request body is empty"))
+		return nil, false
+	}
+	return document, true
+}
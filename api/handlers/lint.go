@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"net/http"
+
+	"vdt-dashboard-backend/models"
+	"vdt-dashboard-backend/services"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LintHandler handles schema lint requests
+type LintHandler struct {
+	linter services.SchemaLinter
+}
+
+// NewLintHandler creates a new lint handler
+func NewLintHandler(linter services.SchemaLinter) *LintHandler {
+	return &LintHandler{
+		linter: linter,
+	}
+}
+
+// LintSchema handles POST /schemas/lint. Findings are advisory, so a schema
+// with findings still gets a 200 response.
+func (h *LintHandler) LintSchema(c *gin.Context) {
+	var request models.LintRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindingError(c, err, "Invalid request data")
+		return
+	}
+
+	result, err := h.linter.Lint(request)
+	if err != nil {
+		respondServiceError(c, err, "Invalid lint configuration")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Schema lint completed", result))
+}
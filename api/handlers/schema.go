@@ -1,7 +1,13 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"strconv"
+	"time"
 
 	"vdt-dashboard-backend/api/middleware"
 	"vdt-dashboard-backend/models"
@@ -14,12 +20,14 @@ import (
 // SchemaHandler handles schema-related HTTP requests
 type SchemaHandler struct {
 	schemaService services.SchemaService
+	dbmlGenerator services.DBMLGeneratorService
 }
 
 // NewSchemaHandler creates a new schema handler
-func NewSchemaHandler(schemaService services.SchemaService) *SchemaHandler {
+func NewSchemaHandler(schemaService services.SchemaService, dbmlGenerator services.DBMLGeneratorService) *SchemaHandler {
 	return &SchemaHandler{
 		schemaService: schemaService,
+		dbmlGenerator: dbmlGenerator,
 	}
 }
 
@@ -28,23 +36,70 @@ func (h *SchemaHandler) CreateSchema(c *gin.Context) {
 	// Get authenticated user ID
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse("User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
 		return
 	}
 
 	var request models.CreateSchemaRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse("Invalid request data", models.ErrValidation, err.Error()))
+		respondBindingError(c, err, "Invalid request data")
 		return
 	}
 
-	schema, err := h.schemaService.CreateSchema(request, userID)
+	var options models.SchemaWriteOptions
+	if err := c.ShouldBindQuery(&options); err != nil {
+		respondBindingError(c, err, "Invalid query parameters")
+		return
+	}
+
+	// Retries carrying the same Idempotency-Key return the original schema
+	if key := c.GetHeader("Idempotency-Key"); key != "" {
+		if len(key) > models.MaxIdempotencyKeyLength {
+			c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid idempotency key", models.ErrValidation, fmt.Sprintf("Idempotency-Key must be at most %d characters", models.MaxIdempotencyKeyLength)))
+			return
+		}
+
+		schema, replayed, err := h.schemaService.CreateSchemaIdempotent(c.Request.Context(), request, userID, key)
+		if err != nil {
+			respondServiceError(c, err, "Failed to create schema")
+			return
+		}
+		var warning string
+		if options.IncludeSQL {
+			warning = h.attachSQL(c, schema, userID)
+		}
+		if replayed {
+			c.JSON(http.StatusOK, models.SuccessResponse("Schema already created for this idempotency key"+warning, schema))
+			return
+		}
+		c.JSON(http.StatusAccepted, models.SuccessResponse("Schema created, database generation queued"+warning, schema))
+		return
+	}
+
+	schema, err := h.schemaService.CreateSchema(c.Request.Context(), request, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse("Failed to create schema", models.ErrInternalError, err.Error()))
+		respondServiceError(c, err, "Failed to create schema")
 		return
 	}
+	var warning string
+	if options.IncludeSQL {
+		warning = h.attachSQL(c, schema, userID)
+	}
 
-	c.JSON(http.StatusCreated, models.SuccessResponse("Schema created successfully", schema))
+	c.JSON(http.StatusAccepted, models.SuccessResponse("Schema created, database generation queued"+warning, schema))
+}
+
+// attachSQL sets the schema's generatedSql to the DDL the SQL export returns
+// for it. The schema is already saved by then, so a failure doesn't fail the
+// request: generatedSql is omitted and the returned warning is appended to the
+// response message. It returns an empty string when the SQL was attached.
+func (h *SchemaHandler) attachSQL(c *gin.Context, schema *models.Schema, userID uuid.UUID) string {
+	sqlExport, err := h.schemaService.ExportSQL(schema.ID, userID, models.SQLExportOptions{})
+	if err != nil {
+		return "; generatedSql is omitted, the SQL could not be generated: " + middleware.ErrorDetails(c, err)
+	}
+	schema.GeneratedSQL = sqlExport.SQL
+	return ""
 }
 
 // ListSchemas handles GET /schemas
@@ -52,19 +107,19 @@ func (h *SchemaHandler) ListSchemas(c *gin.Context) {
 	// Get authenticated user ID
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse("User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
 		return
 	}
 
 	var pagination models.PaginationRequest
 	if err := c.ShouldBindQuery(&pagination); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse("Invalid pagination parameters", models.ErrValidation, err.Error()))
+		respondBindingError(c, err, "Invalid pagination parameters")
 		return
 	}
 
 	schemas, paginationResp, err := h.schemaService.ListSchemas(pagination, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse("Failed to list schemas", models.ErrInternalError, err.Error()))
+		respondServiceError(c, err, "Failed to list schemas")
 		return
 	}
 
@@ -76,55 +131,206 @@ func (h *SchemaHandler) GetSchema(c *gin.Context) {
 	// Get authenticated user ID
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse("User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
 		return
 	}
 
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse("Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
 		return
 	}
 
 	schema, err := h.schemaService.GetSchema(id, userID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, models.ErrorResponse("Schema not found", models.ErrSchemaNotFound, err.Error()))
+		respondServiceError(c, err, "Schema not found")
 		return
 	}
 
 	c.JSON(http.StatusOK, models.SuccessResponse("Schema retrieved successfully", schema))
 }
 
+// eventKeepAliveInterval is how often an idle event stream sends a comment,
+// so proxies don't close it
+const eventKeepAliveInterval = 15 * time.Second
+
+// StreamEvents handles GET /schemas/:id/events. It streams the status of the
+// schema, then each change of its status and generation job, as server-sent
+// events. The stream ends once the schema reaches a status it stays in or its
+// job finishes.
+func (h *SchemaHandler) StreamEvents(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	schema, events, cancel, err := h.schemaService.SubscribeEvents(id, userID)
+	if err != nil {
+		respondServiceError(c, err, "Schema not found")
+		return
+	}
+	defer cancel()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.SSEvent(models.SchemaEventStatus, models.SchemaEvent{
+		Type:     models.SchemaEventStatus,
+		SchemaID: schema.ID,
+		Status:   schema.Status,
+		Time:     time.Now().UTC(),
+	})
+	c.Writer.Flush()
+	if schema.Status.Terminal() {
+		return
+	}
+
+	keepAlive := time.NewTicker(eventKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-keepAlive.C:
+			fmt.Fprint(c.Writer, ": keep-alive\n\n")
+			c.Writer.Flush()
+		case event, open := <-events:
+			if !open {
+				return
+			}
+			c.SSEvent(event.Type, event)
+			c.Writer.Flush()
+			if streamFinished(event) {
+				return
+			}
+		}
+	}
+}
+
+// streamFinished reports whether an event leaves nothing more to stream
+func streamFinished(event models.SchemaEvent) bool {
+	switch event.Type {
+	case models.SchemaEventStatus:
+		return event.Status.Terminal()
+	case models.SchemaEventJob:
+		return event.Job.State == models.GenerationJobSucceeded || event.Job.State == models.GenerationJobFailed
+	}
+	return false
+}
+
 // UpdateSchema handles PUT /schemas/:id
 func (h *SchemaHandler) UpdateSchema(c *gin.Context) {
 	// Get authenticated user ID
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse("User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
 		return
 	}
 
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse("Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
 		return
 	}
 
 	var request models.UpdateSchemaRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse("Invalid request data", models.ErrValidation, err.Error()))
+		respondBindingError(c, err, "Invalid request data")
+		return
+	}
+
+	var options models.SchemaWriteOptions
+	if err := c.ShouldBindQuery(&options); err != nil {
+		respondBindingError(c, err, "Invalid query parameters")
 		return
 	}
 
-	schema, err := h.schemaService.UpdateSchema(id, userID, request)
+	schema, err := h.schemaService.UpdateSchema(c.Request.Context(), id, userID, request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse("Failed to update schema", models.ErrInternalError, err.Error()))
+		respondServiceError(c, err, "Failed to update schema")
 		return
 	}
+	var warning string
+	if options.IncludeSQL {
+		warning = h.attachSQL(c, schema, userID)
+	}
 
-	c.JSON(http.StatusOK, models.SuccessResponse("Schema updated successfully", schema))
+	c.JSON(http.StatusOK, models.SuccessResponse("Schema updated successfully"+warning, schema))
+}
+
+// DiffSchema handles POST /schemas/:id/diff. It takes a body, so it is a
+// POST even though nothing is changed.
+func (h *SchemaHandler) DiffSchema(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	var request models.UpdateSchemaRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindingError(c, err, "Invalid request data")
+		return
+	}
+
+	diff, err := h.schemaService.DiffSchema(id, userID, request)
+	if err != nil {
+		respondServiceError(c, err, "Failed to diff schema")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Schema diff generated", diff))
+}
+
+// PreviewSQL handles POST /schemas/:id/sql/preview. Like DiffSchema it
+// changes nothing.
+func (h *SchemaHandler) PreviewSQL(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	var request models.UpdateSchemaRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindingError(c, err, "Invalid request data")
+		return
+	}
+
+	preview, err := h.schemaService.PreviewSQL(id, userID, request)
+	if err != nil {
+		respondServiceError(c, err, "Failed to preview SQL")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("SQL preview generated", preview))
 }
 
 // DeleteSchema handles DELETE /schemas/:id
@@ -132,23 +338,124 @@ func (h *SchemaHandler) DeleteSchema(c *gin.Context) {
 	// Get authenticated user ID
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse("User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	var options models.DeleteSchemaOptions
+	if err := c.ShouldBindQuery(&options); err != nil {
+		respondBindingError(c, err, "Invalid delete options")
+		return
+	}
+
+	databaseDropped, err := h.schemaService.DeleteSchema(id, userID, options)
+	if err != nil {
+		respondServiceError(c, err, "Failed to delete schema")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Schema deleted successfully", gin.H{"id": id, "databaseDropped": databaseDropped}))
+}
+
+// BulkDeleteSchemas handles POST /schemas/bulk-delete
+func (h *SchemaHandler) BulkDeleteSchemas(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	var request models.BulkDeleteRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindingError(c, err, "Invalid request data")
+		return
+	}
+
+	if len(request.IDs) == 0 || len(request.IDs) > models.MaxBulkDeleteSize {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid batch size", models.ErrValidation,
+			fmt.Sprintf("Batch must contain between 1 and %d schema IDs", models.MaxBulkDeleteSize)))
+		return
+	}
+
+	var options models.DeleteSchemaOptions
+	if err := c.ShouldBindQuery(&options); err != nil {
+		respondBindingError(c, err, "Invalid delete options")
+		return
+	}
+
+	response := h.schemaService.BulkDeleteSchemas(request.IDs, userID, options)
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Bulk delete completed", response))
+}
+
+// RestoreSchema handles POST /schemas/:id/restore
+func (h *SchemaHandler) RestoreSchema(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	schema, err := h.schemaService.RestoreSchema(c.Request.Context(), id, userID)
+	if err != nil {
+		respondServiceError(c, err, "Failed to restore schema")
+		return
+	}
+
+	// The database was kept on delete, so there is nothing to generate
+	if schema.GenerationJob == nil {
+		c.JSON(http.StatusOK, models.SuccessResponse("Schema restored", schema))
+		return
+	}
+
+	c.JSON(http.StatusAccepted, models.SuccessResponse("Schema restored, database generation queued", schema))
+}
+
+// CloneSchema handles POST /schemas/:id/clone
+func (h *SchemaHandler) CloneSchema(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
 		return
 	}
 
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse("Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	var request models.CloneSchemaRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindingError(c, err, "Invalid request data")
 		return
 	}
 
-	if err := h.schemaService.DeleteSchema(id, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse("Failed to delete schema", models.ErrInternalError, err.Error()))
+	schema, err := h.schemaService.CloneSchema(c.Request.Context(), id, userID, request.Name)
+	if err != nil {
+		respondServiceError(c, err, "Failed to clone schema")
 		return
 	}
 
-	c.JSON(http.StatusOK, models.SuccessResponse("Schema deleted successfully", gin.H{"id": id}))
+	c.JSON(http.StatusAccepted, models.SuccessResponse("Schema cloned, database generation queued", schema))
 }
 
 // ExportSQL handles GET /schemas/:id/export/sql
@@ -156,22 +463,464 @@ func (h *SchemaHandler) ExportSQL(c *gin.Context) {
 	// Get authenticated user ID
 	userID, exists := middleware.GetUserIDFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse("User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
 		return
 	}
 
 	idParam := c.Param("id")
 	id, err := uuid.Parse(idParam)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse("Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
 		return
 	}
 
-	sqlExport, err := h.schemaService.ExportSQL(id, userID)
+	var options models.SQLExportOptions
+	if err := c.ShouldBindQuery(&options); err != nil {
+		respondBindingError(c, err, "Invalid export options")
+		return
+	}
+
+	sqlExport, err := h.schemaService.ExportSQL(id, userID, options)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse("Failed to export SQL", models.ErrInternalError, err.Error()))
+		respondServiceError(c, err, "Failed to export SQL")
 		return
 	}
 
 	c.JSON(http.StatusOK, models.SuccessResponse("SQL export generated", sqlExport))
 }
+
+// ExportTypeScript handles GET /schemas/:id/export/typescript
+func (h *SchemaHandler) ExportTypeScript(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	timestampType := c.DefaultQuery("timestampType", services.TimestampAsString)
+	if timestampType != services.TimestampAsString && timestampType != services.TimestampAsDate {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid timestamp type", models.ErrValidation, "timestampType must be 'string' or 'date'"))
+		return
+	}
+
+	export, err := h.schemaService.ExportTypeScript(id, userID, services.TypeScriptExporter{TimestampType: timestampType})
+	if err != nil {
+		respondServiceError(c, err, "Failed to export TypeScript")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("TypeScript export generated", export))
+}
+
+// ExportDBML handles GET /schemas/:id/export/dbml
+func (h *SchemaHandler) ExportDBML(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	schema, err := h.schemaService.GetSchema(id, userID)
+	if err != nil {
+		respondServiceError(c, err, "Schema not found")
+		return
+	}
+
+	dbml, err := h.dbmlGenerator.GenerateDBML(schema.SchemaDefinition)
+	if err != nil {
+		respondServiceError(c, err, "Failed to export DBML")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("DBML export generated", models.DBMLExportResponse{
+		SchemaID:    schema.ID,
+		DBML:        dbml,
+		GeneratedAt: time.Now().UTC(),
+	}))
+}
+
+// ExportDiagram handles GET /schemas/:id/export/diagram.svg
+func (h *SchemaHandler) ExportDiagram(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	svg, err := h.schemaService.ExportDiagram(id, userID)
+	if err != nil {
+		respondServiceError(c, err, "Failed to export diagram")
+		return
+	}
+
+	c.Data(http.StatusOK, "image/svg+xml; charset=utf-8", []byte(svg))
+}
+
+// ListTables handles GET /schemas/:id/tables
+func (h *SchemaHandler) ListTables(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	var pagination models.PaginationRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		respondBindingError(c, err, "Invalid pagination parameters")
+		return
+	}
+
+	tables, paginationResp, err := h.schemaService.ListTables(id, userID, pagination)
+	if err != nil {
+		respondServiceError(c, err, "Failed to list tables")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedSuccessResponse("Tables retrieved successfully", tables, paginationResp))
+}
+
+// ListRelationships handles GET /schemas/:id/relationships
+func (h *SchemaHandler) ListRelationships(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	var pagination models.PaginationRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		respondBindingError(c, err, "Invalid pagination parameters")
+		return
+	}
+
+	relationships, paginationResp, err := h.schemaService.ListRelationships(id, userID, pagination)
+	if err != nil {
+		respondServiceError(c, err, "Failed to list relationships")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedSuccessResponse("Relationships retrieved successfully", relationships, paginationResp))
+}
+
+// GetTableDDL handles GET /schemas/:id/tables/:tableId/ddl
+func (h *SchemaHandler) GetTableDDL(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	ddl, err := h.schemaService.GetTableDDL(id, userID, c.Param("tableId"))
+	if err != nil {
+		respondServiceError(c, err, "Failed to generate table DDL")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Table DDL generated", ddl))
+}
+
+// ImportTableData handles POST /schemas/:id/tables/:tableId/data. The CSV
+// file is sent as the "file" field of a multipart form or as the request
+// body, and the table is named by its ID or name.
+func (h *SchemaHandler) ImportTableData(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	data, ok := readTableData(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.schemaService.ImportTableData(id, userID, c.Param("tableId"), data)
+	if err != nil {
+		respondServiceError(c, err, "Failed to import table data")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Table data imported", result))
+}
+
+// ExecuteQuery handles POST /schemas/:id/query
+func (h *SchemaHandler) ExecuteQuery(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	var request models.QueryRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindingError(c, err, "Invalid request data")
+		return
+	}
+
+	result, err := h.schemaService.ExecuteQuery(c.Request.Context(), id, userID, request)
+	if err != nil {
+		respondServiceError(c, err, "Failed to execute query")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Query executed", result))
+}
+
+// readTableData reads an uploaded CSV file of at most MaxDataImportBytes,
+// writing the error response when it can't
+func readTableData(c *gin.Context) ([]byte, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, models.MaxDataImportBytes)
+
+	var data []byte
+	var err error
+	if c.ContentType() == "multipart/form-data" {
+		var header *multipart.FileHeader
+		header, err = c.FormFile("file")
+		if err == nil {
+			var file multipart.File
+			if file, err = header.Open(); err == nil {
+				data, err = io.ReadAll(file)
+				file.Close()
+			}
+		}
+	} else {
+		data, err = io.ReadAll(c.Request.Body)
+	}
+
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, middleware.ErrorResponse(c, "File too large", models.ErrDefinitionTooLarge,
+			fmt.Sprintf("CSV files are limited to %d bytes", models.MaxDataImportBytes)))
+		return nil, false
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid request data", models.ErrValidation, err.Error()))
+		return nil, false
+	}
+	if len(data) == 0 {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid request data", models.ErrValidation, "CSV file is empty"))
+		return nil, false
+	}
+	return data, true
+}
+
+// SetFavorite handles PATCH /schemas/:id/favorite
+func (h *SchemaHandler) SetFavorite(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	// An empty body toggles the current flag
+	var request models.FavoriteSchemaRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&request); err != nil {
+			respondBindingError(c, err, "Invalid request data")
+			return
+		}
+	}
+
+	schema, err := h.schemaService.SetFavorite(id, userID, request)
+	if err != nil {
+		respondServiceError(c, err, "Failed to update favorite")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Schema favorite updated", gin.H{"id": schema.ID, "isFavorite": schema.IsFavorite}))
+}
+
+// ShareSchema handles POST /schemas/:id/share
+func (h *SchemaHandler) ShareSchema(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	var request models.ShareSchemaRequest
+	if err := c.ShouldBindJSON(&request); err != nil {
+		respondBindingError(c, err, "Invalid request data")
+		return
+	}
+
+	collaborator, err := h.schemaService.ShareSchema(id, userID, request)
+	if err != nil {
+		respondServiceError(c, err, "Failed to share schema")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Schema shared successfully", collaborator))
+}
+
+// UnshareSchema handles DELETE /schemas/:id/share/:userId
+func (h *SchemaHandler) UnshareSchema(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	collaboratorID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid user ID", models.ErrValidation, "User ID must be a valid UUID"))
+		return
+	}
+
+	if err := h.schemaService.UnshareSchema(id, userID, collaboratorID); err != nil {
+		respondServiceError(c, err, "Failed to unshare schema")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Schema unshared successfully", gin.H{"schemaId": id, "userId": collaboratorID}))
+}
+
+// ListVersions handles GET /schemas/:id/versions
+func (h *SchemaHandler) ListVersions(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	var pagination models.PaginationRequest
+	if err := c.ShouldBindQuery(&pagination); err != nil {
+		respondBindingError(c, err, "Invalid pagination parameters")
+		return
+	}
+
+	versions, paginationResp, err := h.schemaService.ListVersions(id, userID, pagination)
+	if err != nil {
+		respondServiceError(c, err, "Failed to list schema versions")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PaginatedSuccessResponse("Schema versions retrieved successfully", versions, paginationResp))
+}
+
+// RollbackSchema handles POST /schemas/:id/versions/:version/rollback
+func (h *SchemaHandler) RollbackSchema(c *gin.Context) {
+	// Get authenticated user ID
+	userID, exists := middleware.GetUserIDFromContext(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		return
+	}
+
+	idParam := c.Param("id")
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid schema ID", models.ErrValidation, "ID must be a valid UUID"))
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid version", models.ErrValidation, "Version must be a positive integer"))
+		return
+	}
+
+	schema, err := h.schemaService.RollbackSchema(c.Request.Context(), id, userID, version)
+	if err != nil {
+		respondServiceError(c, err, "Failed to roll back schema")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, models.SuccessResponse("Schema rolled back, database generation queued", schema))
+}
@@ -22,7 +22,7 @@ func (h *UserHandler) GetCurrentUser(c *gin.Context) {
 	// Get authenticated user from context
 	user, exists := middleware.GetUserFromContext(c)
 	if !exists {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse("User not authenticated", models.ErrUnauthorized, "Missing user context"))
+		c.JSON(http.StatusUnauthorized, middleware.ErrorResponse(c, "User not authenticated", models.ErrUnauthorized, "Missing user context"))
 		return
 	}
 
@@ -1,12 +1,18 @@
 package handlers
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
+	"runtime"
+	"sync"
 
+	"vdt-dashboard-backend/api/middleware"
 	"vdt-dashboard-backend/models"
 	"vdt-dashboard-backend/services"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
 )
 
 // ValidatorHandler handles validation requests
@@ -27,16 +33,104 @@ func NewValidatorHandler(validatorService services.ValidatorService, sqlGenerato
 func (h *ValidatorHandler) ValidateSchema(c *gin.Context) {
 	var request models.SchemaValidationRequest
 	if err := c.ShouldBindJSON(&request); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse("Invalid request data", models.ErrValidation, err.Error()))
+		respondBindingError(c, err, "Invalid request data")
 		return
 	}
 
-	validationResult, err := h.validatorService.ValidateSchema(request)
+	validationResult, err := h.validateWithPreview(request)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse("Validation failed", models.ErrInternalError, err.Error()))
+		c.JSON(http.StatusInternalServerError, middleware.ErrorResponse(c, "Validation failed", models.ErrInternalError, middleware.ErrorDetails(c, err)))
 		return
 	}
 
+	statusCode := http.StatusOK
+	message := "Schema is valid"
+
+	if !validationResult.Valid {
+		statusCode = http.StatusBadRequest
+		message = "Schema validation failed"
+	}
+
+	c.JSON(statusCode, models.SuccessResponse(message, validationResult))
+}
+
+// ValidateSchemaBatch handles POST /schemas/validate/batch
+func (h *ValidatorHandler) ValidateSchemaBatch(c *gin.Context) {
+	// Items are validated individually below so one malformed schema doesn't
+	// reject the whole batch
+	var requests []models.SchemaValidationRequest
+	if err := json.NewDecoder(c.Request.Body).Decode(&requests); err != nil {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid request data", models.ErrInvalidJSON, err.Error()))
+		return
+	}
+
+	if len(requests) == 0 || len(requests) > models.MaxBatchValidationSize {
+		c.JSON(http.StatusBadRequest, middleware.ErrorResponse(c, "Invalid batch size", models.ErrValidation,
+			fmt.Sprintf("Batch must contain between 1 and %d schemas", models.MaxBatchValidationSize)))
+		return
+	}
+
+	results := make([]models.ValidationResult, len(requests))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	workers := min(runtime.NumCPU(), len(requests))
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = h.validateBatchItem(requests[i])
+			}
+		}()
+	}
+	for i := range requests {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	response := models.BatchValidationResponse{
+		AllValid: true,
+		Results:  results,
+	}
+	for _, result := range results {
+		if !result.Valid {
+			response.AllValid = false
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse("Batch validation completed", response))
+}
+
+// validateBatchItem validates one schema of a batch, reporting request and
+// internal errors as an invalid result
+func (h *ValidatorHandler) validateBatchItem(request models.SchemaValidationRequest) models.ValidationResult {
+	if err := binding.Validator.ValidateStruct(&request); err != nil {
+		return models.ValidationResult{
+			Errors: bindingErrors(err),
+		}
+	}
+
+	result, err := h.validateWithPreview(request)
+	if err != nil {
+		return models.ValidationResult{
+			Errors: []models.ValidationError{{Field: "request", Message: "Validation could not be completed", Code: models.ErrInternalError}},
+		}
+	}
+
+	return *result
+}
+
+// validateWithPreview validates a schema and, if it passes, attaches the
+// generated SQL preview
+func (h *ValidatorHandler) validateWithPreview(request models.SchemaValidationRequest) (*models.ValidationResult, error) {
+	validationResult, err := h.validatorService.ValidateSchema(request)
+	if err != nil {
+		return nil, err
+	}
+
 	// If validation passed, generate SQL preview
 	if validationResult.Valid {
 		schemaData := models.SchemaData{
@@ -50,13 +144,5 @@ func (h *ValidatorHandler) ValidateSchema(c *gin.Context) {
 		}
 	}
 
-	statusCode := http.StatusOK
-	message := "Schema is valid"
-
-	if !validationResult.Valid {
-		statusCode = http.StatusBadRequest
-		message = "Schema validation failed"
-	}
-
-	c.JSON(statusCode, models.SuccessResponse(message, validationResult))
+	return validationResult, nil
 }
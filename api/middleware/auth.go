@@ -2,13 +2,16 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net"
 	"net/http"
 	"strings"
 	"time"
 
 	"vdt-dashboard-backend/models"
 	"vdt-dashboard-backend/repositories"
+	"vdt-dashboard-backend/services"
 
 	"github.com/clerk/clerk-sdk-go/v2"
 	"github.com/clerk/clerk-sdk-go/v2/jwt"
@@ -18,18 +21,66 @@ import (
 	"gorm.io/gorm"
 )
 
+// Clerk token verification modes
+const (
+	// VerifyModeOnline verifies the token and fetches the user from Clerk on every request
+	VerifyModeOnline = "online"
+	// VerifyModeOffline verifies the token locally and trusts its claims, only
+	// calling Clerk to backfill users that are not stored locally yet
+	VerifyModeOffline = "offline"
+)
+
+// APIKeyHeader carries an API key, accepted instead of a Clerk session token
+const APIKeyHeader = "X-API-Key"
+
 // AuthConfig holds Clerk configuration
 type AuthConfig struct {
-	SecretKey string
+	SecretKey  string
+	VerifyMode string
+}
+
+// profileClaims holds optional user profile claims that can be added to
+// Clerk session tokens through a custom session token template
+type profileClaims struct {
+	Email     string `json:"email"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	ImageURL  string `json:"image_url"`
 }
 
-// AuthMiddleware handles Clerk JWT authentication using Clerk SDK
-func AuthMiddleware(userRepo repositories.UserRepository, clerkSecretKey string) gin.HandlerFunc {
+// AuthMiddleware handles Clerk JWT authentication using Clerk SDK. Requests
+// with an X-API-Key header are authenticated by the key instead.
+func AuthMiddleware(userRepo repositories.UserRepository, apiKeys services.APIKeyService, authConfig AuthConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
+		// Preflight requests never carry credentials
+		if c.Request.Method == http.MethodOptions {
+			c.Next()
+			return
+		}
+
+		if apiKey := c.GetHeader(APIKeyHeader); apiKey != "" {
+			currentUser, err := apiKeys.Authenticate(apiKey)
+			if errors.Is(err, services.ErrInvalidAPIKey) {
+				c.JSON(http.StatusUnauthorized, ErrorResponse(c, "Invalid API key", models.ErrUnauthorized, err.Error()))
+				c.Abort()
+				return
+			}
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, ErrorResponse(c, "Failed to authenticate user", models.ErrInternalError, ErrorDetails(c, err)))
+				c.Abort()
+				return
+			}
+
+			setAuthenticatedUser(c, currentUser)
+			c.Set("apiKeyAuthenticated", true)
+			c.Next()
+			return
+		}
+
 		// Get the Authorization header
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse("Authorization header is required", models.ErrUnauthorized, "Missing Authorization header"))
+			c.JSON(http.StatusUnauthorized, ErrorResponse(c, "Authorization header is required", models.ErrUnauthorized, "Missing Authorization header"))
 			c.Abort()
 			return
 		}
@@ -37,7 +88,7 @@ func AuthMiddleware(userRepo repositories.UserRepository, clerkSecretKey string)
 		// Extract the token from "Bearer <token>"
 		parts := strings.Split(authHeader, " ")
 		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse("Invalid authorization header format", models.ErrUnauthorized, "Use Bearer <token>"))
+			c.JSON(http.StatusUnauthorized, ErrorResponse(c, "Invalid authorization header format", models.ErrUnauthorized, "Use Bearer <token>"))
 			c.Abort()
 			return
 		}
@@ -45,26 +96,23 @@ func AuthMiddleware(userRepo repositories.UserRepository, clerkSecretKey string)
 		sessionToken := parts[1]
 
 		// Set Clerk API key
-		clerk.SetKey(clerkSecretKey)
+		clerk.SetKey(authConfig.SecretKey)
 
 		// Verify the token using Clerk SDK v2
 		ctx := context.Background()
-		
+
 		// First decode the token to get the key ID
 		decoded, err := jwt.Decode(ctx, &jwt.DecodeParams{Token: sessionToken})
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse("Invalid token", models.ErrUnauthorized, err.Error()))
+			c.JSON(http.StatusUnauthorized, ErrorResponse(c, "Invalid token", models.ErrUnauthorized, err.Error()))
 			c.Abort()
 			return
 		}
 
-		// Fetch the JSON web key for verification
-		jwk, err := jwt.GetJSONWebKey(ctx, &jwt.GetJSONWebKeyParams{
-			KeyID: decoded.KeyID,
-		})
+		// Fetch the JSON web key for verification, served from cache when possible
+		jwk, err := jwkStore.get(ctx, decoded.KeyID)
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse("Failed to get JWT key", models.ErrUnauthorized, err.Error()))
-			c.Abort()
+			abortWithClerkError(c, err, "Failed to get JWT key")
 			return
 		}
 
@@ -72,36 +120,137 @@ func AuthMiddleware(userRepo repositories.UserRepository, clerkSecretKey string)
 		claims, err := jwt.Verify(ctx, &jwt.VerifyParams{
 			Token: sessionToken,
 			JWK:   jwk,
+			CustomClaimsConstructor: func(_ context.Context) any {
+				return &profileClaims{}
+			},
 		})
 		if err != nil {
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse("Invalid token", models.ErrUnauthorized, err.Error()))
+			c.JSON(http.StatusUnauthorized, ErrorResponse(c, "Invalid token", models.ErrUnauthorized, err.Error()))
 			c.Abort()
 			return
 		}
 
-		// Get user info from Clerk using the SDK
-		clerkUser, err := user.Get(ctx, claims.Subject)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, models.ErrorResponse("Failed to fetch user from Clerk", models.ErrUnauthorized, err.Error()))
-			c.Abort()
-			return
+		var currentUser *models.User
+		if authConfig.VerifyMode == VerifyModeOffline {
+			currentUser, err = getUserFromClaims(userRepo, claims)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, ErrorResponse(c, "Failed to authenticate user", models.ErrInternalError, ErrorDetails(c, err)))
+				c.Abort()
+				return
+			}
 		}
 
-		// Get or create user in our database
-		user, err := getOrCreateUserFromClerk(userRepo, clerkUser, claims.Subject)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse("Failed to authenticate user", models.ErrInternalError, err.Error()))
+		// Online mode, or a user we have never seen: sync the profile from Clerk
+		if currentUser == nil {
+			clerkUser, err := user.Get(ctx, claims.Subject)
+			if err != nil {
+				abortWithClerkError(c, err, "Failed to fetch user from Clerk")
+				return
+			}
+
+			currentUser, err = getOrCreateUserFromClerk(userRepo, clerkUser, claims.Subject)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, ErrorResponse(c, "Failed to authenticate user", models.ErrInternalError, ErrorDetails(c, err)))
+				c.Abort()
+				return
+			}
+		}
+
+		setAuthenticatedUser(c, currentUser)
+		c.Next()
+	}
+}
+
+// RequireSession rejects requests authenticated by an API key with 403, so a
+// leaked key can't be used to mint or revoke keys. It must run after
+// AuthMiddleware.
+func RequireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetBool("apiKeyAuthenticated") {
+			c.JSON(http.StatusForbidden, ErrorResponse(c, "A Clerk session is required", models.ErrForbidden, "API keys can't be used to manage API keys"))
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
-		// Set user in context
-		c.Set("user", user)
-		c.Set("userID", user.ID)
-		c.Set("clerkUserID", user.ClerkUserID)
+// setAuthenticatedUser sets the authenticated user in context
+func setAuthenticatedUser(c *gin.Context, currentUser *models.User) {
+	c.Set("user", currentUser)
+	c.Set("userID", currentUser.ID)
+	c.Set("clerkUserID", currentUser.ClerkUserID)
+}
 
-		c.Next()
+// getUserFromClaims returns the stored user for verified session claims without
+// calling Clerk. Profile fields present in the claims are synced to the user.
+// It returns nil when the user has not been stored locally yet.
+func getUserFromClaims(userRepo repositories.UserRepository, claims *clerk.SessionClaims) (*models.User, error) {
+	existing, err := userRepo.GetByClerkID(claims.Subject)
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("database error: %w", err)
+	}
+
+	profile, ok := claims.Custom.(*profileClaims)
+	if !ok {
+		return existing, nil
+	}
+
+	changed := false
+	for _, field := range []struct {
+		target *string
+		value  string
+	}{
+		{&existing.Email, profile.Email},
+		{&existing.FirstName, profile.FirstName},
+		{&existing.LastName, profile.LastName},
+		{&existing.ProfileImageURL, profile.ImageURL},
+	} {
+		if field.value != "" && *field.target != field.value {
+			*field.target = field.value
+			changed = true
+		}
 	}
+
+	if changed {
+		existing.UpdatedAt = time.Now().UTC()
+		if err := userRepo.Update(existing); err != nil {
+			return nil, fmt.Errorf("failed to update user: %w", err)
+		}
+	}
+
+	return existing, nil
+}
+
+// abortWithClerkError aborts the request after a failed Clerk API call. Outages
+// on Clerk's side are reported as 503 so clients don't discard a valid session.
+func abortWithClerkError(c *gin.Context, err error, message string) {
+	if isClerkUnavailable(err) {
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse(c, "Authentication service unavailable", models.ErrAuthProviderUnavailable, ErrorDetails(c, err)))
+	} else {
+		c.JSON(http.StatusUnauthorized, ErrorResponse(c, message, models.ErrUnauthorized, err.Error()))
+	}
+	c.Abort()
+}
+
+// isClerkUnavailable reports whether err is caused by Clerk's infrastructure
+// (network failure, timeout or 5xx response) rather than by the token itself
+func isClerkUnavailable(err error) bool {
+	var apiErr *clerk.APIErrorResponse
+	if errors.As(err, &apiErr) {
+		return apiErr.HTTPStatusCode >= http.StatusInternalServerError ||
+			apiErr.HTTPStatusCode == http.StatusTooManyRequests
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return errors.Is(err, context.DeadlineExceeded)
 }
 
 // getOrCreateUserFromClerk retrieves or creates a user in our database based on Clerk user data
@@ -114,7 +263,7 @@ func getOrCreateUserFromClerk(userRepo repositories.UserRepository, clerkUser *c
 
 	// Extract user info from Clerk user object
 	var email, firstName, lastName, profileImageURL string
-	
+
 	// Get primary email
 	if len(clerkUser.EmailAddresses) > 0 {
 		for _, emailAddr := range clerkUser.EmailAddresses {
@@ -150,8 +299,8 @@ func getOrCreateUserFromClerk(userRepo repositories.UserRepository, clerkUser *c
 			FirstName:       firstName,
 			LastName:        lastName,
 			ProfileImageURL: profileImageURL,
-			CreatedAt:       time.Now(),
-			UpdatedAt:       time.Now(),
+			CreatedAt:       time.Now().UTC(),
+			UpdatedAt:       time.Now().UTC(),
 		}
 
 		if err := userRepo.Create(user); err != nil {
@@ -163,7 +312,7 @@ func getOrCreateUserFromClerk(userRepo repositories.UserRepository, clerkUser *c
 		user.FirstName = firstName
 		user.LastName = lastName
 		user.ProfileImageURL = profileImageURL
-		user.UpdatedAt = time.Now()
+		user.UpdatedAt = time.Now().UTC()
 
 		if err := userRepo.Update(user); err != nil {
 			return nil, fmt.Errorf("failed to update user: %w", err)
@@ -189,4 +338,4 @@ func GetUserIDFromContext(c *gin.Context) (uuid.UUID, bool) {
 		return uuid.Nil, false
 	}
 	return userID.(uuid.UUID), true
-} 
\ No newline at end of file
+}
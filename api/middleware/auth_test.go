@@ -0,0 +1,303 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"vdt-dashboard-backend/models"
+	"vdt-dashboard-backend/services"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// signToken returns an RS256 session token for subject signed with key
+func signToken(t *testing.T, key *rsa.PrivateKey, keyID, subject string) string {
+	t.Helper()
+
+	encode := func(value any) string {
+		data, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	now := time.Now().Unix()
+	signingInput := encode(map[string]string{"alg": "RS256", "kid": keyID, "typ": "JWT"}) + "." +
+		encode(map[string]any{"sub": subject, "iat": now, "nbf": now, "exp": now + 60})
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// useClerkBackend points the Clerk SDK at url for the rest of the test
+func useClerkBackend(t *testing.T, url string) {
+	t.Helper()
+
+	previous := clerk.GetBackend()
+	clerk.SetBackend(clerk.NewBackend(&clerk.BackendConfig{URL: clerk.String(url)}))
+	t.Cleanup(func() { clerk.SetBackend(previous) })
+}
+
+// serveJWKS returns a fake Clerk API serving key as the only JSON web key
+func serveJWKS(t *testing.T, keyID string, key *rsa.PublicKey) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/jwks" {
+			http.NotFound(w, r)
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"keys": []map[string]string{{
+				"kty": "RSA",
+				"use": "sig",
+				"alg": "RS256",
+				"kid": keyID,
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// authenticate runs a request with token through AuthMiddleware and returns
+// the response
+func authenticate(t *testing.T, token string) (*httptest.ResponseRecorder, models.APIResponse) {
+	t.Helper()
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/protected", AuthMiddleware(nil, nil, AuthConfig{VerifyMode: VerifyModeOnline}), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	request := httptest.NewRequest(http.MethodGet, "/protected", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	var response models.APIResponse
+	if recorder.Body.Len() > 0 {
+		if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+			t.Fatalf("invalid response body %q: %v", recorder.Body.String(), err)
+		}
+	}
+	return recorder, response
+}
+
+func TestAuthMiddlewareClerkFailures(t *testing.T) {
+	signingKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		keyID   string
+		backend func(t *testing.T, keyID string) string
+		status  int
+		code    string
+	}{
+		{
+			name:  "Clerk unreachable",
+			keyID: "unreachable",
+			backend: func(t *testing.T, keyID string) string {
+				// A closed server refuses connections
+				server := serveJWKS(t, keyID, &signingKey.PublicKey)
+				server.Close()
+				return server.URL
+			},
+			status: http.StatusServiceUnavailable,
+			code:   models.ErrAuthProviderUnavailable,
+		},
+		{
+			name:  "Clerk server error",
+			keyID: "server-error",
+			backend: func(t *testing.T, keyID string) string {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					w.WriteHeader(http.StatusBadGateway)
+					w.Write([]byte(`{"errors":[{"code":"internal_error","message":"bad gateway"}]}`))
+				}))
+				t.Cleanup(server.Close)
+				return server.URL
+			},
+			status: http.StatusServiceUnavailable,
+			code:   models.ErrAuthProviderUnavailable,
+		},
+		{
+			name:  "bad signature",
+			keyID: "bad-signature",
+			backend: func(t *testing.T, keyID string) string {
+				return serveJWKS(t, keyID, &otherKey.PublicKey).URL
+			},
+			status: http.StatusUnauthorized,
+			code:   models.ErrUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			useClerkBackend(t, test.backend(t, test.keyID))
+
+			recorder, response := authenticate(t, signToken(t, signingKey, test.keyID, "user_test"))
+			if recorder.Code != test.status {
+				t.Fatalf("status = %d, want %d: %s", recorder.Code, test.status, recorder.Body.String())
+			}
+			if response.Error == nil || response.Error.Code != test.code {
+				t.Fatalf("error = %+v, want code %s", response.Error, test.code)
+			}
+		})
+	}
+}
+
+func TestAuthMiddlewareMalformedToken(t *testing.T) {
+	recorder, response := authenticate(t, strings.Repeat("x", 20))
+	if recorder.Code != http.StatusUnauthorized {
+		t.Fatalf("status = %d, want %d", recorder.Code, http.StatusUnauthorized)
+	}
+	if response.Error == nil || response.Error.Code != models.ErrUnauthorized {
+		t.Fatalf("error = %+v, want code %s", response.Error, models.ErrUnauthorized)
+	}
+}
+
+func TestAuthMiddlewareSkipsPreflight(t *testing.T) {
+	clerkServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("preflight reached Clerk: %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(clerkServer.Close)
+	useClerkBackend(t, clerkServer.URL)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORS(CORSConfig{
+		AllowOrigins: []string{"http://localhost:3000"},
+		AllowMethods: []string{http.MethodGet, http.MethodPost},
+		AllowHeaders: []string{"Authorization", "Content-Type"},
+	}))
+	// Registered like the /schemas group, behind CORS
+	router.Use(AuthMiddleware(nil, nil, AuthConfig{VerifyMode: VerifyModeOnline}))
+	router.POST("/protected", func(c *gin.Context) {
+		t.Error("preflight reached the handler")
+	})
+
+	request := httptest.NewRequest(http.MethodOptions, "/protected", nil)
+	request.Header.Set("Origin", "http://localhost:3000")
+	request.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	request.Header.Set("Access-Control-Request-Headers", "Authorization")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusNoContent, recorder.Body.String())
+	}
+	if got := recorder.Header().Get("Access-Control-Allow-Origin"); got != "http://localhost:3000" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want http://localhost:3000", got)
+	}
+	if !strings.Contains(strings.Join(recorder.Header().Values("Vary"), ","), "Origin") {
+		t.Errorf("Vary = %q, want Origin", recorder.Header().Values("Vary"))
+	}
+}
+
+func TestAuthMiddlewareSkipsOptionsRequests(t *testing.T) {
+	clerkServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("OPTIONS request reached Clerk: %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	t.Cleanup(clerkServer.Close)
+	useClerkBackend(t, clerkServer.URL)
+
+	// Without CORS in front, an OPTIONS request reaches the middleware itself
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(AuthMiddleware(nil, nil, AuthConfig{VerifyMode: VerifyModeOnline}))
+	router.OPTIONS("/protected", func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodOptions, "/protected", nil))
+
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusNoContent, recorder.Body.String())
+	}
+}
+
+// fakeAPIKeyService authenticates the single key it holds
+type fakeAPIKeyService struct {
+	services.APIKeyService
+	key string
+}
+
+func (s *fakeAPIKeyService) Authenticate(key string) (*models.User, error) {
+	if key != s.key {
+		return nil, services.ErrInvalidAPIKey
+	}
+	return &models.User{ID: uuid.New(), ClerkUserID: "user_test"}, nil
+}
+
+func TestRequireSessionRejectsAPIKeys(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	apiKeys := &fakeAPIKeyService{key: "vdt_test"}
+	router.POST("/user/api-keys", AuthMiddleware(nil, apiKeys, AuthConfig{VerifyMode: VerifyModeOnline}), RequireSession(), func(c *gin.Context) {
+		t.Error("a request authenticated by an API key reached the handler")
+	})
+
+	request := httptest.NewRequest(http.MethodPost, "/user/api-keys", strings.NewReader(`{"name":"escalated"}`))
+	request.Header.Set(APIKeyHeader, "vdt_test")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	if recorder.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusForbidden, recorder.Body.String())
+	}
+	var response models.APIResponse
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	if response.Error == nil || response.Error.Code != models.ErrForbidden {
+		t.Fatalf("error = %+v, want code %s", response.Error, models.ErrForbidden)
+	}
+}
+
+func TestRequireSessionAllowsClerkSessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// Stand in for AuthMiddleware having verified a Clerk session token
+	signIn := func(c *gin.Context) {
+		setAuthenticatedUser(c, &models.User{ID: uuid.New(), ClerkUserID: "user_test"})
+	}
+	router.GET("/user/api-keys", signIn, RequireSession(), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/user/api-keys", nil))
+
+	if recorder.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d: %s", recorder.Code, http.StatusNoContent, recorder.Body.String())
+	}
+}
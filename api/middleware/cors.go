@@ -1,19 +1,37 @@
 package middleware
 
 import (
+	"time"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
 
-// CORS returns a CORS middleware with specified allowed origins
-func CORS(allowedOrigins []string) gin.HandlerFunc {
+// CORSConfig holds the cross-origin settings
+type CORSConfig struct {
+	AllowOrigins []string
+	AllowMethods []string
+	AllowHeaders []string
+	MaxAge       time.Duration
+}
+
+// CORS returns a CORS middleware with the specified origins, methods and headers
+func CORS(corsConfig CORSConfig) gin.HandlerFunc {
 	config := cors.Config{
-		AllowOrigins:     allowedOrigins,
-		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With"},
+		AllowOrigins:     corsConfig.AllowOrigins,
+		AllowMethods:     corsConfig.AllowMethods,
+		AllowHeaders:     corsConfig.AllowHeaders,
 		ExposeHeaders:    []string{"Content-Length"},
 		AllowCredentials: true,
+		MaxAge:           corsConfig.MaxAge,
 	}
 
-	return cors.New(config)
+	corsHandler := cors.New(config)
+
+	return func(c *gin.Context) {
+		// Responses depend on the Origin header even for same-origin requests,
+		// so caches must never share them across origins
+		c.Writer.Header().Add("Vary", "Origin")
+		corsHandler(c)
+	}
 }
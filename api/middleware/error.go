@@ -1,38 +1,66 @@
 package middleware
 
 import (
+	"fmt"
 	"net/http"
 
+	"vdt-dashboard-backend/models"
+
 	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
 )
 
-// ErrorHandler middleware for handling errors consistently
-func ErrorHandler() gin.HandlerFunc {
-	return gin.ErrorLogger()
+// ErrorHandler middleware for handling errors consistently. Outside production
+// unexpected error details are returned to clients to ease debugging.
+func ErrorHandler(environment string) gin.HandlerFunc {
+	verbose := environment != "production"
+	logErrors := gin.ErrorLogger()
+
+	return func(c *gin.Context) {
+		c.Set("verboseErrors", verbose)
+		logErrors(c)
+	}
+}
+
+// ErrorDetails returns the client-facing details of an unexpected error. The
+// full error is always logged; in production the client only receives the
+// request ID needed to find it in the logs.
+func ErrorDetails(c *gin.Context, err error) string {
+	requestID := GetRequestID(c)
+
+	logrus.WithError(err).WithFields(logrus.Fields{
+		"request_id": requestID,
+		"method":     c.Request.Method,
+		"path":       c.Request.URL.Path,
+	}).Error("Request failed")
+
+	if c.GetBool("verboseErrors") {
+		return err.Error()
+	}
+	return fmt.Sprintf("An internal error occurred (request ID: %s)", requestID)
+}
+
+// ErrorResponse creates an error API response carrying the request ID, so
+// clients can report it
+func ErrorResponse(c *gin.Context, message string, code string, details string) *models.APIResponse {
+	response := models.ErrorResponse(message, code, details)
+	response.Error.RequestID = GetRequestID(c)
+	return response
 }
 
 // HandleError is a utility function to handle errors in handlers
 func HandleError(c *gin.Context, err error, message string, statusCode int) {
-	c.JSON(statusCode, gin.H{
-		"success": false,
-		"message": message,
-		"error": gin.H{
-			"code":    getErrorCode(statusCode),
-			"details": err.Error(),
-		},
-	})
+	details := err.Error()
+	if statusCode >= http.StatusInternalServerError {
+		details = ErrorDetails(c, err)
+	}
+
+	c.JSON(statusCode, ErrorResponse(c, message, getErrorCode(statusCode), details))
 }
 
 // HandleValidationError handles validation errors specifically
 func HandleValidationError(c *gin.Context, err error) {
-	c.JSON(http.StatusBadRequest, gin.H{
-		"success": false,
-		"message": "Validation failed",
-		"error": gin.H{
-			"code":    "VALIDATION_ERROR",
-			"details": err.Error(),
-		},
-	})
+	c.JSON(http.StatusBadRequest, ErrorResponse(c, "Validation failed", models.ErrValidation, err.Error()))
 }
 
 // getErrorCode returns appropriate error code based on HTTP status
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/clerk/clerk-sdk-go/v2"
+	"github.com/clerk/clerk-sdk-go/v2/jwt"
+)
+
+// jwkCacheTTL is how long a fetched JSON web key is used before refreshing it
+const jwkCacheTTL = time.Hour
+
+// jwkStore is shared by all auth middleware instances
+var jwkStore = newJWKCache(jwkCacheTTL)
+
+type cachedJWK struct {
+	key       *clerk.JSONWebKey
+	fetchedAt time.Time
+}
+
+// jwkCache caches Clerk JSON web keys by key ID so tokens can be verified
+// without a JWKS request to Clerk on every call
+type jwkCache struct {
+	mu   sync.RWMutex
+	ttl  time.Duration
+	keys map[string]cachedJWK
+}
+
+func newJWKCache(ttl time.Duration) *jwkCache {
+	return &jwkCache{
+		ttl:  ttl,
+		keys: make(map[string]cachedJWK),
+	}
+}
+
+// get returns the key for keyID, fetching it from Clerk when missing or expired.
+// An expired key is still served if Clerk is unavailable, so sessions keep
+// working during short outages.
+func (c *jwkCache) get(ctx context.Context, keyID string) (*clerk.JSONWebKey, error) {
+	c.mu.RLock()
+	cached, found := c.keys[keyID]
+	c.mu.RUnlock()
+
+	if found && time.Since(cached.fetchedAt) < c.ttl {
+		return cached.key, nil
+	}
+
+	key, err := jwt.GetJSONWebKey(ctx, &jwt.GetJSONWebKeyParams{KeyID: keyID})
+	if err != nil {
+		if found && isClerkUnavailable(err) {
+			return cached.key, nil
+		}
+		return nil, err
+	}
+
+	c.mu.Lock()
+	c.keys[keyID] = cachedJWK{key: key, fetchedAt: time.Now()}
+	c.mu.Unlock()
+
+	return key, nil
+}
@@ -1,6 +1,8 @@
 package middleware
 
 import (
+	"vdt-dashboard-backend/models"
+
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 )
@@ -10,6 +12,7 @@ func Logger() gin.HandlerFunc {
 	return gin.LoggerWithConfig(gin.LoggerConfig{
 		Formatter: func(param gin.LogFormatterParams) string {
 			log := logrus.WithFields(logrus.Fields{
+				"request_id": param.Keys[requestIDKey],
 				"status":     param.StatusCode,
 				"method":     param.Method,
 				"path":       param.Path,
@@ -18,7 +21,9 @@ func Logger() gin.HandlerFunc {
 				"user_agent": param.Request.UserAgent(),
 			})
 
-			if param.StatusCode >= 400 {
+			if param.Method == "OPTIONS" {
+				log.Debug("CORS preflight")
+			} else if param.StatusCode >= 400 {
 				log.Error("HTTP Request")
 			} else {
 				log.Info("HTTP Request")
@@ -35,17 +40,12 @@ func Logger() gin.HandlerFunc {
 func Recovery() gin.HandlerFunc {
 	return gin.CustomRecovery(func(c *gin.Context, recovered interface{}) {
 		logrus.WithFields(logrus.Fields{
-			"panic": recovered,
-			"path":  c.Request.URL.Path,
+			"request_id": GetRequestID(c),
+			"panic":      recovered,
+			"method":     c.Request.Method,
+			"path":       c.Request.URL.Path,
 		}).Error("Panic recovered")
 
-		c.JSON(500, gin.H{
-			"success": false,
-			"message": "Internal server error",
-			"error": map[string]interface{}{
-				"code":    "INTERNAL_ERROR",
-				"details": "An unexpected error occurred",
-			},
-		})
+		c.JSON(500, ErrorResponse(c, "Internal server error", models.ErrInternalError, "An unexpected error occurred"))
 	})
 }
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"time"
+
+	"vdt-dashboard-backend/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics returns a Gin middleware recording the count and latency of
+// requests. Requests are labelled with the route template, like
+// /api/v1/schemas/:id, and requests matching no route with "unmatched".
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		metrics.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
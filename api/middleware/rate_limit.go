@@ -0,0 +1,114 @@
+package middleware
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// rateLimitSweepInterval is how often buckets of idle clients are forgotten
+const rateLimitSweepInterval = time.Minute
+
+// tokenBucket holds the remaining requests of one client
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// rateLimiter keeps a token bucket per client. Each bucket holds up to a
+// minute's worth of requests and refills continuously.
+type rateLimiter struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	capacity  float64
+	perSecond float64
+	swept     time.Time
+}
+
+// RateLimit limits every client to requestsPerMinute requests, rejecting the
+// rest with 429 and a Retry-After header. Clients are the authenticated user
+// when the route sets one, otherwise the client IP, so it must run after
+// AuthMiddleware on protected routes. A limit of 0 disables it. Each call
+// returns an independent limiter.
+func RateLimit(requestsPerMinute int) gin.HandlerFunc {
+	if requestsPerMinute <= 0 {
+		return func(c *gin.Context) { c.Next() }
+	}
+
+	limiter := &rateLimiter{
+		buckets:   make(map[string]*tokenBucket),
+		capacity:  float64(requestsPerMinute),
+		perSecond: float64(requestsPerMinute) / 60,
+		swept:     time.Now(),
+	}
+
+	return func(c *gin.Context) {
+		key := "ip:" + c.ClientIP()
+		if userID, exists := GetUserIDFromContext(c); exists {
+			key = "user:" + userID.String()
+		}
+
+		retryAfter, allowed := limiter.take(key, time.Now())
+		if allowed {
+			c.Next()
+			return
+		}
+
+		c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+		c.JSON(http.StatusTooManyRequests, ErrorResponse(c, "Too many requests", models.ErrRateLimited,
+			fmt.Sprintf("Rate limit of %d requests per minute exceeded", requestsPerMinute)))
+		c.Abort()
+	}
+}
+
+// take spends a token of a client's bucket. When the bucket is empty it
+// returns how long until a token is available.
+func (l *rateLimiter) take(key string, now time.Time) (time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	bucket, exists := l.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: l.capacity, updated: now}
+		l.buckets[key] = bucket
+	}
+	bucket.tokens = l.refilled(bucket, now)
+	bucket.updated = now
+
+	if bucket.tokens < 1 {
+		wait := (1 - bucket.tokens) / l.perSecond
+		return time.Duration(wait * float64(time.Second)), false
+	}
+	bucket.tokens--
+	return 0, true
+}
+
+// refilled returns the tokens of a bucket at now
+func (l *rateLimiter) refilled(bucket *tokenBucket, now time.Time) float64 {
+	elapsed := now.Sub(bucket.updated).Seconds()
+	return math.Min(l.capacity, bucket.tokens+elapsed*l.perSecond)
+}
+
+// sweep forgets the buckets that have refilled completely, which behave like
+// new ones, so clients that went away don't accumulate
+func (l *rateLimiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < rateLimitSweepInterval {
+		return
+	}
+	l.swept = now
+
+	for key, bucket := range l.buckets {
+		if l.refilled(bucket, now) >= l.capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
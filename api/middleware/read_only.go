@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReadOnly rejects mutating requests with 503 while the service is in
+// maintenance mode. GET, HEAD and OPTIONS requests are still served.
+func ReadOnly(enabled bool, retryAfter time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !enabled {
+			c.Next()
+			return
+		}
+
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			c.Next()
+			return
+		}
+
+		c.Header("Retry-After", strconv.Itoa(int(retryAfter.Seconds())))
+		c.JSON(http.StatusServiceUnavailable, ErrorResponse(c, "Service is in read-only mode", models.ErrReadOnlyMode, "Changes are disabled during maintenance, retry later"))
+		c.Abort()
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestIDHeader carries the request correlation ID
+const RequestIDHeader = "X-Request-ID"
+
+// requestIDKey stores the request correlation ID in the gin context
+const requestIDKey = "requestID"
+
+// maxRequestIDLength bounds client-supplied request IDs
+const maxRequestIDLength = 128
+
+// RequestID assigns every request a correlation ID, reusing the client's
+// X-Request-ID when present, and echoes it in the response
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = uuid.New().String()
+		}
+
+		c.Set(requestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		c.Next()
+	}
+}
+
+// GetRequestID extracts the request correlation ID from gin context
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
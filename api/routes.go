@@ -11,53 +11,124 @@ import (
 	"gorm.io/gorm"
 )
 
+// Services are the long-running services behind the routes, which the server
+// stops on shutdown
+type Services struct {
+	Schema          services.SchemaService
+	DatabaseManager services.DatabaseManagerService
+}
+
 // SetupRoutes configures all API routes
-func SetupRoutes(router *gin.RouterGroup, db *gorm.DB, cfg *config.Config) {
+func SetupRoutes(router *gin.RouterGroup, db *gorm.DB, cfg *config.Config) Services {
 	// Initialize repositories
 	schemaRepo := repositories.NewSchemaRepository(db)
+	idempotencyKeyRepo := repositories.NewIdempotencyKeyRepository(db)
 	userRepo := repositories.NewUserRepository(db)
+	apiKeyRepo := repositories.NewAPIKeyRepository(db)
+	collaboratorRepo := repositories.NewSchemaCollaboratorRepository(db)
+	versionRepo := repositories.NewSchemaVersionRepository(db)
 
 	// Initialize services
 	databaseManagerService := services.NewDatabaseManagerService(cfg)
-	schemaService := services.NewSchemaService(schemaRepo, databaseManagerService, cfg)
-	validatorService := services.NewValidatorService()
-	sqlGeneratorService := services.NewSQLGeneratorService()
+	validatorService := services.NewValidatorService(cfg)
+	sqlGeneratorService := services.NewSQLGeneratorService(cfg)
+	schemaService := services.NewSchemaService(schemaRepo, idempotencyKeyRepo, collaboratorRepo, versionRepo, userRepo, databaseManagerService, validatorService, sqlGeneratorService, cfg)
+	schemaLinter := services.NewSchemaLinter(cfg)
+	dbmlGeneratorService := services.NewDBMLGeneratorService(cfg)
+	apiKeyService := services.NewAPIKeyService(apiKeyRepo, userRepo)
+	importService := services.NewSchemaImportService(cfg)
+
+	authConfig := middleware.AuthConfig{
+		SecretKey:  cfg.ClerkSecretKey,
+		VerifyMode: cfg.ClerkVerifyMode,
+	}
 
 	// Initialize handlers
-	schemaHandler := handlers.NewSchemaHandler(schemaService)
-	healthHandler := handlers.NewHealthHandler(db)
+	schemaHandler := handlers.NewSchemaHandler(schemaService, dbmlGeneratorService)
+	healthHandler := handlers.NewHealthHandler(db, cfg, schemaRepo, databaseManagerService)
 	validatorHandler := handlers.NewValidatorHandler(validatorService, sqlGeneratorService)
+	lintHandler := handlers.NewLintHandler(schemaLinter)
 	databaseHandler := handlers.NewDatabaseHandler(databaseManagerService, schemaService)
 	userHandler := handlers.NewUserHandler()
+	apiKeyHandler := handlers.NewAPIKeyHandler(apiKeyService)
+	importHandler := handlers.NewImportHandler(importService)
+
+	// Endpoints that provision databases are limited more strictly
+	writeLimit := middleware.RateLimit(cfg.RateLimitWritePerMinute)
 
 	// Health check
 	router.GET("/health", healthHandler.HealthCheck)
+	router.GET("/health/detailed", healthHandler.DetailedHealthCheck)
+	router.GET("/health/live", healthHandler.Liveness)
+	router.GET("/health/ready", healthHandler.Readiness)
 
 	// User routes (protected)
 	userRoutes := router.Group("/user")
-	userRoutes.Use(middleware.AuthMiddleware(userRepo, cfg.ClerkSecretKey)) // Apply authentication middleware
+	userRoutes.Use(middleware.ReadOnly(cfg.ReadOnly, cfg.ReadOnlyRetryAfter))
+	userRoutes.Use(middleware.AuthMiddleware(userRepo, apiKeyService, authConfig)) // Apply authentication middleware
 	{
 		userRoutes.GET("/me", userHandler.GetCurrentUser)
+
+		// API keys are managed with a Clerk session only
+		apiKeyRoutes := userRoutes.Group("/api-keys", middleware.RequireSession())
+		apiKeyRoutes.POST("", apiKeyHandler.CreateAPIKey)
+		apiKeyRoutes.GET("", apiKeyHandler.ListAPIKeys)
+		apiKeyRoutes.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
 	}
 
 	// Schema management routes (protected)
 	schemaRoutes := router.Group("/schemas")
-	schemaRoutes.Use(middleware.AuthMiddleware(userRepo, cfg.ClerkSecretKey)) // Apply authentication middleware
+	schemaRoutes.Use(middleware.ReadOnly(cfg.ReadOnly, cfg.ReadOnlyRetryAfter))
+	schemaRoutes.Use(middleware.AuthMiddleware(userRepo, apiKeyService, authConfig)) // Apply authentication middleware
+	schemaRoutes.Use(middleware.RateLimit(cfg.RateLimitPerMinute))
 	{
-		schemaRoutes.POST("", schemaHandler.CreateSchema)
+		schemaRoutes.POST("", writeLimit, schemaHandler.CreateSchema)
 		schemaRoutes.GET("", schemaHandler.ListSchemas)
 		schemaRoutes.GET("/:id", schemaHandler.GetSchema)
-		schemaRoutes.PUT("/:id", schemaHandler.UpdateSchema)
+		schemaRoutes.GET("/:id/events", schemaHandler.StreamEvents)
+		schemaRoutes.PUT("/:id", writeLimit, schemaHandler.UpdateSchema)
+		schemaRoutes.POST("/:id/diff", schemaHandler.DiffSchema)
+		schemaRoutes.POST("/:id/sql/preview", schemaHandler.PreviewSQL)
 		schemaRoutes.DELETE("/:id", schemaHandler.DeleteSchema)
+		schemaRoutes.POST("/bulk-delete", writeLimit, schemaHandler.BulkDeleteSchemas)
+		schemaRoutes.PATCH("/:id/favorite", schemaHandler.SetFavorite)
+		schemaRoutes.POST("/:id/clone", writeLimit, schemaHandler.CloneSchema)
+		schemaRoutes.POST("/:id/restore", writeLimit, schemaHandler.RestoreSchema)
+		schemaRoutes.POST("/:id/share", schemaHandler.ShareSchema)
+		schemaRoutes.DELETE("/:id/share/:userId", schemaHandler.UnshareSchema)
+		schemaRoutes.GET("/:id/versions", schemaHandler.ListVersions)
+		schemaRoutes.POST("/:id/versions/:version/rollback", writeLimit, schemaHandler.RollbackSchema)
+		schemaRoutes.GET("/:id/tables", schemaHandler.ListTables)
+		schemaRoutes.GET("/:id/relationships", schemaHandler.ListRelationships)
+		schemaRoutes.POST("/:id/tables/:tableId/data", writeLimit, schemaHandler.ImportTableData)
+		schemaRoutes.POST("/:id/query", schemaHandler.ExecuteQuery)
 
 		// Schema export
 		schemaRoutes.GET("/:id/export/sql", schemaHandler.ExportSQL)
+		schemaRoutes.GET("/:id/export/typescript", schemaHandler.ExportTypeScript)
+		schemaRoutes.GET("/:id/export/diagram.svg", schemaHandler.ExportDiagram)
+		schemaRoutes.GET("/:id/export/dbml", schemaHandler.ExportDBML)
+		schemaRoutes.GET("/:id/tables/:tableId/ddl", schemaHandler.GetTableDDL)
 
 		// Database management
 		schemaRoutes.GET("/:id/database/status", databaseHandler.GetDatabaseStatus)
-		schemaRoutes.POST("/:id/database/regenerate", databaseHandler.RegenerateDatabase)
+		schemaRoutes.GET("/:id/database/drift", databaseHandler.GetDatabaseDrift)
+		schemaRoutes.GET("/:id/database/tables", databaseHandler.ListLiveTables)
+		schemaRoutes.POST("/:id/database/regenerate", writeLimit, databaseHandler.RegenerateDatabase)
+		schemaRoutes.GET("/:id/database/job", databaseHandler.GetGenerationJob)
+		schemaRoutes.POST("/:id/database/foreign-keys/rebuild", databaseHandler.RebuildForeignKeys)
 	}
 
 	// Validation routes
-	router.POST("/schemas/validate", validatorHandler.ValidateSchema)
+	validationLimit := middleware.RateLimit(cfg.RateLimitValidationPerMinute)
+	router.POST("/schemas/validate", validationLimit, validatorHandler.ValidateSchema)
+	router.POST("/schemas/validate/batch", validationLimit, validatorHandler.ValidateSchemaBatch)
+	router.POST("/schemas/lint", validationLimit, lintHandler.LintSchema)
+	router.POST("/schemas/import/json-schema", validationLimit, importHandler.ImportJSONSchema)
+	router.POST("/schemas/import/sql", validationLimit, importHandler.ImportSQL)
+
+	return Services{
+		Schema:          schemaService,
+		DatabaseManager: databaseManagerService,
+	}
 }
@@ -1,8 +1,17 @@
 package api
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
+
 	"vdt-dashboard-backend/api/middleware"
 	"vdt-dashboard-backend/config"
+	"vdt-dashboard-backend/metrics"
 
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
@@ -10,9 +19,10 @@ import (
 
 // Server represents the HTTP server
 type Server struct {
-	router *gin.Engine
-	db     *gorm.DB
-	config *config.Config
+	router   *gin.Engine
+	db       *gorm.DB
+	config   *config.Config
+	services Services
 }
 
 // NewServer creates a new HTTP server
@@ -32,10 +42,22 @@ func (s *Server) setupRouter() {
 	s.router = gin.New()
 
 	// Add middleware
+	s.router.Use(middleware.RequestID())
 	s.router.Use(middleware.Logger())
 	s.router.Use(middleware.Recovery())
-	s.router.Use(middleware.CORS(s.config.AllowOrigins))
-	s.router.Use(middleware.ErrorHandler())
+	s.router.Use(middleware.CORS(middleware.CORSConfig{
+		AllowOrigins: s.config.AllowOrigins,
+		AllowMethods: s.config.CORSAllowMethods,
+		AllowHeaders: s.config.CORSAllowHeaders,
+		MaxAge:       s.config.CORSMaxAge,
+	}))
+	s.router.Use(middleware.ErrorHandler(s.config.Environment))
+	if s.config.MetricsEnabled {
+		s.router.Use(middleware.Metrics())
+	}
+	if s.config.OTLPEndpoint != "" {
+		s.router.Use(middleware.Tracing())
+	}
 
 	// Setup routes
 	s.setupRoutes()
@@ -47,7 +69,13 @@ func (s *Server) setupRoutes() {
 	v1 := s.router.Group("/api/v1")
 
 	// Initialize routes
-	SetupRoutes(v1, s.db, s.config)
+	s.services = SetupRoutes(v1, s.db, s.config)
+
+	// Prometheus scrapes the conventional path, outside the API
+	if s.config.MetricsEnabled {
+		metrics.SetPoolStats(s.services.DatabaseManager.PoolStats)
+		s.router.GET("/metrics", gin.WrapH(metrics.Handler()))
+	}
 }
 
 // Run starts the HTTP server
@@ -55,6 +83,68 @@ func (s *Server) Run(addr string) error {
 	return s.router.Run(addr)
 }
 
+// RunWithGracefulShutdown starts the HTTP server and serves until SIGINT or
+// SIGTERM. It then stops accepting requests and waits up to the configured
+// shutdown timeout for in-flight requests and background database jobs
+// before closing the database connections, so a deploy doesn't leave schema
+// databases half-built. A second signal exits immediately.
+func (s *Server) RunWithGracefulShutdown(addr string) error {
+	httpServer := &http.Server{
+		Addr:    addr,
+		Handler: s.router,
+	}
+	// Event streams otherwise last until their schema settles, holding up
+	// the shutdown
+	httpServer.RegisterOnShutdown(s.services.Schema.CloseEvents)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- httpServer.ListenAndServe()
+	}()
+
+	select {
+	case err := <-serveErr:
+		return errors.Join(err, s.Close())
+	case <-ctx.Done():
+	}
+	stop()
+
+	log.Printf("Shutting down, waiting up to %s for requests and database jobs", s.config.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), s.config.ShutdownTimeout)
+	defer cancel()
+
+	var errs []error
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		errs = append(errs, fmt.Errorf("failed to finish requests: %w", err))
+	}
+	// Requests can queue jobs until they finish, so jobs are awaited last
+	if err := s.services.Schema.WaitForJobs(shutdownCtx); err != nil {
+		errs = append(errs, err)
+	}
+	if err := s.Close(); err != nil {
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// Close releases the connections the server holds to the metadata database
+// and schema databases
+func (s *Server) Close() error {
+	var errs []error
+	if err := s.services.DatabaseManager.Close(); err != nil {
+		errs = append(errs, fmt.Errorf("failed to close schema database connections: %w", err))
+	}
+	if sqlDB, err := s.db.DB(); err == nil {
+		if err := sqlDB.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close database connection: %w", err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 // GetRouter returns the Gin router instance
 func (s *Server) GetRouter() *gin.Engine {
 	return s.router
@@ -1,43 +1,207 @@
 package config
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"vdt-dashboard-backend/models"
 )
 
 // Config holds all configuration for the application
 type Config struct {
-	Environment    string
-	Port           string
-	DatabaseURL    string
-	DatabaseHost   string
-	DatabasePort   string
-	DatabaseUser   string
-	DatabasePass   string
-	DatabaseName   string
-	LogLevel       string
-	AllowOrigins   []string
-	ClerkSecretKey string
+	Environment          string
+	Port                 string
+	DatabaseDriver       string
+	DatabaseURL          string
+	DatabaseHost         string
+	DatabasePort         string
+	DatabaseUser         string
+	DatabasePass         string
+	DatabaseName         string
+	DBSSLMode            string
+	DBSSLRootCert        string
+	LogLevel             string
+	AllowOrigins         []string
+	ClerkSecretKey       string
+	ClerkVerifyMode      string
+	CORSAllowMethods     []string
+	CORSAllowHeaders     []string
+	CORSMaxAge           time.Duration
+	DefaultFKOnDelete    string
+	DefaultFKOnUpdate    string
+	AutoIndexForeignKeys bool
+	IdentifierCase       string
+	QuoteReserved        bool
+	ReadOnly             bool
+	ReadOnlyRetryAfter   time.Duration
+	SkipCreateDBCheck    bool
+	GenerationWorkers    int
+	IdempotencyKeyTTL    time.Duration
+	ShutdownTimeout      time.Duration
+	QueryRowLimit        int
+	QueryTimeout         time.Duration
+	QueryMaxBytes        int
+	QueryRole            string
+	MetricsEnabled       bool
+	OTLPEndpoint         string
+
+	// Requests per minute per client, 0 disables the limit
+	RateLimitPerMinute           int
+	RateLimitWritePerMinute      int
+	RateLimitValidationPerMinute int
+
+	// Connection pool of the application database
+	DBMaxIdleConns           int
+	DBMaxOpenConns           int
+	DBConnMaxLifetimeMinutes int
+
+	// Connection pools kept open to generated databases
+	DynamicDBMaxCached    int
+	DynamicDBMaxIdleConns int
+	DynamicDBMaxOpenConns int
+	DynamicDBIdleTimeout  int
 }
 
 // Load loads configuration from environment variables
 func Load() *Config {
 	return &Config{
-		Environment:    getEnv("ENVIRONMENT", "development"),
-		Port:           getEnv("PORT", "8080"),
-		DatabaseURL:    getEnv("DATABASE_URL", ""),
-		DatabaseHost:   getEnv("DB_HOST", "localhost"),
-		DatabasePort:   getEnv("DB_PORT", "5432"),
-		DatabaseUser:   getEnv("DB_USER", "postgres"),
-		DatabasePass:   getEnv("DB_PASSWORD", "postgres"),
-		DatabaseName:   getEnv("DB_NAME", "vdt_dashboard"),
-		LogLevel:       getEnv("LOG_LEVEL", "info"),
-		ClerkSecretKey: getEnv("CLERK_SECRET_KEY", ""),
+		Environment:     getEnv("ENVIRONMENT", "development"),
+		Port:            getEnv("PORT", "8080"),
+		DatabaseDriver:  getEnv("DB_DRIVER", DriverPostgres),
+		DatabaseURL:     getEnv("DATABASE_URL", ""),
+		DatabaseHost:    getEnv("DB_HOST", "localhost"),
+		DatabasePort:    getEnv("DB_PORT", "5432"),
+		DatabaseUser:    getEnv("DB_USER", "postgres"),
+		DatabasePass:    getEnv("DB_PASSWORD", "postgres"),
+		DatabaseName:    getEnv("DB_NAME", "vdt_dashboard"),
+		DBSSLMode:       getEnv("DB_SSL_MODE", "disable"),
+		DBSSLRootCert:   getEnv("DB_SSL_ROOT_CERT", ""),
+		LogLevel:        getEnv("LOG_LEVEL", "info"),
+		ClerkSecretKey:  getEnv("CLERK_SECRET_KEY", ""),
+		ClerkVerifyMode: getEnv("CLERK_VERIFY_MODE", "online"),
 		AllowOrigins: []string{
 			getEnv("FRONTEND_URL", "http://localhost:3000"),
 			getEnv("STORYBOOK_URL", "http://localhost:6006"),
 		},
+		CORSAllowMethods:     getEnvAsSlice("CORS_ALLOW_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+		CORSAllowHeaders:     getEnvAsSlice("CORS_ALLOW_HEADERS", []string{"Origin", "Content-Type", "Accept", "Authorization", "X-Requested-With", "Idempotency-Key", "X-API-Key"}),
+		CORSMaxAge:           time.Duration(getEnvAsInt("CORS_MAX_AGE", 43200)) * time.Second,
+		DefaultFKOnDelete:    getEnv("DEFAULT_FK_ON_DELETE", "RESTRICT"),
+		DefaultFKOnUpdate:    getEnv("DEFAULT_FK_ON_UPDATE", "RESTRICT"),
+		AutoIndexForeignKeys: getEnvAsBool("AUTO_INDEX_FOREIGN_KEYS", false),
+		IdentifierCase:       getEnv("IDENTIFIER_CASE", models.IdentifierCasePreserve),
+		QuoteReserved:        getEnvAsBool("QUOTE_RESERVED_IDENTIFIERS", true),
+		ReadOnly:             getEnvAsBool("READ_ONLY", false),
+		ReadOnlyRetryAfter:   time.Duration(getEnvAsInt("READ_ONLY_RETRY_AFTER", 300)) * time.Second,
+		SkipCreateDBCheck:    getEnvAsBool("SKIP_CREATEDB_CHECK", false),
+		GenerationWorkers:    getEnvAsInt("GENERATION_WORKERS", 4),
+		IdempotencyKeyTTL:    time.Duration(getEnvAsInt("IDEMPOTENCY_KEY_TTL", 86400)) * time.Second,
+		ShutdownTimeout:      time.Duration(getEnvAsInt("SHUTDOWN_TIMEOUT", 30)) * time.Second,
+		QueryRowLimit:        getEnvAsInt("QUERY_ROW_LIMIT", 1000),
+		QueryTimeout:         time.Duration(getEnvAsInt("QUERY_TIMEOUT", 10)) * time.Second,
+		QueryMaxBytes:        getEnvAsInt("QUERY_MAX_BYTES", 10<<20),
+		QueryRole:            getEnv("QUERY_ROLE", "vdt_query_reader"),
+		MetricsEnabled:       getEnvAsBool("METRICS_ENABLED", false),
+		OTLPEndpoint:         getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+
+		RateLimitPerMinute:           getEnvAsInt("RATE_LIMIT_PER_MINUTE", 100),
+		RateLimitWritePerMinute:      getEnvAsInt("RATE_LIMIT_WRITE_PER_MINUTE", 10),
+		RateLimitValidationPerMinute: getEnvAsInt("RATE_LIMIT_VALIDATION_PER_MINUTE", 50),
+
+		DBMaxIdleConns:           getEnvAsInt("DB_MAX_IDLE_CONNS", 10),
+		DBMaxOpenConns:           getEnvAsInt("DB_MAX_OPEN_CONNS", 100),
+		DBConnMaxLifetimeMinutes: getEnvAsInt("DB_CONN_MAX_LIFETIME_MINUTES", 60),
+
+		DynamicDBMaxCached:    getEnvAsInt("DYNAMIC_DB_MAX_CACHED", 20),
+		DynamicDBMaxIdleConns: getEnvAsInt("DYNAMIC_DB_MAX_IDLE_CONNS", 2),
+		DynamicDBMaxOpenConns: getEnvAsInt("DYNAMIC_DB_MAX_OPEN_CONNS", 5),
+		DynamicDBIdleTimeout:  getEnvAsInt("DYNAMIC_DB_IDLE_TIMEOUT", 300),
+	}
+}
+
+// Validate checks the configuration for invalid combinations
+func (c *Config) Validate() error {
+	// Credentialed requests are rejected by browsers when the origin is a wildcard
+	for _, origin := range c.AllowOrigins {
+		if origin == "*" {
+			return errors.New("CORS origin \"*\" is not allowed because credentials are enabled, list the allowed origins explicitly")
+		}
+	}
+
+	if c.ClerkVerifyMode != "online" && c.ClerkVerifyMode != "offline" {
+		return fmt.Errorf("CLERK_VERIFY_MODE %q must be online or offline", c.ClerkVerifyMode)
+	}
+
+	// Generated databases are reached by swapping the database of DATABASE_URL
+	if c.DatabaseDriver == DriverPostgres && c.DatabaseURL != "" {
+		if _, err := withDatabaseName(c.DatabaseURL, maintenanceDatabase); err != nil {
+			return err
+		}
+	}
+
+	if !validSSLModes[c.DBSSLMode] {
+		return fmt.Errorf("DB_SSL_MODE %q must be disable, allow, prefer, require, verify-ca or verify-full", c.DBSSLMode)
+	}
+	if c.DBSSLRootCert != "" {
+		if _, err := os.Stat(c.DBSSLRootCert); err != nil {
+			return fmt.Errorf("DB_SSL_ROOT_CERT is not readable: %w", err)
+		}
+	}
+
+	if !models.ValidForeignKeyActions[c.DefaultFKOnDelete] {
+		return fmt.Errorf("DEFAULT_FK_ON_DELETE %q is not a valid foreign key action", c.DefaultFKOnDelete)
 	}
+	if !models.ValidForeignKeyActions[c.DefaultFKOnUpdate] {
+		return fmt.Errorf("DEFAULT_FK_ON_UPDATE %q is not a valid foreign key action", c.DefaultFKOnUpdate)
+	}
+	if !models.ValidIdentifierCases[c.IdentifierCase] {
+		return fmt.Errorf("IDENTIFIER_CASE %q must be preserve, snake or lower", c.IdentifierCase)
+	}
+	if c.GenerationWorkers < 1 {
+		return fmt.Errorf("GENERATION_WORKERS must be at least 1, got %d", c.GenerationWorkers)
+	}
+	if c.RateLimitPerMinute < 0 || c.RateLimitWritePerMinute < 0 || c.RateLimitValidationPerMinute < 0 {
+		return errors.New("rate limits must not be negative, use 0 to disable a limit")
+	}
+	if c.ShutdownTimeout <= 0 {
+		return fmt.Errorf("SHUTDOWN_TIMEOUT must be positive, got %s", c.ShutdownTimeout)
+	}
+	if c.QueryRowLimit < 1 {
+		return fmt.Errorf("QUERY_ROW_LIMIT must be at least 1, got %d", c.QueryRowLimit)
+	}
+	if c.QueryTimeout <= 0 {
+		return fmt.Errorf("QUERY_TIMEOUT must be positive, got %s", c.QueryTimeout)
+	}
+	if c.QueryMaxBytes < 1024 {
+		return fmt.Errorf("QUERY_MAX_BYTES must be at least 1024, got %d", c.QueryMaxBytes)
+	}
+	if c.QueryRole == "" {
+		return errors.New("QUERY_ROLE must not be empty")
+	}
+	if c.DBMaxOpenConns < 1 {
+		return fmt.Errorf("DB_MAX_OPEN_CONNS must be at least 1, got %d", c.DBMaxOpenConns)
+	}
+	if c.DBMaxIdleConns < 0 || c.DBMaxIdleConns > c.DBMaxOpenConns {
+		return fmt.Errorf("DB_MAX_IDLE_CONNS must be between 0 and DB_MAX_OPEN_CONNS, got %d", c.DBMaxIdleConns)
+	}
+	if c.DBConnMaxLifetimeMinutes < 0 {
+		return fmt.Errorf("DB_CONN_MAX_LIFETIME_MINUTES must not be negative, use 0 to keep connections indefinitely, got %d", c.DBConnMaxLifetimeMinutes)
+	}
+	if c.DynamicDBMaxCached < 1 {
+		return fmt.Errorf("DYNAMIC_DB_MAX_CACHED must be at least 1, got %d", c.DynamicDBMaxCached)
+	}
+	if c.DynamicDBMaxOpenConns < 1 {
+		return fmt.Errorf("DYNAMIC_DB_MAX_OPEN_CONNS must be at least 1, got %d", c.DynamicDBMaxOpenConns)
+	}
+	if c.DynamicDBMaxIdleConns < 0 || c.DynamicDBMaxIdleConns > c.DynamicDBMaxOpenConns {
+		return fmt.Errorf("DYNAMIC_DB_MAX_IDLE_CONNS must be between 0 and DYNAMIC_DB_MAX_OPEN_CONNS, got %d", c.DynamicDBMaxIdleConns)
+	}
+
+	return nil
 }
 
 // getEnv gets an environment variable with a fallback value
@@ -58,6 +222,22 @@ func getEnvAsInt(key string, fallback int) int {
 	return fallback
 }
 
+// getEnvAsSlice gets a comma-separated environment variable as a slice with a fallback value
+func getEnvAsSlice(key string, fallback []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return fallback
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 // getEnvAsBool gets an environment variable as boolean with a fallback value
 func getEnvAsBool(key string, fallback bool) bool {
 	if value := os.Getenv(key); value != "" {
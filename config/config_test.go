@@ -0,0 +1,31 @@
+package config
+
+import "testing"
+
+func TestValidateClerkVerifyMode(t *testing.T) {
+	tests := []struct {
+		mode  string
+		valid bool
+	}{
+		{mode: "online", valid: true},
+		{mode: "offline", valid: true},
+		{mode: "", valid: false},
+		{mode: "Offline", valid: false},
+		{mode: "ofline", valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.mode, func(t *testing.T) {
+			cfg := Load()
+			cfg.ClerkVerifyMode = test.mode
+
+			err := cfg.Validate()
+			if test.valid && err != nil {
+				t.Fatalf("Validate() = %v, want nil", err)
+			}
+			if !test.valid && err == nil {
+				t.Fatal("Validate() = nil, want an error")
+			}
+		})
+	}
+}
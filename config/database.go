@@ -1,31 +1,89 @@
 package config
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net/url"
+	"regexp"
+	"strings"
 	"time"
 
+	"vdt-dashboard-backend/tracing"
+
+	"github.com/glebarez/sqlite"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
 )
 
+// ErrDatabaseExists is returned when a database name is already taken
+var ErrDatabaseExists = errors.New("database already exists")
+
+// ErrInvalidDatabaseName is returned for a database name that isn't a plain
+// lowercase PostgreSQL identifier
+var ErrInvalidDatabaseName = errors.New("invalid database name")
+
+// databaseNamePattern matches the names given to generated databases. They
+// are interpolated into DDL unquoted, so nothing else is accepted.
+var databaseNamePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,62}$`)
+
+// validateDatabaseName checks that a generated database name is safe to
+// interpolate into DDL and fits PostgreSQL's 63 byte identifier limit
+func validateDatabaseName(name string) error {
+	if !databaseNamePattern.MatchString(name) {
+		return fmt.Errorf("%w: %q must be 1 to 63 lowercase letters, digits or underscores, not starting with a digit", ErrInvalidDatabaseName, name)
+	}
+	return nil
+}
+
+// ErrLockHeld is returned when another session holds an advisory lock
+var ErrLockHeld = errors.New("lock is held by another session")
+
+// Supported drivers for the metadata database
+const (
+	DriverPostgres = "postgres"
+	// DriverSQLite is meant for tests; it only backs the metadata repositories
+	// and cannot provision dynamic databases
+	DriverSQLite = "sqlite"
+)
+
+// validSSLModes are the sslmode values understood by PostgreSQL clients
+var validSSLModes = map[string]bool{
+	"disable":     true,
+	"allow":       true,
+	"prefer":      true,
+	"require":     true,
+	"verify-ca":   true,
+	"verify-full": true,
+}
+
+// SSLSettings returns the sslmode, and the root certificate verifying the
+// server when one is configured, as keyword/value DSN settings
+func (c *Config) SSLSettings() string {
+	settings := "sslmode=" + c.DBSSLMode
+	if c.DBSSLRootCert != "" {
+		settings += " sslrootcert=" + quoteDSNValue(c.DBSSLRootCert)
+	}
+	return settings
+}
+
+// quoteDSNValue quotes a keyword/value DSN value so it may contain spaces
+func quoteDSNValue(value string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(value) + "'"
+}
+
+// defaultSQLiteDSN is an in-memory database shared by all connections of the pool
+const defaultSQLiteDSN = "file::memory:?cache=shared"
+
 // InitDatabase initializes the database connection
 func InitDatabase(config *Config) (*gorm.DB, error) {
-	var dsn string
-
-	// Use DATABASE_URL if provided, otherwise construct from individual components
-	if config.DatabaseURL != "" {
-		dsn = config.DatabaseURL
-	} else {
-		dsn = fmt.Sprintf(
-			"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable TimeZone=UTC",
-			config.DatabaseHost,
-			config.DatabasePort,
-			config.DatabaseUser,
-			config.DatabasePass,
-			config.DatabaseName,
-		)
+	dialector, err := metadataDialector(config)
+	if err != nil {
+		return nil, err
 	}
 
 	// Configure GORM logger
@@ -37,7 +95,7 @@ func InitDatabase(config *Config) (*gorm.DB, error) {
 	}
 
 	// Open database connection
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	db, err := gorm.Open(dialector, &gorm.Config{
 		Logger: gormLogger,
 		NowFunc: func() time.Time {
 			return time.Now().UTC()
@@ -55,9 +113,11 @@ func InitDatabase(config *Config) (*gorm.DB, error) {
 	}
 
 	// Set connection pool settings
-	sqlDB.SetMaxIdleConns(10)
-	sqlDB.SetMaxOpenConns(100)
-	sqlDB.SetConnMaxLifetime(time.Hour)
+	sqlDB.SetMaxIdleConns(config.DBMaxIdleConns)
+	sqlDB.SetMaxOpenConns(config.DBMaxOpenConns)
+	sqlDB.SetConnMaxLifetime(time.Duration(config.DBConnMaxLifetimeMinutes) * time.Minute)
+	log.Printf("Database pool: max %d open connections, %d idle, %d minute lifetime",
+		config.DBMaxOpenConns, config.DBMaxIdleConns, config.DBConnMaxLifetimeMinutes)
 
 	// Test the connection
 	if err := sqlDB.Ping(); err != nil {
@@ -68,34 +128,148 @@ func InitDatabase(config *Config) (*gorm.DB, error) {
 	return db, nil
 }
 
-// CreateDynamicDatabase creates a new database for user schemas
-func CreateDynamicDatabase(config *Config, databaseName string) error {
-	// Connect to postgres database to create new database
-	var dsn string
+// metadataDialector selects the GORM dialector for the metadata database
+func metadataDialector(config *Config) (gorm.Dialector, error) {
+	switch config.DatabaseDriver {
+	case DriverPostgres:
+		return postgres.Open(BuildDSN(config, "")), nil
+	case DriverSQLite:
+		dsn := config.DatabaseURL
+		if dsn == "" {
+			dsn = defaultSQLiteDSN
+		}
+		return sqlite.Open(dsn), nil
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", config.DatabaseDriver)
+	}
+}
+
+// ErrMissingCreateDB is returned when the database user cannot create databases
+var ErrMissingCreateDB = errors.New("database user lacks the CREATEDB privilege")
+
+// CheckCreateDBPrivilege verifies that the connected user may create
+// databases, which provisioning every schema database requires
+func CheckCreateDBPrivilege(db *gorm.DB) error {
+	var canCreate bool
+	err := db.Raw("SELECT rolcreatedb OR rolsuper FROM pg_roles WHERE rolname = current_user").Scan(&canCreate).Error
+	if err != nil {
+		return fmt.Errorf("failed to check CREATEDB privilege: %w", err)
+	}
+	if !canCreate {
+		return ErrMissingCreateDB
+	}
+	return nil
+}
+
+// maintenanceDatabase is the database through which user schema databases
+// are created, renamed and dropped
+const maintenanceDatabase = "postgres"
 
+// BuildDSN returns the DSN connecting to databaseName on the configured
+// server, or to the application database when databaseName is empty. When
+// DATABASE_URL is set only its database is replaced; Validate has already
+// checked that it parses.
+func BuildDSN(config *Config, databaseName string) string {
 	if config.DatabaseURL != "" {
-		// For DATABASE_URL, we need to connect to the default postgres database
-		dsn = config.DatabaseURL + "_postgres"
-	} else {
-		dsn = fmt.Sprintf(
-			"host=%s port=%s user=%s password=%s dbname=postgres sslmode=disable",
-			config.DatabaseHost,
-			config.DatabasePort,
-			config.DatabaseUser,
-			config.DatabasePass,
-		)
+		if databaseName == "" {
+			return config.DatabaseURL
+		}
+		dsn, err := withDatabaseName(config.DatabaseURL, databaseName)
+		if err != nil {
+			return config.DatabaseURL
+		}
+		return dsn
+	}
+
+	if databaseName == "" {
+		databaseName = config.DatabaseName
+	}
+	return fmt.Sprintf(
+		"host=%s port=%s user=%s password=%s dbname=%s %s TimeZone=UTC",
+		config.DatabaseHost,
+		config.DatabasePort,
+		config.DatabaseUser,
+		config.DatabasePass,
+		databaseName,
+		config.SSLSettings(),
+	)
+}
+
+// dbnameKeyword matches the dbname setting of a keyword/value DSN, with its
+// value either bare or single-quoted
+var dbnameKeyword = regexp.MustCompile(`(^|\s)dbname\s*=\s*('(?:[^'\\]|\\.)*'|\S*)`)
+
+// withDatabaseName returns dsn connecting to databaseName instead of its own
+// database. Both the URL form (postgres://user@host/db?sslmode=require) and
+// the keyword/value form (host=... dbname=...) are accepted.
+func withDatabaseName(dsn, databaseName string) (string, error) {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			// url.Error quotes the whole URL, password included
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			return "", fmt.Errorf("invalid DATABASE_URL: %w", err)
+		}
+		u.Path = "/" + databaseName
+		u.RawPath = ""
+		return u.String(), nil
+	}
+
+	setting := "dbname=" + databaseName
+	if dbnameKeyword.MatchString(dsn) {
+		return dbnameKeyword.ReplaceAllString(dsn, "${1}"+setting), nil
+	}
+	return strings.TrimSpace(dsn) + " " + setting, nil
+}
+
+// PingAdminDatabase checks that the postgres maintenance database accepts
+// connections, i.e. that user schema databases can be provisioned
+func PingAdminDatabase(ctx context.Context, config *Config) error {
+	if config.DatabaseDriver != DriverPostgres {
+		return fmt.Errorf("provisioning is not supported with the %s driver", config.DatabaseDriver)
+	}
+
+	db, err := gorm.Open(postgres.Open(BuildDSN(config, maintenanceDatabase)), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to postgres database: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	defer sqlDB.Close()
+
+	return db.WithContext(ctx).Exec("SELECT 1").Error
+}
+
+// CreateDynamicDatabase creates a new database for user schemas
+func CreateDynamicDatabase(ctx context.Context, config *Config, databaseName string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "database.create", trace.WithAttributes(attribute.String("db.name", databaseName)))
+	defer func() { tracing.End(span, err) }()
+
+	if err := validateDatabaseName(databaseName); err != nil {
+		return err
 	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	// Connect to postgres database to create new database
+	db, err := gorm.Open(postgres.Open(BuildDSN(config, maintenanceDatabase)), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to connect to postgres database: %w", err)
 	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
 
 	// Create the new database
 	createSQL := fmt.Sprintf("CREATE DATABASE %s", databaseName)
-	if err := db.Exec(createSQL).Error; err != nil {
+	if err := db.WithContext(ctx).Exec(createSQL).Error; err != nil {
 		return fmt.Errorf("failed to create database %s: %w", databaseName, err)
 	}
 
@@ -104,35 +278,147 @@ func CreateDynamicDatabase(config *Config, databaseName string) error {
 }
 
 // DropDynamicDatabase drops a user schema database
-func DropDynamicDatabase(config *Config, databaseName string) error {
-	// Connect to postgres database to drop database
-	var dsn string
+func DropDynamicDatabase(ctx context.Context, config *Config, databaseName string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "database.drop", trace.WithAttributes(attribute.String("db.name", databaseName)))
+	defer func() { tracing.End(span, err) }()
 
-	if config.DatabaseURL != "" {
-		dsn = config.DatabaseURL + "_postgres"
-	} else {
-		dsn = fmt.Sprintf(
-			"host=%s port=%s user=%s password=%s dbname=postgres sslmode=disable",
-			config.DatabaseHost,
-			config.DatabasePort,
-			config.DatabaseUser,
-			config.DatabasePass,
-		)
+	if err := validateDatabaseName(databaseName); err != nil {
+		return err
 	}
 
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
+	// Connect to postgres database to drop database
+	db, err := gorm.Open(postgres.Open(BuildDSN(config, maintenanceDatabase)), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent),
 	})
 	if err != nil {
 		return fmt.Errorf("failed to connect to postgres database: %w", err)
 	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
 
 	// Drop the database
 	dropSQL := fmt.Sprintf("DROP DATABASE IF EXISTS %s", databaseName)
-	if err := db.Exec(dropSQL).Error; err != nil {
+	if err := db.WithContext(ctx).Exec(dropSQL).Error; err != nil {
 		return fmt.Errorf("failed to drop database %s: %w", databaseName, err)
 	}
 
 	log.Printf("Database %s dropped successfully", databaseName)
 	return nil
 }
+
+// DynamicDatabaseExists reports whether a user schema database exists
+func DynamicDatabaseExists(ctx context.Context, config *Config, databaseName string) (bool, error) {
+	db, err := gorm.Open(postgres.Open(BuildDSN(config, maintenanceDatabase)), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to connect to postgres database: %w", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	return databaseExists(db.WithContext(ctx), databaseName)
+}
+
+// databaseExists reports whether the server db is connected to has a
+// database named databaseName
+func databaseExists(db *gorm.DB, databaseName string) (bool, error) {
+	var exists bool
+	if err := db.Raw("SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = ?)", databaseName).Scan(&exists).Error; err != nil {
+		return false, fmt.Errorf("failed to check database %s: %w", databaseName, err)
+	}
+	return exists, nil
+}
+
+// RenameDynamicDatabase renames a user schema database. PostgreSQL cannot
+// rename the database a session is connected to, nor one with open sessions,
+// so this connects through the postgres maintenance database and first closes
+// the service's own sessions on the old database.
+func RenameDynamicDatabase(config *Config, oldName, newName string) error {
+	for _, name := range []string{oldName, newName} {
+		if err := validateDatabaseName(name); err != nil {
+			return err
+		}
+	}
+
+	db, err := gorm.Open(postgres.Open(BuildDSN(config, maintenanceDatabase)), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect to postgres database: %w", err)
+	}
+	if sqlDB, err := db.DB(); err == nil {
+		defer sqlDB.Close()
+	}
+
+	exists, err := databaseExists(db, newName)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return fmt.Errorf("%w: %s", ErrDatabaseExists, newName)
+	}
+
+	// Sessions of other users are left alone, so the rename fails while
+	// someone else is connected
+	terminateSQL := `SELECT pg_terminate_backend(pid) FROM pg_stat_activity
+		WHERE datname = ? AND usename = current_user AND pid <> pg_backend_pid()`
+	if err := db.Exec(terminateSQL, oldName).Error; err != nil {
+		return fmt.Errorf("failed to close connections to database %s: %w", oldName, err)
+	}
+
+	renameSQL := fmt.Sprintf("ALTER DATABASE %s RENAME TO %s", oldName, newName)
+	if err := db.Exec(renameSQL).Error; err != nil {
+		return fmt.Errorf("failed to rename database %s to %s: %w", oldName, newName, err)
+	}
+
+	log.Printf("Database %s renamed to %s", oldName, newName)
+	return nil
+}
+
+// TryAdvisoryLock takes a session-level PostgreSQL advisory lock keyed by a
+// hash of key, so it is shared by every instance using the same server. It
+// returns ErrLockHeld without waiting when another session holds the lock.
+// The lock lives on a dedicated connection that unlock releases and closes.
+func TryAdvisoryLock(ctx context.Context, config *Config, key string) (unlock func(), err error) {
+	db, err := gorm.Open(postgres.Open(BuildDSN(config, maintenanceDatabase)), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgres database: %w", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+
+	// Advisory locks belong to a session, so pin a single connection
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to open lock connection: %w", err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock(hashtext($1))", key).Scan(&acquired); err != nil {
+		conn.Close()
+		sqlDB.Close()
+		return nil, fmt.Errorf("failed to take advisory lock %s: %w", key, err)
+	}
+	if !acquired {
+		conn.Close()
+		sqlDB.Close()
+		return nil, fmt.Errorf("%w: %s", ErrLockHeld, key)
+	}
+
+	return func() {
+		// Closing the session releases the lock even if the unlock fails
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock(hashtext($1))", key); err != nil {
+			log.Printf("Warning: failed to release advisory lock %s: %v", key, err)
+		}
+		conn.Close()
+		sqlDB.Close()
+	}, nil
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"fmt"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"vdt-dashboard-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// migrationCheckInterval is how often AwaitMigrations looks for the tables
+const migrationCheckInterval = 10 * time.Second
+
+// ready is set once the application database has been connected to and its
+// migrations have been applied. It is never cleared: a later outage is
+// reported by the health checks, not by readiness.
+var ready atomic.Bool
+
+// IsReady reports whether the service can take traffic
+func IsReady() bool {
+	return ready.Load()
+}
+
+// MarkReady records that the service can take traffic
+func MarkReady() {
+	ready.Store(true)
+}
+
+// CheckMigrations checks that the tables created by the migrations exist.
+// Migrations are applied separately with `make migrate`.
+func CheckMigrations(db *gorm.DB) error {
+	for _, model := range []any{&models.User{}, &models.Schema{}, &models.IdempotencyKey{}, &models.APIKey{}, &models.SchemaCollaborator{}, &models.SchemaVersion{}} {
+		if !db.Migrator().HasTable(model) {
+			stmt := &gorm.Statement{DB: db}
+			if err := stmt.Parse(model); err != nil {
+				return fmt.Errorf("failed to check migrations: %w", err)
+			}
+			return fmt.Errorf("table %s is missing, migrations have not been applied", stmt.Schema.Table)
+		}
+	}
+	return nil
+}
+
+// AwaitMigrations checks the migrations periodically and marks the service
+// ready once they have been applied
+func AwaitMigrations(db *gorm.DB) {
+	for {
+		time.Sleep(migrationCheckInterval)
+		if err := CheckMigrations(db); err != nil {
+			continue
+		}
+		log.Println("Migrations applied, service is ready")
+		MarkReady()
+		return
+	}
+}
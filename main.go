@@ -1,10 +1,12 @@
 package main
 
 import (
+	"context"
 	"log"
 
 	"vdt-dashboard-backend/api"
 	"vdt-dashboard-backend/config"
+	"vdt-dashboard-backend/tracing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
@@ -18,6 +20,15 @@ func main() {
 
 	// Initialize configuration
 	cfg := config.Load()
+	if err := cfg.Validate(); err != nil {
+		log.Fatal("Invalid configuration: ", err)
+	}
+
+	// Export traces when OTEL_EXPORTER_OTLP_ENDPOINT is set
+	shutdownTracing, err := tracing.Init(context.Background(), cfg.OTLPEndpoint, config.GetBuildInfo().Version)
+	if err != nil {
+		log.Fatal("Failed to initialize tracing: ", err)
+	}
 
 	// Initialize database
 	db, err := config.InitDatabase(cfg)
@@ -25,6 +36,25 @@ func main() {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	// The readiness probe fails until the migrations have been applied
+	if err := config.CheckMigrations(db); err != nil {
+		log.Printf("WARNING: %v, /health/ready reports not ready until they are", err)
+		go config.AwaitMigrations(db)
+	} else {
+		config.MarkReady()
+	}
+
+	// Schema databases can't be provisioned without CREATEDB, so surface it
+	// now instead of on the first schema creation
+	if cfg.DatabaseDriver == config.DriverPostgres && !cfg.SkipCreateDBCheck {
+		if err := config.CheckCreateDBPrivilege(db); err != nil {
+			if cfg.Environment == "production" {
+				log.Fatalf("Startup check failed: %v (grant it with ALTER ROLE %s CREATEDB, or set SKIP_CREATEDB_CHECK=true)", err, cfg.DatabaseUser)
+			}
+			log.Printf("WARNING: %v, creating schemas will fail (grant it with ALTER ROLE %s CREATEDB, or set SKIP_CREATEDB_CHECK=true)", err, cfg.DatabaseUser)
+		}
+	}
+
 	// Set Gin mode
 	if cfg.Environment == "production" {
 		gin.SetMode(gin.ReleaseMode)
@@ -35,7 +65,12 @@ func main() {
 
 	// Start server
 	log.Printf("Server starting on port %s", cfg.Port)
-	if err := server.Run(":" + cfg.Port); err != nil {
-		log.Fatal("Failed to start server:", err)
+	err = server.RunWithGracefulShutdown(":" + cfg.Port)
+	if shutdownErr := shutdownTracing(context.Background()); shutdownErr != nil {
+		log.Printf("Warning: failed to flush traces: %v", shutdownErr)
+	}
+	if err != nil {
+		log.Fatal("Server stopped with error: ", err)
 	}
+	log.Println("Server stopped")
 }
@@ -0,0 +1,122 @@
+// Package metrics defines the Prometheus metrics of the service, exposed on
+// /metrics when METRICS_ENABLED is set
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "vdt"
+
+// Registry holds the metrics exposed on /metrics
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency by method, route template and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	generations = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "schema_generations_total",
+		Help:      "Background database generation jobs by operation and result.",
+	}, []string{"operation", "result"})
+
+	generationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "schema_generation_duration_seconds",
+		Help:      "Duration of background database generation jobs by operation.",
+		Buckets:   prometheus.ExponentialBuckets(0.05, 2, 12),
+	}, []string{"operation"})
+
+	poolsDesc           = prometheus.NewDesc(namespace+"_dynamic_db_pools", "Connection pools open to generated databases.", nil, nil)
+	openConnectionsDesc = prometheus.NewDesc(namespace+"_dynamic_db_open_connections", "Connections open to generated databases.", nil, nil)
+	inUseDesc           = prometheus.NewDesc(namespace+"_dynamic_db_in_use_connections", "Connections to generated databases in use.", nil, nil)
+	idleDesc            = prometheus.NewDesc(namespace+"_dynamic_db_idle_connections", "Idle connections to generated databases.", nil, nil)
+)
+
+// poolStats reads the connection pools of generated databases; it is set by
+// SetPoolStats
+var poolStats atomic.Pointer[func() models.DatabasePoolStats]
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		generations,
+		generationDuration,
+		poolCollector{},
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a served HTTP request. route must be the route
+// template, not the request path, so IDs don't create a series each.
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpRequestDuration.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+// ObserveGeneration records a finished database generation job
+func ObserveGeneration(operation string, err error, duration time.Duration) {
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	generations.WithLabelValues(operation, result).Inc()
+	generationDuration.WithLabelValues(operation).Observe(duration.Seconds())
+}
+
+// SetPoolStats sets the function reporting the connection pools of generated
+// databases at each scrape
+func SetPoolStats(stats func() models.DatabasePoolStats) {
+	poolStats.Store(&stats)
+}
+
+// poolCollector reports the connection pools of generated databases when a
+// stats function has been set
+type poolCollector struct{}
+
+func (poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolsDesc
+	ch <- openConnectionsDesc
+	ch <- inUseDesc
+	ch <- idleDesc
+}
+
+func (poolCollector) Collect(ch chan<- prometheus.Metric) {
+	stats := poolStats.Load()
+	if stats == nil {
+		return
+	}
+	current := (*stats)()
+	ch <- prometheus.MustNewConstMetric(poolsDesc, prometheus.GaugeValue, float64(current.Pools))
+	ch <- prometheus.MustNewConstMetric(openConnectionsDesc, prometheus.GaugeValue, float64(current.OpenConnections))
+	ch <- prometheus.MustNewConstMetric(inUseDesc, prometheus.GaugeValue, float64(current.InUse))
+	ch <- prometheus.MustNewConstMetric(idleDesc, prometheus.GaugeValue, float64(current.Idle))
+}
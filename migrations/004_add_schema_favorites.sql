@@ -0,0 +1,8 @@
+-- Migration: 004_add_schema_favorites.sql
+-- Description: Allow users to pin favorite schemas to the top of their list
+
+ALTER TABLE schemas ADD COLUMN IF NOT EXISTS is_favorite BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS idx_schemas_user_id_is_favorite ON schemas(user_id, is_favorite);
+
+COMMENT ON COLUMN schemas.is_favorite IS 'Whether the owner pinned the schema to the top of their list';
@@ -0,0 +1,8 @@
+-- Migration: 005_add_schema_validation_status.sql
+-- Description: Store the outcome of the last validation of each schema definition
+
+ALTER TABLE schemas ADD COLUMN IF NOT EXISTS validation_status VARCHAR(20) NOT NULL DEFAULT 'unknown';
+ALTER TABLE schemas ADD COLUMN IF NOT EXISTS last_validated_at TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON COLUMN schemas.validation_status IS 'Outcome of the last validation: valid, invalid or unknown';
+COMMENT ON COLUMN schemas.last_validated_at IS 'Timestamp of the last validation of the schema definition';
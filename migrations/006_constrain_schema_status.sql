@@ -0,0 +1,8 @@
+-- Migration: 006_constrain_schema_status.sql
+-- Description: Restrict schemas.status to the known schema statuses
+
+ALTER TABLE schemas DROP CONSTRAINT IF EXISTS chk_schemas_status;
+ALTER TABLE schemas ADD CONSTRAINT chk_schemas_status
+    CHECK (status IN ('creating', 'created', 'updating', 'updated', 'regenerated', 'error'));
+
+COMMENT ON COLUMN schemas.status IS 'Current status: creating, created, updating, updated, regenerated or error';
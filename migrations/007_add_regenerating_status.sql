@@ -0,0 +1,8 @@
+-- Migration: 007_add_regenerating_status.sql
+-- Description: Allow the regenerating status held while a database is rebuilt
+
+ALTER TABLE schemas DROP CONSTRAINT IF EXISTS chk_schemas_status;
+ALTER TABLE schemas ADD CONSTRAINT chk_schemas_status
+    CHECK (status IN ('creating', 'created', 'updating', 'updated', 'regenerating', 'regenerated', 'error'));
+
+COMMENT ON COLUMN schemas.status IS 'Current status: creating, created, updating, updated, regenerating, regenerated or error';
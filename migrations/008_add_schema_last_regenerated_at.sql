@@ -0,0 +1,6 @@
+-- Migration: 008_add_schema_last_regenerated_at.sql
+-- Description: Record when the database of each schema was last built
+
+ALTER TABLE schemas ADD COLUMN IF NOT EXISTS last_regenerated_at TIMESTAMP WITH TIME ZONE;
+
+COMMENT ON COLUMN schemas.last_regenerated_at IS 'Timestamp of the last successful build of the schema database';
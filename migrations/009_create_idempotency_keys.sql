@@ -0,0 +1,15 @@
+-- Migration: 009_create_idempotency_keys.sql
+-- Description: Remember the schema created for each Idempotency-Key so retried creates are not repeated
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    key VARCHAR(255) NOT NULL,
+    user_id UUID NOT NULL,
+    request_hash TEXT NOT NULL,
+    schema_id UUID,
+    created_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (key, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);
+
+COMMENT ON TABLE idempotency_keys IS 'Schema created for each client-supplied Idempotency-Key, per user';
@@ -0,0 +1,4 @@
+-- Migration: 011_api_key_last_used.sql
+-- Description: Record when each API key was last used
+
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS last_used_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,15 @@
+-- Migration: 012_create_schema_collaborators.sql
+-- Description: Share schemas with other users as viewers or editors
+
+CREATE TABLE IF NOT EXISTS schema_collaborators (
+    schema_id UUID NOT NULL REFERENCES schemas(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(20) NOT NULL CHECK (role IN ('viewer', 'editor')),
+    created_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (schema_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_schema_collaborators_user_id ON schema_collaborators(user_id);
+
+COMMENT ON TABLE schema_collaborators IS 'Users a schema is shared with, and their role';
@@ -0,0 +1,41 @@
+-- Migration: 013_create_schema_versions.sql
+-- Description: Keep a snapshot of every stored schema definition, numbered by an integer version
+
+-- Turn the version string into a counter. Schemas were stored as '1.0' and
+-- bumped to '1.1' on every update, so anything else counts as version 2.
+DO $$
+BEGIN
+    IF (SELECT data_type FROM information_schema.columns
+        WHERE table_name = 'schemas' AND column_name = 'version') <> 'integer' THEN
+        ALTER TABLE schemas ALTER COLUMN version DROP DEFAULT;
+        ALTER TABLE schemas ALTER COLUMN version TYPE INTEGER USING (
+            CASE
+                WHEN version ~ '^[0-9]+$' THEN version::INTEGER
+                WHEN version = '1.0' THEN 1
+                ELSE 2
+            END
+        );
+        ALTER TABLE schemas ALTER COLUMN version SET DEFAULT 1;
+    END IF;
+END $$;
+
+COMMENT ON COLUMN schemas.version IS 'Incremented every time the schema definition is stored';
+
+CREATE TABLE IF NOT EXISTS schema_versions (
+    schema_id UUID NOT NULL REFERENCES schemas(id) ON DELETE CASCADE,
+    version INTEGER NOT NULL,
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    schema_definition JSONB NOT NULL,
+    created_by UUID,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (schema_id, version)
+);
+
+-- Start the history of existing schemas from their current definition
+INSERT INTO schema_versions (schema_id, version, name, description, schema_definition, created_by, created_at)
+SELECT id, version, name, description, schema_definition, user_id, updated_at
+FROM schemas
+ON CONFLICT (schema_id, version) DO NOTHING;
+
+COMMENT ON TABLE schema_versions IS 'Snapshots of schema definitions, one per stored version';
@@ -0,0 +1,15 @@
+-- Migration: 014_create_query_role.sql
+-- Description: Role that ad-hoc queries on generated databases run as
+
+-- The role can't log in; the service switches to it with SET ROLE, so it
+-- must be granted to the service user. It only gets SELECT on the tables of
+-- each generated database, which the service grants before running a query.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT FROM pg_roles WHERE rolname = 'vdt_query_reader') THEN
+        CREATE ROLE vdt_query_reader NOLOGIN NOSUPERUSER NOCREATEDB NOCREATEROLE NOINHERIT;
+    END IF;
+    GRANT vdt_query_reader TO CURRENT_USER;
+EXCEPTION WHEN insufficient_privilege THEN
+    RAISE NOTICE 'Cannot create role vdt_query_reader, create it and grant it to % to enable ad-hoc queries', CURRENT_USER;
+END $$;
@@ -114,7 +114,7 @@ func createModels(db *gorm.DB) error {
 
 	// AutoMigrate will create tables, missing columns, missing indexes
 	// It will NOT delete unused columns to protect data
-	if err := db.AutoMigrate(&models.User{}, &models.Schema{}); err != nil {
+	if err := db.AutoMigrate(&models.User{}, &models.Schema{}, &models.IdempotencyKey{}, &models.APIKey{}, &models.SchemaCollaborator{}, &models.SchemaVersion{}); err != nil {
 		return fmt.Errorf("failed to migrate models: %w", err)
 	}
 
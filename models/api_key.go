@@ -0,0 +1,55 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// APIKey lets a user authenticate without a Clerk session, for example from
+// CI pipelines. Only a salted SHA-256 hash of the key is stored; the key
+// itself is returned once, when it is created. Keys are looked up by their
+// prefix, which is unique.
+type APIKey struct {
+	ID         uuid.UUID  `json:"id" gorm:"type:uuid;primary_key"`
+	UserID     uuid.UUID  `json:"-" gorm:"type:uuid;not null;index"`
+	Name       string     `json:"name" gorm:"not null;size:100"`
+	Prefix     string     `json:"prefix" gorm:"uniqueIndex;not null;size:16"` // Start of the key, to tell keys apart
+	Salt       string     `json:"-" gorm:"not null;size:32"`
+	KeyHash    string     `json:"-" gorm:"not null;size:64"`
+	ExpiresAt  *time.Time `json:"expiresAt,omitempty"`
+	RevokedAt  *time.Time `json:"revokedAt,omitempty"`
+	LastUsedAt *time.Time `json:"lastUsedAt,omitempty"`
+	CreatedAt  time.Time  `json:"createdAt"`
+}
+
+// BeforeCreate sets up UUID before creating the API key
+func (k *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsActive reports whether the key can authenticate at the given time
+func (k *APIKey) IsActive(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// CreateAPIKeyRequest represents the request to create an API key. Keys
+// without ExpiresInDays never expire.
+type CreateAPIKeyRequest struct {
+	Name          string `json:"name" binding:"required,min=1,max=100"`
+	ExpiresInDays *int   `json:"expiresInDays,omitempty" binding:"omitempty,min=1,max=3650"`
+}
+
+// CreatedAPIKeyResponse returns a new API key along with the key itself,
+// which can't be retrieved again
+type CreatedAPIKeyResponse struct {
+	APIKey
+	Key string `json:"key"`
+}
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxIdempotencyKeyLength is the longest Idempotency-Key header accepted
+const MaxIdempotencyKeyLength = 255
+
+// IdempotencyKey records the schema created for a client-supplied
+// Idempotency-Key, so a retried request returns it instead of creating
+// another. SchemaID is unset while the first request is still running.
+type IdempotencyKey struct {
+	Key         string     `gorm:"primaryKey;size:255"`
+	UserID      uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	RequestHash string     `gorm:"not null"`
+	SchemaID    *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt   time.Time  `gorm:"index"`
+}
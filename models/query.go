@@ -0,0 +1,18 @@
+package models
+
+import "encoding/json"
+
+// QueryRequest is an ad-hoc query to run against a schema's database
+type QueryRequest struct {
+	SQL string `json:"sql" binding:"required"`
+}
+
+// QueryResponse holds the result of an ad-hoc query. Rows is the JSON array
+// of rows, encoded as they were read. Truncated is set when the query
+// returned more rows than the configured row or byte limit.
+type QueryResponse struct {
+	Columns   []string        `json:"columns"`
+	Rows      json.RawMessage `json:"rows"`
+	RowCount  int             `json:"rowCount"`
+	Truncated bool            `json:"truncated"`
+}
@@ -10,16 +10,29 @@ type APIResponse struct {
 
 // APIError represents error information in API responses
 type APIError struct {
-	Code    string `json:"code"`
-	Details string `json:"details,omitempty"`
+	Code      string `json:"code"`
+	Details   string `json:"details,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
 }
 
-// PaginationResponse represents pagination metadata
+// PaginationResponse represents pagination metadata. Cursor-paginated
+// listings report NextCursor instead of page numbers.
 type PaginationResponse struct {
-	Page       int `json:"page"`
-	Limit      int `json:"limit"`
-	Total      int `json:"total"`
-	TotalPages int `json:"totalPages"`
+	Page       int    `json:"page"`
+	Limit      int    `json:"limit"`
+	Total      int    `json:"total"`
+	TotalPages int    `json:"totalPages"`
+	NextCursor string `json:"nextCursor,omitempty"`
+}
+
+// PagePagination returns the metadata of a page-numbered listing
+func PagePagination(page, limit, total int) *PaginationResponse {
+	return &PaginationResponse{
+		Page:       page,
+		Limit:      limit,
+		Total:      total,
+		TotalPages: (total + limit - 1) / limit,
+	}
 }
 
 // PaginatedResponse represents a paginated API response
@@ -64,16 +77,26 @@ func PaginatedSuccessResponse(message string, data interface{}, pagination *Pagi
 
 // Error codes constants
 const (
-	ErrValidation             = "VALIDATION_ERROR"
-	ErrSchemaNotFound         = "SCHEMA_NOT_FOUND"
-	ErrDatabaseError          = "DATABASE_ERROR"
-	ErrDuplicateName          = "DUPLICATE_NAME"
-	ErrInvalidJSON            = "INVALID_JSON"
-	ErrMissingRequiredField   = "MISSING_REQUIRED_FIELD"
-	ErrUnsupportedDataType    = "UNSUPPORTED_DATA_TYPE"
-	ErrForeignKeyError        = "FOREIGN_KEY_ERROR"
-	ErrDatabaseCreationFailed = "DATABASE_CREATION_FAILED"
-	ErrInternalError          = "INTERNAL_ERROR"
-	ErrUnauthorized           = "UNAUTHORIZED"
-	ErrForbidden              = "FORBIDDEN"
+	ErrValidation              = "VALIDATION_ERROR"
+	ErrSchemaNotFound          = "SCHEMA_NOT_FOUND"
+	ErrDatabaseError           = "DATABASE_ERROR"
+	ErrDuplicateName           = "DUPLICATE_NAME"
+	ErrInvalidJSON             = "INVALID_JSON"
+	ErrMissingRequiredField    = "MISSING_REQUIRED_FIELD"
+	ErrUnsupportedDataType     = "UNSUPPORTED_DATA_TYPE"
+	ErrForeignKeyError         = "FOREIGN_KEY_ERROR"
+	ErrDatabaseCreationFailed  = "DATABASE_CREATION_FAILED"
+	ErrInternalError           = "INTERNAL_ERROR"
+	ErrUnauthorized            = "UNAUTHORIZED"
+	ErrForbidden               = "FORBIDDEN"
+	ErrAuthProviderUnavailable = "AUTH_PROVIDER_UNAVAILABLE"
+	ErrQuotaExceeded           = "QUOTA_EXCEEDED"
+	ErrReadOnlyMode            = "READ_ONLY_MODE"
+	ErrDefinitionTooLarge      = "DEFINITION_TOO_LARGE"
+	ErrGenerationInProgress    = "GENERATION_IN_PROGRESS"
+	ErrIdempotencyKeyConflict  = "IDEMPOTENCY_KEY_CONFLICT"
+	ErrRateLimited             = "RATE_LIMITED"
+	ErrAPIKeyNotFound          = "API_KEY_NOT_FOUND"
+	ErrQueryTimeout            = "QUERY_TIMEOUT"
+	ErrQueryCanceled           = "QUERY_CANCELED"
 )
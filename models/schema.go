@@ -2,8 +2,11 @@ package models
 
 import (
 	"database/sql/driver"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -12,17 +15,33 @@ import (
 
 // Schema represents a database schema definition
 type Schema struct {
-	ID               uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	Name             string         `json:"name" gorm:"not null"`
-	Description      string         `json:"description"`
-	DatabaseName     string         `json:"databaseName" gorm:"not null"`
-	Status           string         `json:"status" gorm:"not null;default:'created'"`
-	Version          string         `json:"version" gorm:"not null;default:'1.0'"`
-	SchemaDefinition SchemaData     `json:"schemaDefinition" gorm:"type:jsonb"`
-	UserID           uuid.UUID      `json:"userId" gorm:"type:uuid;not null;index"` // Foreign key to User
-	CreatedAt        time.Time      `json:"createdAt"`
-	UpdatedAt        time.Time      `json:"updatedAt"`
-	DeletedAt        gorm.DeletedAt `json:"-" gorm:"index"`
+	ID                uuid.UUID      `json:"id" gorm:"type:uuid;primary_key"`
+	Name              string         `json:"name" gorm:"not null"`
+	Description       string         `json:"description"`
+	DatabaseName      string         `json:"databaseName" gorm:"not null"`
+	Status            SchemaStatus   `json:"status" gorm:"not null;default:'created'"`
+	Version           int            `json:"version" gorm:"not null;default:1"` // Incremented every time the definition is stored
+	IsFavorite        bool           `json:"isFavorite" gorm:"not null;default:false"`
+	ValidationStatus  string         `json:"validationStatus" gorm:"not null;default:'unknown'"`
+	LastValidatedAt   *time.Time     `json:"lastValidatedAt"`
+	LastRegeneratedAt *time.Time     `json:"lastRegeneratedAt"` // When the schema's database was last built
+	SchemaDefinition  SchemaData     `json:"schemaDefinition" gorm:"type:jsonb"`
+	UserID            uuid.UUID      `json:"userId" gorm:"type:uuid;not null;index"` // Foreign key to User
+	CreatedAt         time.Time      `json:"createdAt"`
+	UpdatedAt         time.Time      `json:"updatedAt"`
+	DeletedAt         gorm.DeletedAt `json:"-" gorm:"index"`
+
+	// Warnings from the latest validation run, returned on create and update
+	// but never stored
+	ValidationWarnings []string `json:"validationWarnings,omitempty" gorm:"-"`
+
+	// Background database generation started by the request, returned on
+	// create and regenerate but never stored
+	GenerationJob *GenerationJob `json:"generationJob,omitempty" gorm:"-"`
+
+	// DDL of the stored definition, returned on create and update when
+	// includeSql is set but never stored
+	GeneratedSQL string `json:"generatedSql,omitempty" gorm:"-"`
 
 	// Add unique constraint for name per user
 	// This will be handled in migration: UNIQUE(name, user_id)
@@ -30,10 +49,12 @@ type Schema struct {
 
 // SchemaData represents the complete schema definition structure
 type SchemaData struct {
-	Tables      []Table      `json:"tables"`
-	ForeignKeys []ForeignKey `json:"foreignKeys"`
-	Version     string       `json:"version"`
-	ExportedAt  string       `json:"exportedAt,omitempty"`
+	Tables               []Table      `json:"tables"`
+	ForeignKeys          []ForeignKey `json:"foreignKeys"`
+	AutoIndexForeignKeys *bool        `json:"autoIndexForeignKeys,omitempty"`                                          // Overrides AUTO_INDEX_FOREIGN_KEYS when set
+	IdentifierCase       string       `json:"identifierCase,omitempty" binding:"omitempty,oneof=preserve snake lower"` // Overrides IDENTIFIER_CASE when set
+	Version              string       `json:"version"`
+	ExportedAt           time.Time    `json:"exportedAt,omitzero"`
 }
 
 // Value implements the driver.Valuer interface for database storage
@@ -81,33 +102,73 @@ type Table struct {
 	Columns  []Column `json:"columns"`
 	Position Position `json:"position"`
 	Indexes  []Index  `json:"indexes,omitempty"`
+	Comment  string   `json:"comment,omitempty"` // Emitted as COMMENT ON TABLE
 }
 
 // Column represents a database column definition
 type Column struct {
-	ID            string      `json:"id"`
-	Name          string      `json:"name"`
-	DataType      string      `json:"dataType"`
-	Length        *int        `json:"length,omitempty"`
-	Precision     *int        `json:"precision,omitempty"`
-	Scale         *int        `json:"scale,omitempty"`
-	Nullable      bool        `json:"nullable"`
-	PrimaryKey    bool        `json:"primaryKey"`
-	AutoIncrement bool        `json:"autoIncrement"`
-	Unique        bool        `json:"unique,omitempty"`
-	DefaultValue  interface{} `json:"defaultValue,omitempty"`
-}
-
-// ForeignKey represents a foreign key relationship
+	ID                string      `json:"id"`
+	Name              string      `json:"name"`
+	DataType          string      `json:"dataType"`
+	Length            *int        `json:"length,omitempty"`
+	Precision         *int        `json:"precision,omitempty"`
+	Scale             *int        `json:"scale,omitempty"`
+	Nullable          *bool       `json:"nullable,omitempty"` // Defaults to true, primary key columns are never nullable
+	PrimaryKey        bool        `json:"primaryKey"`
+	AutoIncrement     bool        `json:"autoIncrement"`
+	IsArray           bool        `json:"isArray,omitempty"` // Generated as an array of DataType, e.g. TEXT[]
+	Unique            bool        `json:"unique,omitempty"`
+	DefaultValue      interface{} `json:"defaultValue,omitempty"`
+	DefaultExpression string      `json:"defaultExpression,omitempty"` // SQL expression emitted verbatim, takes precedence over DefaultValue
+	Check             *string     `json:"check,omitempty"`             // Boolean SQL expression emitted verbatim as a CHECK constraint
+	Order             int         `json:"order,omitempty"`             // Position in generated DDL, 0 keeps array order
+	Comment           string      `json:"comment,omitempty"`           // Emitted as COMMENT ON COLUMN
+}
+
+// IsNullable reports whether the column accepts NULL. Columns are nullable
+// unless nullable is explicitly false or they are part of the primary key.
+func (c Column) IsNullable() bool {
+	if c.PrimaryKey {
+		return false
+	}
+	return c.Nullable == nil || *c.Nullable
+}
+
+// ForeignKey represents a foreign key relationship. Composite foreign keys
+// list their columns in SourceColumnIds and TargetColumnIds, which take
+// precedence over the single-column fields.
 type ForeignKey struct {
-	ID             string `json:"id"`
-	Name           string `json:"name,omitempty"`
-	SourceTableId  string `json:"sourceTableId"`
-	SourceColumnId string `json:"sourceColumnId"`
-	TargetTableId  string `json:"targetTableId"`
-	TargetColumnId string `json:"targetColumnId"`
-	OnDelete       string `json:"onDelete"`
-	OnUpdate       string `json:"onUpdate"`
+	ID              string   `json:"id"`
+	Name            string   `json:"name,omitempty"`
+	SourceTableId   string   `json:"sourceTableId"`
+	SourceColumnId  string   `json:"sourceColumnId,omitempty"`
+	SourceColumnIds []string `json:"sourceColumnIds,omitempty"`
+	TargetTableId   string   `json:"targetTableId"`
+	TargetColumnId  string   `json:"targetColumnId,omitempty"`
+	TargetColumnIds []string `json:"targetColumnIds,omitempty"`
+	OnDelete        string   `json:"onDelete"`
+	OnUpdate        string   `json:"onUpdate"`
+}
+
+// SourceColumns returns the IDs of the referencing columns in order
+func (fk ForeignKey) SourceColumns() []string {
+	return foreignKeyColumnIDs(fk.SourceColumnIds, fk.SourceColumnId)
+}
+
+// TargetColumns returns the IDs of the referenced columns in order
+func (fk ForeignKey) TargetColumns() []string {
+	return foreignKeyColumnIDs(fk.TargetColumnIds, fk.TargetColumnId)
+}
+
+// foreignKeyColumnIDs prefers the column list over the single column ID
+func foreignKeyColumnIDs(ids []string, id string) []string {
+	if len(ids) > 0 {
+		return ids
+	}
+	if id != "" {
+		return []string{id}
+	}
+	return nil
 }
 
 // Position represents UI positioning for tables
@@ -121,42 +182,119 @@ type Index struct {
 	Name    string   `json:"name"`
 	Columns []string `json:"columns"`
 	Unique  bool     `json:"unique"`
+	Where   *string  `json:"where,omitempty"`  // Predicate of a partial index
+	Method  string   `json:"method,omitempty"` // Index access method, defaults to btree
 }
 
 // CreateSchemaRequest represents the request structure for creating a schema
 type CreateSchemaRequest struct {
-	Name        string       `json:"name" binding:"required,min=1,max=100"`
-	Description string       `json:"description" binding:"max=500"`
-	Tables      []Table      `json:"tables" binding:"required,min=1"`
-	ForeignKeys []ForeignKey `json:"foreignKeys"`
+	Name                 string       `json:"name" binding:"required,min=1,max=100"`
+	Description          string       `json:"description" binding:"max=500"`
+	Tables               []Table      `json:"tables" binding:"required,min=1"`
+	ForeignKeys          []ForeignKey `json:"foreignKeys"`
+	AutoIndexForeignKeys *bool        `json:"autoIndexForeignKeys,omitempty"`                                          // Overrides AUTO_INDEX_FOREIGN_KEYS when set
+	IdentifierCase       string       `json:"identifierCase,omitempty" binding:"omitempty,oneof=preserve snake lower"` // Overrides IDENTIFIER_CASE when set
 }
 
 // UpdateSchemaRequest represents the request structure for updating a schema
 type UpdateSchemaRequest struct {
-	Name        string       `json:"name" binding:"required,min=1,max=100"`
-	Description string       `json:"description" binding:"max=500"`
-	Tables      []Table      `json:"tables" binding:"required,min=1"`
-	ForeignKeys []ForeignKey `json:"foreignKeys"`
+	Name                   string       `json:"name" binding:"required,min=1,max=100"`
+	Description            string       `json:"description" binding:"max=500"`
+	Tables                 []Table      `json:"tables" binding:"required,min=1"`
+	ForeignKeys            []ForeignKey `json:"foreignKeys"`
+	AutoIndexForeignKeys   *bool        `json:"autoIndexForeignKeys,omitempty"`                                          // Overrides AUTO_INDEX_FOREIGN_KEYS when set
+	IdentifierCase         string       `json:"identifierCase,omitempty" binding:"omitempty,oneof=preserve snake lower"` // Overrides IDENTIFIER_CASE when set
+	RenamePhysicalDatabase bool         `json:"renamePhysicalDatabase,omitempty"`                                        // Renames the generated database after the schema
 }
 
 // SchemaListResponse represents a simplified schema for listing
 type SchemaListResponse struct {
-	ID           uuid.UUID `json:"id"`
-	Name         string    `json:"name"`
-	Description  string    `json:"description"`
-	DatabaseName string    `json:"databaseName"`
-	Status       string    `json:"status"`
-	TableCount   int       `json:"tableCount"`
-	CreatedAt    time.Time `json:"createdAt"`
-	UpdatedAt    time.Time `json:"updatedAt"`
-	Version      string    `json:"version"`
+	ID                uuid.UUID    `json:"id"`
+	Name              string       `json:"name"`
+	Description       string       `json:"description"`
+	DatabaseName      string       `json:"databaseName"`
+	Status            SchemaStatus `json:"status"`
+	TableCount        int          `json:"tableCount"`
+	CreatedAt         time.Time    `json:"createdAt"`
+	UpdatedAt         time.Time    `json:"updatedAt"`
+	Version           int          `json:"version"`
+	IsFavorite        bool         `json:"isFavorite"`
+	ValidationStatus  string       `json:"validationStatus"`
+	LastValidatedAt   *time.Time   `json:"lastValidatedAt"`
+	LastRegeneratedAt *time.Time   `json:"lastRegeneratedAt"`
+	DeletedAt         *time.Time   `json:"deletedAt,omitempty"`
+}
+
+// CloneSchemaRequest represents the request structure for cloning a schema
+type CloneSchemaRequest struct {
+	Name string `json:"name" binding:"required,min=1,max=100"`
+}
+
+// FavoriteSchemaRequest represents the request for pinning a schema.
+// When IsFavorite is omitted the current flag is toggled.
+type FavoriteSchemaRequest struct {
+	IsFavorite *bool `json:"isFavorite"`
 }
 
 // SchemaValidationRequest represents the request for schema validation
 type SchemaValidationRequest struct {
-	Name        string       `json:"name" binding:"required"`
-	Tables      []Table      `json:"tables" binding:"required,min=1"`
-	ForeignKeys []ForeignKey `json:"foreignKeys"`
+	Name                 string       `json:"name" binding:"required"`
+	Tables               []Table      `json:"tables" binding:"required,min=1"`
+	ForeignKeys          []ForeignKey `json:"foreignKeys"`
+	AutoIndexForeignKeys *bool        `json:"autoIndexForeignKeys,omitempty"`                                          // Overrides AUTO_INDEX_FOREIGN_KEYS when set
+	IdentifierCase       string       `json:"identifierCase,omitempty" binding:"omitempty,oneof=preserve snake lower"` // Overrides IDENTIFIER_CASE when set
+}
+
+// LintRequest represents the request for linting a schema definition. Rules
+// are keyed by rule ID and override the default of each rule.
+type LintRequest struct {
+	Name                 string                    `json:"name"`
+	Tables               []Table                   `json:"tables" binding:"required,min=1"`
+	ForeignKeys          []ForeignKey              `json:"foreignKeys"`
+	AutoIndexForeignKeys *bool                     `json:"autoIndexForeignKeys,omitempty"` // Overrides AUTO_INDEX_FOREIGN_KEYS when set
+	Rules                map[string]LintRuleConfig `json:"rules,omitempty" binding:"omitempty,dive"`
+}
+
+// LintRuleConfig enables, disables or changes the severity of a lint rule
+type LintRuleConfig struct {
+	Enabled  *bool  `json:"enabled,omitempty"`
+	Severity string `json:"severity,omitempty" binding:"omitempty,oneof=info warning"`
+}
+
+// LintResult represents the findings of a schema lint, grouped by rule in
+// rule order. Rules without findings are omitted.
+type LintResult struct {
+	Rules         []LintRuleResult `json:"rules"`
+	TotalFindings int              `json:"totalFindings"`
+}
+
+// LintRuleResult represents the findings of one lint rule
+type LintRuleResult struct {
+	RuleID      string        `json:"ruleId"`
+	Description string        `json:"description"`
+	Severity    string        `json:"severity"`
+	Findings    []LintFinding `json:"findings"`
+}
+
+// LintFinding represents a single lint finding
+type LintFinding struct {
+	Table   string `json:"table"`
+	Column  string `json:"column,omitempty"`
+	Message string `json:"message"`
+}
+
+// MaxDefinitionBytes caps the size of a stored schema definition that is
+// loaded to serve its tables and relationships
+const MaxDefinitionBytes = 5 << 20
+
+// MaxBatchValidationSize caps the number of schemas validated in one request
+const MaxBatchValidationSize = 50
+
+// BatchValidationResponse represents the results of a batch validation, in
+// request order
+type BatchValidationResponse struct {
+	AllValid bool               `json:"allValid"`
+	Results  []ValidationResult `json:"results"`
 }
 
 // ValidationResult represents the result of schema validation
@@ -164,6 +302,7 @@ type ValidationResult struct {
 	Valid        bool              `json:"valid"`
 	Errors       []ValidationError `json:"errors,omitempty"`
 	Warnings     []string          `json:"warnings,omitempty"`
+	TableOrder   []string          `json:"tableOrder,omitempty"` // Table names, referenced tables first
 	GeneratedSQL []string          `json:"generatedSQL,omitempty"`
 }
 
@@ -184,6 +323,97 @@ type DatabaseStatus struct {
 	ConnectionString string    `json:"connectionString,omitempty"`
 }
 
+// DatabaseConnectionStatus reports whether a cached connection pool to a
+// generated database answers, with the pool's connection counts
+type DatabaseConnectionStatus struct {
+	DatabaseName    string `json:"databaseName"`
+	Reachable       bool   `json:"reachable"`
+	Error           string `json:"error,omitempty"`
+	OpenConnections int    `json:"openConnections"`
+	InUse           int    `json:"inUse"`
+	Idle            int    `json:"idle"`
+}
+
+// DatabasePoolStats sums the connection pools open to generated databases
+type DatabasePoolStats struct {
+	Pools           int `json:"pools"`
+	OpenConnections int `json:"openConnections"`
+	InUse           int `json:"inUse"`
+	Idle            int `json:"idle"`
+}
+
+// SchemaObject identifies a table, column or foreign key in a schema diff
+type SchemaObject struct {
+	Kind         string `json:"kind"` // table, column or foreignKey
+	Name         string `json:"name"`
+	PreviousName string `json:"previousName,omitempty"` // Set on renamed objects
+	Details      string `json:"details,omitempty"`
+}
+
+// SchemaDiff represents the differences between two schema definitions
+type SchemaDiff struct {
+	Added   []SchemaObject `json:"added"`
+	Removed []SchemaObject `json:"removed"`
+	Changed []SchemaObject `json:"changed"`
+	Renamed []SchemaObject `json:"renamed,omitempty"` // Only reported when objects are matched by ID
+}
+
+// HasChanges reports whether the diff contains any difference
+func (d SchemaDiff) HasChanges() bool {
+	return len(d.Added) > 0 || len(d.Removed) > 0 || len(d.Changed) > 0 || len(d.Renamed) > 0
+}
+
+// SchemaDiffResponse represents the changes a schema update would make,
+// without applying them
+type SchemaDiffResponse struct {
+	SchemaID   uuid.UUID  `json:"schemaId"`
+	HasChanges bool       `json:"hasChanges"`
+	Diff       SchemaDiff `json:"diff"`
+}
+
+// DriftReport represents differences between a stored schema definition and
+// its live database. Added objects exist only in the database, removed
+// objects exist only in the definition.
+type DriftReport struct {
+	SchemaID     uuid.UUID  `json:"schemaId"`
+	DatabaseName string     `json:"databaseName"`
+	HasDrift     bool       `json:"hasDrift"`
+	Diff         SchemaDiff `json:"diff"`
+	CheckedAt    time.Time  `json:"checkedAt"`
+}
+
+// LiveTablesResponse lists the tables of a generated database as they exist
+// in PostgreSQL
+type LiveTablesResponse struct {
+	SchemaID     uuid.UUID   `json:"schemaId"`
+	DatabaseName string      `json:"databaseName"`
+	Tables       []LiveTable `json:"tables"`
+	CheckedAt    time.Time   `json:"checkedAt"`
+}
+
+// LiveTable is a table of a generated database
+type LiveTable struct {
+	Name        string           `json:"name"`
+	Columns     []LiveColumn     `json:"columns"`
+	Constraints []LiveConstraint `json:"constraints"`
+}
+
+// LiveColumn is a column as information_schema reports it
+type LiveColumn struct {
+	Name     string  `json:"name"`
+	DataType string  `json:"dataType"` // PostgreSQL type, e.g. character varying(255) or integer[]
+	Nullable bool    `json:"nullable"`
+	Default  *string `json:"default"`
+}
+
+// LiveConstraint is a table constraint with its definition as PostgreSQL
+// prints it
+type LiveConstraint struct {
+	Name       string `json:"name"`
+	Type       string `json:"type"` // PRIMARY KEY, UNIQUE, FOREIGN KEY, CHECK or EXCLUDE
+	Definition string `json:"definition"`
+}
+
 // SQLExportResponse represents the response for SQL export
 type SQLExportResponse struct {
 	SchemaID    uuid.UUID `json:"schemaId"`
@@ -191,13 +421,279 @@ type SQLExportResponse struct {
 	GeneratedAt time.Time `json:"generatedAt"`
 }
 
-// PaginationRequest represents pagination parameters
+// SQLPreviewResponse represents the SQL an update request would generate,
+// without saving it
+type SQLPreviewResponse struct {
+	SchemaID    uuid.UUID `json:"schemaId"`
+	Statements  []string  `json:"statements"`
+	Warnings    []string  `json:"warnings,omitempty"`
+	GeneratedAt time.Time `json:"generatedAt"`
+}
+
+// DeleteSchemaOptions represents the query parameters for deleting a schema
+type DeleteSchemaOptions struct {
+	DropDatabase bool `form:"dropDatabase,default=true"`
+}
+
+// MaxBulkDeleteSize caps the number of schemas deleted in one request
+const MaxBulkDeleteSize = 50
+
+// BulkDeleteRequest lists the schemas to delete. IDs are checked one by one,
+// so a malformed ID fails only its own result.
+type BulkDeleteRequest struct {
+	IDs []string `json:"ids" binding:"required"`
+}
+
+// Outcomes of deleting one schema of a bulk delete
+const (
+	BulkDeleteDeleted = "deleted"
+	BulkDeleteSkipped = "skipped" // Not found, already deleted or not owned by the user
+	BulkDeleteFailed  = "failed"
+)
+
+// BulkDeleteResult is the outcome of deleting one schema of a bulk delete
+type BulkDeleteResult struct {
+	ID              string `json:"id"`
+	Status          string `json:"status"`
+	DatabaseDropped bool   `json:"databaseDropped"`
+	Error           string `json:"error,omitempty"`
+}
+
+// BulkDeleteResponse represents the results of a bulk delete, in request
+// order
+type BulkDeleteResponse struct {
+	Deleted int                `json:"deleted"`
+	Skipped int                `json:"skipped"`
+	Failed  int                `json:"failed"`
+	Results []BulkDeleteResult `json:"results"`
+}
+
+// SQLExportOptions selects what the SQL export contains
+type SQLExportOptions struct {
+	Mode                  string `form:"mode" binding:"omitempty,oneof=create drop full"` // Defaults to SQLExportModeCreate
+	IncludeCreateDatabase bool   `form:"includeCreateDatabase"`
+	IncludeData           bool   `form:"includeData"`
+}
+
+// SchemaWriteOptions selects what create and update return besides the schema
+type SchemaWriteOptions struct {
+	IncludeSQL bool `form:"includeSql"` // Return the DDL of GET /schemas/:id/export/sql in generatedSql
+}
+
+// SQL export modes
+const (
+	SQLExportModeCreate = "create" // CREATE statements only
+	SQLExportModeDrop   = "drop"   // DROP TABLE statements only
+	SQLExportModeFull   = "full"   // DROP TABLE statements, then CREATE statements
+)
+
+// Limits of the data included in a SQL export
+const (
+	MaxDataExportRows  = 10000
+	MaxDataExportBytes = 10 << 20
+)
+
+// TableDDLResponse represents the DDL of a single table
+type TableDDLResponse struct {
+	SchemaID    uuid.UUID `json:"schemaId"`
+	TableID     string    `json:"tableId"`
+	TableName   string    `json:"tableName"`
+	Statements  []string  `json:"statements"`
+	SQL         string    `json:"sql"`
+	GeneratedAt time.Time `json:"generatedAt"`
+}
+
+// DBMLExportResponse represents the response for DBML export
+type DBMLExportResponse struct {
+	SchemaID    uuid.UUID `json:"schemaId"`
+	DBML        string    `json:"dbml"`
+	GeneratedAt time.Time `json:"generatedAt"`
+}
+
+// TypeScriptExportResponse represents the response for TypeScript export
+type TypeScriptExportResponse struct {
+	SchemaID    uuid.UUID `json:"schemaId"`
+	TypeScript  string    `json:"typescript"`
+	GeneratedAt time.Time `json:"generatedAt"`
+}
+
+// Database generation job states
+const (
+	GenerationJobNone      = "none"
+	GenerationJobQueued    = "queued"
+	GenerationJobRunning   = "running"
+	GenerationJobSucceeded = "succeeded"
+	GenerationJobFailed    = "failed"
+)
+
+// Database generation job operations
+const (
+	GenerationOperationCreate     = "create"
+	GenerationOperationRegenerate = "regenerate"
+	GenerationOperationRestore    = "restore"
+	GenerationOperationRollback   = "rollback"
+)
+
+// GenerationJob is the state of a background database generation
+type GenerationJob struct {
+	SchemaID     uuid.UUID `json:"schemaId"`
+	DatabaseName string    `json:"databaseName"`
+	Operation    string    `json:"operation,omitempty"`
+	State        string    `json:"state"`
+	Error        string    `json:"error,omitempty"`
+	// Failure locates the statement that failed, when the job failed
+	// executing DDL
+	Failure    *StatementFailure `json:"failure,omitempty"`
+	QueuedAt   *time.Time        `json:"queuedAt,omitempty"`
+	StartedAt  *time.Time        `json:"startedAt,omitempty"`
+	FinishedAt *time.Time        `json:"finishedAt,omitempty"`
+}
+
+// Schema event types
+const (
+	SchemaEventStatus = "status"
+	SchemaEventJob    = "job"
+)
+
+// SchemaEvent is a change of a schema's status, or of the state of its
+// database generation job, streamed to clients watching the schema
+type SchemaEvent struct {
+	Type     string         `json:"type"`
+	SchemaID uuid.UUID      `json:"schemaId"`
+	Status   SchemaStatus   `json:"status,omitempty"`
+	Job      *GenerationJob `json:"job,omitempty"`
+	Time     time.Time      `json:"time"`
+}
+
+// StatementFailure describes the DDL statement that failed while generating
+// a database, and the table it was generating
+type StatementFailure struct {
+	Kind       string `json:"kind"` // table, index, foreign key or foreign key index
+	TableID    string `json:"tableId"`
+	Table      string `json:"table"`
+	Constraint string `json:"constraint,omitempty"` // Constraint PostgreSQL reported, if any
+	Statement  string `json:"statement"`
+	Message    string `json:"message"`
+	Detail     string `json:"detail,omitempty"`
+}
+
+// Pagination limits enforced on every listing
+const (
+	DefaultPageLimit = 10
+	MaxPageLimit     = 100
+)
+
+// PaginationRequest represents pagination parameters. Listings that support
+// it switch from page numbers to keyset pagination when Cursor is set.
 type PaginationRequest struct {
-	Page   int    `form:"page,default=1" binding:"min=1"`
-	Limit  int    `form:"limit,default=10" binding:"min=1,max=100"`
-	Search string `form:"search"`
+	Page      int     `form:"page,default=1" binding:"min=1"`
+	Limit     int     `form:"limit,default=10" binding:"min=1,max=100"`
+	Search    string  `form:"search"`
+	Favorites bool    `form:"favorites"`
+	Cursor    *string `form:"cursor"` // Empty for the first page of a cursor-paginated listing
+	Status    string  `form:"status"`
+	Deleted   bool    `form:"deleted"`
+}
+
+// Normalize clamps the page to at least 1 and the limit to [1, MaxPageLimit],
+// whether or not the request went through binding validation
+func (p *PaginationRequest) Normalize() {
+	if p.Page < 1 {
+		p.Page = 1
+	}
+	if p.Limit < 1 {
+		p.Limit = DefaultPageLimit
+	}
+	if p.Limit > MaxPageLimit {
+		p.Limit = MaxPageLimit
+	}
+}
+
+// SchemaCursor is the position of a schema in a cursor-paginated listing,
+// ordered by creation time and then ID
+type SchemaCursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// Encode returns the cursor as an opaque base64 string of "created_at,id"
+func (c SchemaCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.CreatedAt.UTC().Format(time.RFC3339Nano) + "," + c.ID.String()))
 }
 
+// ParseSchemaCursor decodes a cursor returned by SchemaCursor.Encode. The
+// empty cursor, requesting the first page, is before every schema.
+func ParseSchemaCursor(cursor string) (SchemaCursor, error) {
+	if cursor == "" {
+		return SchemaCursor{}, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return SchemaCursor{}, fmt.Errorf("invalid cursor encoding: %w", err)
+	}
+	createdAt, id, found := strings.Cut(string(data), ",")
+	if !found {
+		return SchemaCursor{}, errors.New("invalid cursor: expected created_at,id")
+	}
+	parsedCreatedAt, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return SchemaCursor{}, fmt.Errorf("invalid cursor timestamp: %w", err)
+	}
+	parsedID, err := uuid.Parse(id)
+	if err != nil {
+		return SchemaCursor{}, fmt.Errorf("invalid cursor ID: %w", err)
+	}
+	return SchemaCursor{CreatedAt: parsedCreatedAt, ID: parsedID}, nil
+}
+
+// MaxIdentifierLength is the longest identifier, in bytes, PostgreSQL stores
+// without silently truncating it (NAMEDATALEN - 1)
+const MaxIdentifierLength = 63
+
+// SchemaStatus is the provisioning state of a schema's database
+type SchemaStatus string
+
+// Schema statuses
+const (
+	SchemaStatusCreating     SchemaStatus = "creating"
+	SchemaStatusCreated      SchemaStatus = "created"
+	SchemaStatusUpdating     SchemaStatus = "updating"
+	SchemaStatusUpdated      SchemaStatus = "updated"
+	SchemaStatusRegenerating SchemaStatus = "regenerating"
+	SchemaStatusRegenerated  SchemaStatus = "regenerated"
+	SchemaStatusError        SchemaStatus = "error"
+)
+
+// Terminal reports whether a schema stays in the status until it is changed
+// again, rather than moving on once its database is generated
+func (s SchemaStatus) Terminal() bool {
+	return s != SchemaStatusCreating && s != SchemaStatusUpdating && s != SchemaStatusRegenerating
+}
+
+// Valid schema statuses
+var ValidSchemaStatuses = map[SchemaStatus]bool{
+	SchemaStatusCreating:     true,
+	SchemaStatusCreated:      true,
+	SchemaStatusUpdating:     true,
+	SchemaStatusUpdated:      true,
+	SchemaStatusRegenerating: true,
+	SchemaStatusRegenerated:  true,
+	SchemaStatusError:        true,
+}
+
+// Schema validation statuses
+const (
+	ValidationStatusValid   = "valid"
+	ValidationStatusInvalid = "invalid"
+	ValidationStatusUnknown = "unknown"
+)
+
+// Lint finding severities
+const (
+	LintSeverityInfo    = "info"
+	LintSeverityWarning = "warning"
+)
+
 // Supported data types
 var SupportedDataTypes = map[string]bool{
 	"INT":       true,
@@ -223,10 +719,61 @@ var ValidForeignKeyActions = map[string]bool{
 	"NO ACTION": true,
 }
 
+// DefaultIndexMethod is used when an index doesn't set a method
+const DefaultIndexMethod = "btree"
+
+// Valid index access methods
+var ValidIndexMethods = map[string]bool{
+	"btree": true,
+	"gin":   true,
+	"gist":  true,
+	"hash":  true,
+	"brin":  true,
+}
+
+// Identifier case policies applied to generated names
+const (
+	IdentifierCasePreserve = "preserve"
+	IdentifierCaseSnake    = "snake"
+	IdentifierCaseLower    = "lower"
+)
+
+// Valid identifier case policies
+var ValidIdentifierCases = map[string]bool{
+	IdentifierCasePreserve: true,
+	IdentifierCaseSnake:    true,
+	IdentifierCaseLower:    true,
+}
+
 // BeforeCreate sets up UUID before creating the schema
 func (s *Schema) BeforeCreate(tx *gorm.DB) error {
 	if s.ID == uuid.Nil {
 		s.ID = uuid.New()
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// BeforeSave rejects statuses outside the SchemaStatus constants
+func (s *Schema) BeforeSave(tx *gorm.DB) error {
+	if s.Status != "" && !ValidSchemaStatuses[s.Status] {
+		return fmt.Errorf("invalid schema status %q", s.Status)
+	}
+	return nil
+}
+
+// AfterFind converts loaded timestamps to UTC, since drivers return them in
+// the connection's or the process's time zone
+func (s *Schema) AfterFind(tx *gorm.DB) error {
+	s.CreatedAt = s.CreatedAt.UTC()
+	s.UpdatedAt = s.UpdatedAt.UTC()
+	if s.LastValidatedAt != nil {
+		lastValidatedAt := s.LastValidatedAt.UTC()
+		s.LastValidatedAt = &lastValidatedAt
+	}
+	if s.LastRegeneratedAt != nil {
+		lastRegeneratedAt := s.LastRegeneratedAt.UTC()
+		s.LastRegeneratedAt = &lastRegeneratedAt
+	}
+	s.SchemaDefinition.ExportedAt = s.SchemaDefinition.ExportedAt.UTC()
+	return nil
+}
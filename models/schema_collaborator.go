@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Collaborator roles. Viewers can read a shared schema; editors can also
+// update, delete and regenerate it. Only the owner manages sharing.
+const (
+	CollaboratorRoleViewer = "viewer"
+	CollaboratorRoleEditor = "editor"
+)
+
+// SchemaCollaborator gives a user other than the owner access to a schema
+type SchemaCollaborator struct {
+	SchemaID  uuid.UUID `json:"schemaId" gorm:"type:uuid;primaryKey"`
+	UserID    uuid.UUID `json:"userId" gorm:"type:uuid;primaryKey;index"`
+	Role      string    `json:"role" gorm:"not null;size:20"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// ShareSchemaRequest represents the request to share a schema with a user.
+// Sharing with an existing collaborator changes their role.
+type ShareSchemaRequest struct {
+	UserID uuid.UUID `json:"userId" binding:"required"`
+	Role   string    `json:"role" binding:"required,oneof=viewer editor"`
+}
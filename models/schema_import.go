@@ -0,0 +1,18 @@
+package models
+
+// ImportWarning reports a part of an imported document that couldn't be
+// mapped exactly. It is located by a JSON pointer in JSON documents and by
+// line in SQL scripts.
+type ImportWarning struct {
+	Path    string `json:"path,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+// SchemaImportResponse is a schema definition built from an imported
+// document. It isn't stored: it is meant to be reviewed and then sent to
+// Create Schema.
+type SchemaImportResponse struct {
+	SchemaDefinition SchemaData      `json:"schemaDefinition"`
+	Warnings         []ImportWarning `json:"warnings"`
+}
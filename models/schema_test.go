@@ -0,0 +1,56 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestPaginationRequestNormalize(t *testing.T) {
+	tests := []struct {
+		name      string
+		page      int
+		limit     int
+		wantPage  int
+		wantLimit int
+	}{
+		{name: "within bounds", page: 3, limit: 25, wantPage: 3, wantLimit: 25},
+		{name: "huge limit", page: 1, limit: 100000, wantPage: 1, wantLimit: MaxPageLimit},
+		{name: "zero values", page: 0, limit: 0, wantPage: 1, wantLimit: DefaultPageLimit},
+		{name: "negative values", page: -2, limit: -5, wantPage: 1, wantLimit: DefaultPageLimit},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pagination := PaginationRequest{Page: test.page, Limit: test.limit}
+			pagination.Normalize()
+
+			if pagination.Page != test.wantPage || pagination.Limit != test.wantLimit {
+				t.Fatalf("Normalize() = page %d limit %d, want page %d limit %d", pagination.Page, pagination.Limit, test.wantPage, test.wantLimit)
+			}
+		})
+	}
+}
+
+func TestParseSchemaCursor(t *testing.T) {
+	first, err := ParseSchemaCursor("")
+	if err != nil || first != (SchemaCursor{}) {
+		t.Fatalf("ParseSchemaCursor(\"\") = %+v, %v, want the zero cursor", first, err)
+	}
+
+	cursor := SchemaCursor{CreatedAt: time.Date(2025, 6, 9, 3, 22, 4, 67360000, time.UTC), ID: uuid.New()}
+	parsed, err := ParseSchemaCursor(cursor.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !parsed.CreatedAt.Equal(cursor.CreatedAt) || parsed.ID != cursor.ID {
+		t.Fatalf("ParseSchemaCursor(Encode()) = %+v, want %+v", parsed, cursor)
+	}
+
+	for _, invalid := range []string{"start", "not base64!", "bm8tY29tbWE"} {
+		if _, err := ParseSchemaCursor(invalid); err == nil {
+			t.Errorf("ParseSchemaCursor(%q) = nil error, want an error", invalid)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SchemaVersion is a snapshot of a schema taken every time its definition
+// is stored: on create, update and rollback. Versions are numbered from 1,
+// matching Schema.Version at the time of the snapshot.
+type SchemaVersion struct {
+	SchemaID         uuid.UUID  `json:"schemaId" gorm:"type:uuid;primaryKey"`
+	Version          int        `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	Name             string     `json:"name" gorm:"not null"`
+	Description      string     `json:"description"`
+	SchemaDefinition SchemaData `json:"schemaDefinition" gorm:"type:jsonb"`
+	CreatedBy        uuid.UUID  `json:"createdBy" gorm:"type:uuid"` // User who stored this version
+	CreatedAt        time.Time  `json:"createdAt"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "github.com/google/uuid"
+
+// MaxDataImportBytes is the largest CSV file accepted by a table data upload
+const MaxDataImportBytes = 10 << 20
+
+// TableDataRowError reports a CSV row that wasn't inserted. Rows are numbered
+// from 1, not counting the header.
+type TableDataRowError struct {
+	Row     int    `json:"row"`
+	Message string `json:"message"`
+}
+
+// TableDataImportResponse is the outcome of loading a CSV file into a table
+// of a schema's database
+type TableDataImportResponse struct {
+	SchemaID     uuid.UUID           `json:"schemaId"`
+	TableID      string              `json:"tableId"`
+	TableName    string              `json:"tableName"`
+	RowsInserted int                 `json:"rowsInserted"`
+	Errors       []TableDataRowError `json:"errors"`
+}
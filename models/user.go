@@ -9,7 +9,7 @@ import (
 
 // User represents a user from Clerk authentication
 type User struct {
-	ID              uuid.UUID      `json:"id" gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	ID              uuid.UUID      `json:"id" gorm:"type:uuid;primary_key"`
 	ClerkUserID     string         `json:"clerkUserId" gorm:"uniqueIndex;not null"` // Clerk's user ID
 	Email           string         `json:"email" gorm:"not null"`
 	FirstName       string         `json:"firstName"`
@@ -29,4 +29,19 @@ func (u *User) GetFullName() string {
 		return u.Email
 	}
 	return u.FirstName + " " + u.LastName
-} 
\ No newline at end of file
+}
+
+// BeforeCreate sets up UUID before creating the user
+func (u *User) BeforeCreate(tx *gorm.DB) error {
+	if u.ID == uuid.Nil {
+		u.ID = uuid.New()
+	}
+	return nil
+}
+
+// AfterFind converts loaded timestamps to UTC
+func (u *User) AfterFind(tx *gorm.DB) error {
+	u.CreatedAt = u.CreatedAt.UTC()
+	u.UpdatedAt = u.UpdatedAt.UTC()
+	return nil
+}
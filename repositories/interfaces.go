@@ -1,10 +1,14 @@
 package repositories
 
 import (
+	"fmt"
+	"time"
+
 	"vdt-dashboard-backend/models"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // SchemaRepository defines the interface for schema data access
@@ -17,8 +21,13 @@ type SchemaRepository interface {
 	List(pagination models.PaginationRequest) ([]models.SchemaListResponse, int, error)
 	ListByUserID(pagination models.PaginationRequest, userID uuid.UUID) ([]models.SchemaListResponse, int, error)
 	Update(schema *models.Schema) error
+	UpdateFavorite(id, userID uuid.UUID, isFavorite bool) error
+	GetDefinitionSize(id, userID uuid.UUID) (int64, error)
 	Delete(id uuid.UUID) error
 	DeleteByIDAndUserID(id, userID uuid.UUID) error
+	GetDeletedByIDAndUserID(id, userID uuid.UUID) (*models.Schema, error)
+	RestoreByIDAndUserID(id, userID uuid.UUID) error
+	CountByStatus() (map[models.SchemaStatus]int64, error)
 }
 
 // UserRepository defines the interface for user data access
@@ -30,6 +39,39 @@ type UserRepository interface {
 	Delete(id uuid.UUID) error
 }
 
+// IdempotencyKeyRepository defines the interface for idempotency key data access
+type IdempotencyKeyRepository interface {
+	CreateIfAbsent(key *models.IdempotencyKey) (bool, error)
+	Get(key string, userID uuid.UUID) (*models.IdempotencyKey, error)
+	SetSchemaID(key string, userID, schemaID uuid.UUID) error
+	Delete(key string, userID uuid.UUID) error
+}
+
+// SchemaCollaboratorRepository defines the interface for schema collaborator
+// data access
+type SchemaCollaboratorRepository interface {
+	Upsert(collaborator *models.SchemaCollaborator) error
+	Get(schemaID, userID uuid.UUID) (*models.SchemaCollaborator, error)
+	Delete(schemaID, userID uuid.UUID) error
+}
+
+// SchemaVersionRepository defines the interface for schema version data
+// access
+type SchemaVersionRepository interface {
+	Create(version *models.SchemaVersion) error
+	Get(schemaID uuid.UUID, version int) (*models.SchemaVersion, error)
+	ListBySchemaID(schemaID uuid.UUID, pagination models.PaginationRequest) ([]models.SchemaVersion, int, error)
+}
+
+// APIKeyRepository defines the interface for API key data access
+type APIKeyRepository interface {
+	Create(key *models.APIKey) error
+	GetByPrefix(prefix string) (*models.APIKey, error)
+	ListByUserID(userID uuid.UUID) ([]models.APIKey, error)
+	Revoke(id, userID uuid.UUID) error
+	TouchLastUsed(id uuid.UUID, usedAt time.Time) error
+}
+
 // NewSchemaRepository creates a new schema repository
 func NewSchemaRepository(db *gorm.DB) SchemaRepository {
 	return &schemaRepository{db: db}
@@ -40,6 +82,44 @@ func NewUserRepository(db *gorm.DB) UserRepository {
 	return &userRepository{db: db}
 }
 
+// NewIdempotencyKeyRepository creates a new idempotency key repository
+func NewIdempotencyKeyRepository(db *gorm.DB) IdempotencyKeyRepository {
+	return &idempotencyKeyRepository{db: db}
+}
+
+// NewSchemaCollaboratorRepository creates a new schema collaborator repository
+func NewSchemaCollaboratorRepository(db *gorm.DB) SchemaCollaboratorRepository {
+	return &schemaCollaboratorRepository{db: db}
+}
+
+// NewSchemaVersionRepository creates a new schema version repository
+func NewSchemaVersionRepository(db *gorm.DB) SchemaVersionRepository {
+	return &schemaVersionRepository{db: db}
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+// likeOperator returns the case-insensitive LIKE operator of the connected
+// database. SQLite's LIKE is already case-insensitive and has no ILIKE.
+func likeOperator(db *gorm.DB) string {
+	if db.Dialector.Name() == "postgres" {
+		return "ILIKE"
+	}
+	return "LIKE"
+}
+
+// definitionSizeExpression returns the SQL expression measuring the stored
+// schema definition in bytes
+func definitionSizeExpression(db *gorm.DB) string {
+	if db.Dialector.Name() == "postgres" {
+		return "octet_length(schema_definition::text)"
+	}
+	return "length(CAST(schema_definition AS BLOB))"
+}
+
 // schemaRepository implements SchemaRepository
 type schemaRepository struct {
 	db *gorm.DB
@@ -60,16 +140,25 @@ func (r *schemaRepository) GetByID(id uuid.UUID) (*models.Schema, error) {
 	return &schema, nil
 }
 
-// GetByIDAndUserID gets a schema by ID and user ID
+// GetByIDAndUserID gets a schema by ID that the user owns or collaborates on
 func (r *schemaRepository) GetByIDAndUserID(id, userID uuid.UUID) (*models.Schema, error) {
 	var schema models.Schema
-	err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&schema).Error
+	err := r.db.Scopes(r.accessibleBy(userID)).Where("id = ?", id).First(&schema).Error
 	if err != nil {
 		return nil, err
 	}
 	return &schema, nil
 }
 
+// accessibleBy scopes a schema query to the schemas a user owns or is a
+// collaborator of
+func (r *schemaRepository) accessibleBy(userID uuid.UUID) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		shared := r.db.Model(&models.SchemaCollaborator{}).Select("schema_id").Where("user_id = ?", userID)
+		return db.Where("user_id = ? OR id IN (?)", userID, shared)
+	}
+}
+
 // GetByName gets a schema by name
 func (r *schemaRepository) GetByName(name string) (*models.Schema, error) {
 	var schema models.Schema
@@ -92,6 +181,8 @@ func (r *schemaRepository) GetByNameAndUserID(name string, userID uuid.UUID) (*m
 
 // List gets paginated list of schemas
 func (r *schemaRepository) List(pagination models.PaginationRequest) ([]models.SchemaListResponse, int, error) {
+	pagination.Normalize()
+
 	var schemas []models.Schema
 	var total int64
 
@@ -100,7 +191,11 @@ func (r *schemaRepository) List(pagination models.PaginationRequest) ([]models.S
 	// Add search filter if provided
 	if pagination.Search != "" {
 		searchPattern := "%" + pagination.Search + "%"
-		query = query.Where("name ILIKE ? OR description ILIKE ?", searchPattern, searchPattern)
+		query = query.Where(fmt.Sprintf("name %[1]s ? OR description %[1]s ?", likeOperator(r.db)), searchPattern, searchPattern)
+	}
+
+	if pagination.Favorites {
+		query = query.Where("is_favorite = ?", true)
 	}
 
 	// Count total records
@@ -110,12 +205,13 @@ func (r *schemaRepository) List(pagination models.PaginationRequest) ([]models.S
 
 	// Apply pagination
 	offset := (pagination.Page - 1) * pagination.Limit
-	if err := query.Offset(offset).Limit(pagination.Limit).Find(&schemas).Error; err != nil {
+	// Favorites are pinned to the top
+	if err := query.Order("is_favorite DESC, created_at DESC").Offset(offset).Limit(pagination.Limit).Find(&schemas).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// Convert to response format
-	var response []models.SchemaListResponse
+	response := make([]models.SchemaListResponse, 0, len(schemas))
 	for _, schema := range schemas {
 		// Safely get table count - handle case where SchemaDefinition.Tables might be nil
 		tableCount := 0
@@ -124,15 +220,19 @@ func (r *schemaRepository) List(pagination models.PaginationRequest) ([]models.S
 		}
 
 		response = append(response, models.SchemaListResponse{
-			ID:           schema.ID,
-			Name:         schema.Name,
-			Description:  schema.Description,
-			DatabaseName: schema.DatabaseName,
-			Status:       schema.Status,
-			TableCount:   tableCount,
-			CreatedAt:    schema.CreatedAt,
-			UpdatedAt:    schema.UpdatedAt,
-			Version:      schema.Version,
+			ID:                schema.ID,
+			Name:              schema.Name,
+			Description:       schema.Description,
+			DatabaseName:      schema.DatabaseName,
+			Status:            schema.Status,
+			TableCount:        tableCount,
+			CreatedAt:         schema.CreatedAt,
+			UpdatedAt:         schema.UpdatedAt,
+			Version:           schema.Version,
+			IsFavorite:        schema.IsFavorite,
+			ValidationStatus:  schema.ValidationStatus,
+			LastValidatedAt:   schema.LastValidatedAt,
+			LastRegeneratedAt: schema.LastRegeneratedAt,
 		})
 	}
 
@@ -141,15 +241,29 @@ func (r *schemaRepository) List(pagination models.PaginationRequest) ([]models.S
 
 // ListByUserID gets paginated list of schemas for a specific user
 func (r *schemaRepository) ListByUserID(pagination models.PaginationRequest, userID uuid.UUID) ([]models.SchemaListResponse, int, error) {
+	pagination.Normalize()
+
 	var schemas []models.Schema
 	var total int64
 
-	query := r.db.Model(&models.Schema{}).Where("user_id = ?", userID)
+	query := r.db.Model(&models.Schema{})
+	if pagination.Deleted {
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+	query = query.Where("user_id = ?", userID)
 
 	// Add search filter if provided
 	if pagination.Search != "" {
 		searchPattern := "%" + pagination.Search + "%"
-		query = query.Where("name ILIKE ? OR description ILIKE ?", searchPattern, searchPattern)
+		query = query.Where(fmt.Sprintf("name %[1]s ? OR description %[1]s ?", likeOperator(r.db)), searchPattern, searchPattern)
+	}
+
+	if pagination.Favorites {
+		query = query.Where("is_favorite = ?", true)
+	}
+
+	if pagination.Status != "" {
+		query = query.Where("status = ?", pagination.Status)
 	}
 
 	// Count total records
@@ -157,15 +271,35 @@ func (r *schemaRepository) ListByUserID(pagination models.PaginationRequest, use
 		return nil, 0, err
 	}
 
-	// Apply pagination
-	offset := (pagination.Page - 1) * pagination.Limit
-	if err := query.Offset(offset).Limit(pagination.Limit).Find(&schemas).Error; err != nil {
+	if pagination.Cursor != nil {
+		// Keyset pagination stays consistent while schemas are created, so
+		// favorites aren't pinned in this mode
+		cursor, err := models.ParseSchemaCursor(*pagination.Cursor)
+		if err != nil {
+			return nil, 0, err
+		}
+		if !cursor.CreatedAt.IsZero() {
+			query = query.Where("(created_at, id) < (?, ?)", cursor.CreatedAt, cursor.ID)
+		}
+		query = query.Order("created_at DESC, id DESC")
+	} else {
+		// Favorites are pinned to the top
+		offset := (pagination.Page - 1) * pagination.Limit
+		query = query.Order("is_favorite DESC, created_at DESC").Offset(offset)
+	}
+
+	if err := query.Limit(pagination.Limit).Find(&schemas).Error; err != nil {
 		return nil, 0, err
 	}
 
 	// Convert to response format
-	var response []models.SchemaListResponse
+	response := make([]models.SchemaListResponse, 0, len(schemas))
 	for _, schema := range schemas {
+		var deletedAt *time.Time
+		if schema.DeletedAt.Valid {
+			deletedAt = &schema.DeletedAt.Time
+		}
+
 		// Safely get table count - handle case where SchemaDefinition.Tables might be nil
 		tableCount := 0
 		if schema.SchemaDefinition.Tables != nil {
@@ -173,15 +307,20 @@ func (r *schemaRepository) ListByUserID(pagination models.PaginationRequest, use
 		}
 
 		response = append(response, models.SchemaListResponse{
-			ID:           schema.ID,
-			Name:         schema.Name,
-			Description:  schema.Description,
-			DatabaseName: schema.DatabaseName,
-			Status:       schema.Status,
-			TableCount:   tableCount,
-			CreatedAt:    schema.CreatedAt,
-			UpdatedAt:    schema.UpdatedAt,
-			Version:      schema.Version,
+			ID:                schema.ID,
+			Name:              schema.Name,
+			Description:       schema.Description,
+			DatabaseName:      schema.DatabaseName,
+			Status:            schema.Status,
+			TableCount:        tableCount,
+			CreatedAt:         schema.CreatedAt,
+			UpdatedAt:         schema.UpdatedAt,
+			Version:           schema.Version,
+			IsFavorite:        schema.IsFavorite,
+			ValidationStatus:  schema.ValidationStatus,
+			LastValidatedAt:   schema.LastValidatedAt,
+			LastRegeneratedAt: schema.LastRegeneratedAt,
+			DeletedAt:         deletedAt,
 		})
 	}
 
@@ -193,14 +332,83 @@ func (r *schemaRepository) Update(schema *models.Schema) error {
 	return r.db.Save(schema).Error
 }
 
+// UpdateFavorite sets the favorite flag of a schema owned by a user
+func (r *schemaRepository) UpdateFavorite(id, userID uuid.UUID, isFavorite bool) error {
+	return r.db.Model(&models.Schema{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		UpdateColumn("is_favorite", isFavorite).Error
+}
+
+// GetDefinitionSize returns the size in bytes of the stored definition of a
+// schema the user owns or collaborates on, without loading it
+func (r *schemaRepository) GetDefinitionSize(id, userID uuid.UUID) (int64, error) {
+	var size int64
+	err := r.db.Model(&models.Schema{}).
+		Scopes(r.accessibleBy(userID)).
+		Select(definitionSizeExpression(r.db)).
+		Where("id = ?", id).
+		Scan(&size).Error
+	return size, err
+}
+
 // Delete soft deletes a schema
 func (r *schemaRepository) Delete(id uuid.UUID) error {
 	return r.db.Where("id = ?", id).Delete(&models.Schema{}).Error
 }
 
-// DeleteByIDAndUserID soft deletes a schema by ID and user ID
+// DeleteByIDAndUserID soft deletes a schema by ID and user ID. It returns
+// gorm.ErrRecordNotFound when the user has no such schema.
 func (r *schemaRepository) DeleteByIDAndUserID(id, userID uuid.UUID) error {
-	return r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Schema{}).Error
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.Schema{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// GetDeletedByIDAndUserID gets a soft-deleted schema by ID and user ID
+func (r *schemaRepository) GetDeletedByIDAndUserID(id, userID uuid.UUID) (*models.Schema, error) {
+	var schema models.Schema
+	err := r.db.Unscoped().Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).First(&schema).Error
+	if err != nil {
+		return nil, err
+	}
+	return &schema, nil
+}
+
+// RestoreByIDAndUserID clears the soft delete of a schema by ID and user ID.
+// It returns gorm.ErrRecordNotFound when the user has no such deleted schema.
+func (r *schemaRepository) RestoreByIDAndUserID(id, userID uuid.UUID) error {
+	result := r.db.Unscoped().Model(&models.Schema{}).
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", id, userID).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// CountByStatus counts the schemas, excluding deleted ones, in each status
+func (r *schemaRepository) CountByStatus() (map[models.SchemaStatus]int64, error) {
+	var rows []struct {
+		Status models.SchemaStatus
+		Count  int64
+	}
+	if err := r.db.Model(&models.Schema{}).Select("status, COUNT(*) AS count").Group("status").Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[models.SchemaStatus]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
 }
 
 // userRepository implements UserRepository
@@ -242,3 +450,162 @@ func (r *userRepository) Update(user *models.User) error {
 func (r *userRepository) Delete(id uuid.UUID) error {
 	return r.db.Where("id = ?", id).Delete(&models.User{}).Error
 }
+
+// idempotencyKeyRepository implements IdempotencyKeyRepository
+type idempotencyKeyRepository struct {
+	db *gorm.DB
+}
+
+// CreateIfAbsent stores a key unless the user already has it, reporting
+// whether it was stored
+func (r *idempotencyKeyRepository) CreateIfAbsent(key *models.IdempotencyKey) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(key)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Get gets an idempotency key of a user
+func (r *idempotencyKeyRepository) Get(key string, userID uuid.UUID) (*models.IdempotencyKey, error) {
+	var idempotencyKey models.IdempotencyKey
+	err := r.db.Where("key = ? AND user_id = ?", key, userID).First(&idempotencyKey).Error
+	if err != nil {
+		return nil, err
+	}
+	return &idempotencyKey, nil
+}
+
+// SetSchemaID records the schema created for an idempotency key
+func (r *idempotencyKeyRepository) SetSchemaID(key string, userID, schemaID uuid.UUID) error {
+	return r.db.Model(&models.IdempotencyKey{}).
+		Where("key = ? AND user_id = ?", key, userID).
+		UpdateColumn("schema_id", schemaID).Error
+}
+
+// Delete deletes an idempotency key of a user
+func (r *idempotencyKeyRepository) Delete(key string, userID uuid.UUID) error {
+	return r.db.Where("key = ? AND user_id = ?", key, userID).Delete(&models.IdempotencyKey{}).Error
+}
+
+// apiKeyRepository implements APIKeyRepository
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+// Create creates a new API key
+func (r *apiKeyRepository) Create(key *models.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+// GetByPrefix gets an API key by its prefix
+func (r *apiKeyRepository) GetByPrefix(prefix string) (*models.APIKey, error) {
+	var key models.APIKey
+	err := r.db.Where("prefix = ?", prefix).First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListByUserID lists the API keys of a user, newest first, including revoked
+// and expired keys
+func (r *apiKeyRepository) ListByUserID(userID uuid.UUID) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&keys).Error
+	return keys, err
+}
+
+// Revoke revokes an API key of a user. Revoking a revoked key keeps its
+// original revocation time.
+func (r *apiKeyRepository) Revoke(id, userID uuid.UUID) error {
+	var key models.APIKey
+	if err := r.db.Where("id = ? AND user_id = ?", id, userID).First(&key).Error; err != nil {
+		return err
+	}
+	if key.RevokedAt != nil {
+		return nil
+	}
+	return r.db.Model(&key).UpdateColumn("revoked_at", time.Now().UTC()).Error
+}
+
+// TouchLastUsed records when an API key was last used
+func (r *apiKeyRepository) TouchLastUsed(id uuid.UUID, usedAt time.Time) error {
+	return r.db.Model(&models.APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", usedAt).Error
+}
+
+// schemaCollaboratorRepository implements SchemaCollaboratorRepository
+type schemaCollaboratorRepository struct {
+	db *gorm.DB
+}
+
+// Upsert adds a collaborator to a schema, or changes their role
+func (r *schemaCollaboratorRepository) Upsert(collaborator *models.SchemaCollaborator) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "schema_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"role", "updated_at"}),
+	}).Create(collaborator).Error
+}
+
+// Get gets the collaborator entry of a user on a schema
+func (r *schemaCollaboratorRepository) Get(schemaID, userID uuid.UUID) (*models.SchemaCollaborator, error) {
+	var collaborator models.SchemaCollaborator
+	err := r.db.Where("schema_id = ? AND user_id = ?", schemaID, userID).First(&collaborator).Error
+	if err != nil {
+		return nil, err
+	}
+	return &collaborator, nil
+}
+
+// Delete removes a collaborator from a schema. It returns
+// gorm.ErrRecordNotFound when the user isn't a collaborator.
+func (r *schemaCollaboratorRepository) Delete(schemaID, userID uuid.UUID) error {
+	result := r.db.Where("schema_id = ? AND user_id = ?", schemaID, userID).Delete(&models.SchemaCollaborator{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// schemaVersionRepository implements SchemaVersionRepository
+type schemaVersionRepository struct {
+	db *gorm.DB
+}
+
+// Create stores a schema version. It fails when the version already exists.
+func (r *schemaVersionRepository) Create(version *models.SchemaVersion) error {
+	return r.db.Create(version).Error
+}
+
+// Get gets a version of a schema
+func (r *schemaVersionRepository) Get(schemaID uuid.UUID, version int) (*models.SchemaVersion, error) {
+	var schemaVersion models.SchemaVersion
+	err := r.db.Where("schema_id = ? AND version = ?", schemaID, version).First(&schemaVersion).Error
+	if err != nil {
+		return nil, err
+	}
+	return &schemaVersion, nil
+}
+
+// ListBySchemaID gets a paginated list of the versions of a schema, newest
+// first
+func (r *schemaVersionRepository) ListBySchemaID(schemaID uuid.UUID, pagination models.PaginationRequest) ([]models.SchemaVersion, int, error) {
+	pagination.Normalize()
+
+	var versions []models.SchemaVersion
+	var total int64
+
+	query := r.db.Model(&models.SchemaVersion{}).Where("schema_id = ?", schemaID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (pagination.Page - 1) * pagination.Limit
+	if err := query.Order("version DESC").Offset(offset).Limit(pagination.Limit).Find(&versions).Error; err != nil {
+		return nil, 0, err
+	}
+	return versions, int(total), nil
+}
@@ -0,0 +1,120 @@
+package repositories
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/glebarez/sqlite"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDatabase opens an in-memory SQLite database with the schemas table
+func openTestDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to file::memory: gets its own database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	if err := db.AutoMigrate(&models.Schema{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestListByUserIDStatusAndSearch(t *testing.T) {
+	repo := NewSchemaRepository(openTestDatabase(t))
+	userID := uuid.New()
+	for _, schema := range []models.Schema{
+		{Name: "shop", Description: "orders and products", Status: models.SchemaStatusError},
+		{Name: "shop_archive", Status: models.SchemaStatusCreated},
+		{Name: "blog", Description: "posts for the shop", Status: models.SchemaStatusError},
+		{Name: "crm", Status: models.SchemaStatusError},
+	} {
+		schema.UserID = userID
+		schema.DatabaseName = "schema_" + schema.Name
+		if err := repo.Create(&schema); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// Another user's schema matches both filters but is never listed
+	if err := repo.Create(&models.Schema{Name: "shop", DatabaseName: "schema_shop_2", UserID: uuid.New(), Status: models.SchemaStatusError}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		status models.SchemaStatus
+		search string
+		want   []string
+	}{
+		{name: "status and search", status: models.SchemaStatusError, search: "shop", want: []string{"blog", "shop"}},
+		{name: "status only", status: models.SchemaStatusError, want: []string{"blog", "crm", "shop"}},
+		{name: "search only", search: "shop", want: []string{"blog", "shop", "shop_archive"}},
+		{name: "no match", status: models.SchemaStatusUpdating, search: "shop", want: nil},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			schemas, total, err := repo.ListByUserID(models.PaginationRequest{Page: 1, Limit: 10, Status: string(test.status), Search: test.search}, userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, schema := range schemas {
+				names = append(names, schema.Name)
+			}
+			sort.Strings(names)
+			if total != len(test.want) || len(names) != len(test.want) {
+				t.Fatalf("got %v (total %d), want %v", names, total, test.want)
+			}
+			for i := range names {
+				if names[i] != test.want[i] {
+					t.Fatalf("got %v, want %v", names, test.want)
+				}
+			}
+		})
+	}
+}
+
+func TestListByUserIDEmpty(t *testing.T) {
+	repo := NewSchemaRepository(openTestDatabase(t))
+	// Only another user has a schema
+	if err := repo.Create(&models.Schema{Name: "shop", DatabaseName: "schema_shop", UserID: uuid.New(), Status: models.SchemaStatusCreated}); err != nil {
+		t.Fatal(err)
+	}
+
+	pagination := models.PaginationRequest{Page: 1, Limit: 10}
+	schemas, total, err := repo.ListByUserID(pagination, uuid.New())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if schemas == nil || len(schemas) != 0 || total != 0 {
+		t.Fatalf("ListByUserID() = %#v, %d, want an empty non-nil list and 0", schemas, total)
+	}
+
+	body, err := json.Marshal(models.PaginatedSuccessResponse("Schemas retrieved successfully", schemas, models.PagePagination(pagination.Page, pagination.Limit, total)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{`"data":[]`, `"pagination":{"page":1,"limit":10,"total":0,"totalPages":0}`} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("response %s does not contain %s", body, want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"vdt-dashboard-backend/models"
+	"vdt-dashboard-backend/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ErrInvalidAPIKey is returned when an API key is unknown, revoked or expired
+var ErrInvalidAPIKey = errors.New("invalid API key")
+
+const (
+	// apiKeyPrefix starts every API key, so leaked keys are easy to recognise
+	apiKeyPrefix = "vdt_"
+	// apiKeyPrefixLength is how much of a key is stored in clear to find it
+	// and tell keys apart
+	apiKeyPrefixLength = 12
+)
+
+// APIKeyService defines the interface for API key management and
+// authentication
+type APIKeyService interface {
+	CreateAPIKey(userID uuid.UUID, request models.CreateAPIKeyRequest) (*models.CreatedAPIKeyResponse, error)
+	ListAPIKeys(userID uuid.UUID) ([]models.APIKey, error)
+	RevokeAPIKey(id, userID uuid.UUID) error
+	Authenticate(key string) (*models.User, error)
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(repo repositories.APIKeyRepository, userRepo repositories.UserRepository) APIKeyService {
+	return &apiKeyService{
+		repo:     repo,
+		userRepo: userRepo,
+	}
+}
+
+type apiKeyService struct {
+	repo     repositories.APIKeyRepository
+	userRepo repositories.UserRepository
+}
+
+// CreateAPIKey generates a key for a user. The key is only returned here;
+// only its salted hash is stored.
+func (s *apiKeyService) CreateAPIKey(userID uuid.UUID, request models.CreateAPIKeyRequest) (*models.CreatedAPIKeyResponse, error) {
+	key, err := randomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key = apiKeyPrefix + key
+	salt, err := randomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate API key salt: %w", err)
+	}
+
+	apiKey := models.APIKey{
+		UserID:    userID,
+		Name:      strings.TrimSpace(request.Name),
+		Prefix:    key[:apiKeyPrefixLength],
+		Salt:      salt,
+		KeyHash:   hashAPIKey(salt, key),
+		CreatedAt: time.Now().UTC(),
+	}
+	if apiKey.Name == "" {
+		return nil, fmt.Errorf("%w: API key name is required", ErrValidation)
+	}
+	if request.ExpiresInDays != nil {
+		expiresAt := apiKey.CreatedAt.AddDate(0, 0, *request.ExpiresInDays)
+		apiKey.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.Create(&apiKey); err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+	return &models.CreatedAPIKeyResponse{APIKey: apiKey, Key: key}, nil
+}
+
+// ListAPIKeys lists the keys of a user, without the keys themselves
+func (s *apiKeyService) ListAPIKeys(userID uuid.UUID) ([]models.APIKey, error) {
+	keys, err := s.repo.ListByUserID(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes a key of a user, which then no longer authenticates
+func (s *apiKeyService) RevokeAPIKey(id, userID uuid.UUID) error {
+	err := s.repo.Revoke(id, userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: API key %s", ErrNotFound, id)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return nil
+}
+
+// Authenticate returns the owner of an API key, or ErrInvalidAPIKey when the
+// key is unknown, revoked or expired. The last use of the key is recorded in
+// the background.
+func (s *apiKeyService) Authenticate(key string) (*models.User, error) {
+	if !strings.HasPrefix(key, apiKeyPrefix) || len(key) <= apiKeyPrefixLength {
+		return nil, fmt.Errorf("%w: malformed key", ErrInvalidAPIKey)
+	}
+
+	apiKey, err := s.repo.GetByPrefix(key[:apiKeyPrefixLength])
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: unknown key", ErrInvalidAPIKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	if subtle.ConstantTimeCompare([]byte(hashAPIKey(apiKey.Salt, key)), []byte(apiKey.KeyHash)) != 1 {
+		return nil, fmt.Errorf("%w: unknown key", ErrInvalidAPIKey)
+	}
+
+	now := time.Now().UTC()
+	if !apiKey.IsActive(now) {
+		return nil, fmt.Errorf("%w: key is revoked or expired", ErrInvalidAPIKey)
+	}
+
+	user, err := s.userRepo.GetByID(apiKey.UserID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: owner no longer exists", ErrInvalidAPIKey)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key owner: %w", err)
+	}
+
+	go func() {
+		if err := s.repo.TouchLastUsed(apiKey.ID, now); err != nil {
+			log.Printf("Warning: failed to record API key use: %v", err)
+		}
+	}()
+	return user, nil
+}
+
+// randomToken returns size random bytes encoded for use in URLs and headers
+func randomToken(size int) (string, error) {
+	token := make([]byte, size)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// hashAPIKey returns the stored form of an API key
+func hashAPIKey(salt, key string) string {
+	sum := sha256.Sum256([]byte(salt + key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"vdt-dashboard-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// dataDumpBatchSize is the number of rows per INSERT statement
+const dataDumpBatchSize = 100
+
+// DumpData writes batched INSERT statements for the rows of every table of a
+// generated database to w, separated by blank lines and ordered so referenced
+// tables are filled first. Rows are read through a cursor and written as they
+// are read. The dump fails once it exceeds models.MaxDataExportRows rows or
+// models.MaxDataExportBytes bytes, leaving what was written so far in w.
+func (d *databaseManagerService) DumpData(databaseName string, w io.Writer) error {
+	live, err := d.IntrospectDatabase(databaseName)
+	if err != nil {
+		return err
+	}
+
+	db, release, err := d.openDatabase(databaseName, logger.Silent)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer release()
+
+	dump := &dataDump{w: w}
+	for _, table := range tableDependencyOrder(live) {
+		if err := dump.table(db, table.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dataDump writes the INSERT statements of a data export, keeping count of
+// the rows and bytes written against the export limits
+type dataDump struct {
+	w          io.Writer
+	statements int
+	rows       int
+	bytes      int
+}
+
+// table writes the rows of table in batches of dataDumpBatchSize
+func (d *dataDump) table(db *gorm.DB, table string) error {
+	rows, err := db.Raw(fmt.Sprintf("SELECT * FROM %s", quoteIdentifier(table))).Rows()
+	if err != nil {
+		return fmt.Errorf("failed to read table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return fmt.Errorf("failed to read columns of table %s: %w", table, err)
+	}
+	columnNames := make([]string, len(columnTypes))
+	for i, columnType := range columnTypes {
+		columnNames[i] = quoteIdentifier(columnType.Name())
+	}
+	insertPrefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES\n    ", quoteIdentifier(table), strings.Join(columnNames, ", "))
+
+	var batch []string
+	for rows.Next() {
+		values := make([]any, len(columnTypes))
+		pointers := make([]any, len(columnTypes))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return fmt.Errorf("failed to read row of table %s: %w", table, err)
+		}
+
+		literals := make([]string, len(values))
+		for i, value := range values {
+			literals[i] = sqlLiteral(value, columnTypes[i].DatabaseTypeName())
+		}
+		row := "(" + strings.Join(literals, ", ") + ")"
+
+		d.rows++
+		d.bytes += len(row)
+		if d.rows > models.MaxDataExportRows || d.bytes > models.MaxDataExportBytes {
+			return fmt.Errorf("%w: data export is limited to %d rows and %d bytes", ErrQuotaExceeded, models.MaxDataExportRows, models.MaxDataExportBytes)
+		}
+
+		batch = append(batch, row)
+		if len(batch) == dataDumpBatchSize {
+			if err := d.insert(insertPrefix, batch); err != nil {
+				return err
+			}
+			batch = batch[:0]
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read table %s: %w", table, err)
+	}
+	if len(batch) > 0 {
+		return d.insert(insertPrefix, batch)
+	}
+	return nil
+}
+
+// insert writes one INSERT statement for a batch of rows
+func (d *dataDump) insert(insertPrefix string, batch []string) error {
+	separator := ""
+	if d.statements > 0 {
+		separator = "\n\n"
+	}
+	d.statements++
+
+	if _, err := io.WriteString(d.w, separator+insertPrefix+strings.Join(batch, ",\n    ")+";"); err != nil {
+		return fmt.Errorf("failed to write data export: %w", err)
+	}
+	return nil
+}
+
+// sqlLiteral renders a scanned column value as a PostgreSQL literal
+func sqlLiteral(value any, typeName string) string {
+	if value == nil {
+		return "NULL"
+	}
+
+	switch v := value.(type) {
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v))
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case int32:
+		return strconv.FormatInt(int64(v), 10)
+	case float64:
+		return floatLiteral(v, 64)
+	case float32:
+		return floatLiteral(float64(v), 32)
+	case time.Time:
+		return quoteLiteral(v.Format(time.RFC3339Nano))
+	case []byte:
+		switch typeName {
+		case "BYTEA":
+			return `'\x` + hex.EncodeToString(v) + `'::bytea`
+		case "JSON", "JSONB":
+			return quoteLiteral(string(v)) + "::" + strings.ToLower(typeName)
+		}
+		return quoteLiteral(string(v))
+	case string:
+		switch typeName {
+		case "JSON", "JSONB":
+			return quoteLiteral(v) + "::" + strings.ToLower(typeName)
+		}
+		return quoteLiteral(v)
+	default:
+		return quoteLiteral(fmt.Sprint(v))
+	}
+}
+
+// floatLiteral renders a float, quoting the special values PostgreSQL only
+// accepts as strings
+func floatLiteral(value float64, bitSize int) string {
+	switch {
+	case math.IsNaN(value):
+		return "'NaN'"
+	case math.IsInf(value, 1):
+		return "'Infinity'"
+	case math.IsInf(value, -1):
+		return "'-Infinity'"
+	}
+	return strconv.FormatFloat(value, 'g', -1, bitSize)
+}
+
+// quoteLiteral quotes a PostgreSQL string literal, escaping embedded quotes.
+// Backslashes need no escaping with standard_conforming_strings on.
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
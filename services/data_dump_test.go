@@ -0,0 +1,81 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// openTestDatabase opens an empty in-memory SQLite database
+func openTestDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection to file::memory: gets its own database
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { sqlDB.Close() })
+	return db
+}
+
+func TestDataDumpTable(t *testing.T) {
+	db := openTestDatabase(t)
+	if err := db.Exec(`CREATE TABLE "users" ("id" INTEGER PRIMARY KEY, "name" TEXT)`).Error; err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= dataDumpBatchSize+50; i++ {
+		if err := db.Exec(`INSERT INTO "users" VALUES (?, ?)`, i, fmt.Sprintf("user '%d'", i)).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var out strings.Builder
+	dump := &dataDump{w: &out}
+	if err := dump.table(db, "users"); err != nil {
+		t.Fatal(err)
+	}
+
+	statements := strings.Split(out.String(), "\n\n")
+	if len(statements) != 2 {
+		t.Fatalf("got %d statements, want 2:\n%s", len(statements), out.String())
+	}
+	for i, rows := range []int{dataDumpBatchSize, 50} {
+		if !strings.HasPrefix(statements[i], `INSERT INTO "users" ("id", "name") VALUES`) || !strings.HasSuffix(statements[i], ";") {
+			t.Errorf("statement %d = %q, want a complete INSERT", i, statements[i])
+		}
+		if got := strings.Count(statements[i], "\n    ("); got != rows {
+			t.Errorf("statement %d has %d rows, want %d", i, got, rows)
+		}
+	}
+	if !strings.Contains(statements[0], `(1, 'user ''1''')`) {
+		t.Errorf("first statement doesn't escape the quoted name: %q", statements[0][:120])
+	}
+}
+
+func TestDataDumpRowLimit(t *testing.T) {
+	db := openTestDatabase(t)
+	if err := db.Exec(`CREATE TABLE "users" ("id" INTEGER PRIMARY KEY)`).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec(`INSERT INTO "users" VALUES (1)`).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	dump := &dataDump{w: &strings.Builder{}, rows: models.MaxDataExportRows}
+	if err := dump.table(db, "users"); !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("table() = %v, want ErrQuotaExceeded", err)
+	}
+}
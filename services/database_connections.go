@@ -0,0 +1,213 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"sync"
+	"time"
+
+	"vdt-dashboard-backend/models"
+
+	"gorm.io/gorm"
+)
+
+// databaseConnections caches one connection pool per generated database, so
+// repeated requests reuse connections instead of opening a pool each time.
+// Pools idle for longer than idleTimeout are closed on the next acquire, and
+// once more than maxCached are open the least recently used idle pool is
+// closed. A pool that is evicted while in use is closed when released.
+type databaseConnections struct {
+	mu          sync.Mutex
+	pools       map[string]*cachedPool
+	maxCached   int
+	idleTimeout time.Duration
+}
+
+// cachedPool is the connection pool of one database
+type cachedPool struct {
+	db       *gorm.DB
+	sqlDB    *sql.DB
+	users    int
+	lastUsed time.Time
+	evicted  bool
+}
+
+// newDatabaseConnections creates an empty connection cache
+func newDatabaseConnections(maxCached int, idleTimeout time.Duration) *databaseConnections {
+	return &databaseConnections{
+		pools:       make(map[string]*cachedPool),
+		maxCached:   max(maxCached, 1),
+		idleTimeout: idleTimeout,
+	}
+}
+
+// acquire returns the cached pool of a database, opening it when needed.
+// Callers must call release once they are done with it.
+func (c *databaseConnections) acquire(databaseName string, open func() (*gorm.DB, *sql.DB, error)) (*gorm.DB, func(), error) {
+	c.mu.Lock()
+	c.closeIdleLocked()
+	if pool, exists := c.pools[databaseName]; exists {
+		pool.users++
+		c.mu.Unlock()
+		return pool.db, c.releaser(pool), nil
+	}
+	c.mu.Unlock()
+
+	// Connect without holding the lock, so a slow database doesn't block
+	// requests for the others
+	db, sqlDB, err := open()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// Another request may have connected in the meantime
+	if pool, exists := c.pools[databaseName]; exists {
+		sqlDB.Close()
+		pool.users++
+		return pool.db, c.releaser(pool), nil
+	}
+
+	pool := &cachedPool{db: db, sqlDB: sqlDB, users: 1, lastUsed: time.Now()}
+	c.pools[databaseName] = pool
+	c.evictLeastRecentlyUsedLocked()
+	return pool.db, c.releaser(pool), nil
+}
+
+// releaser returns the function releasing one use of pool
+func (c *databaseConnections) releaser(pool *cachedPool) func() {
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			c.mu.Lock()
+			defer c.mu.Unlock()
+
+			pool.users--
+			pool.lastUsed = time.Now()
+			if pool.evicted && pool.users == 0 {
+				closePool(pool)
+			}
+		})
+	}
+}
+
+// ping pings every cached pool, holding each so it isn't closed meanwhile.
+// Pools are pinged one at a time; ctx bounds the whole check. Pinging doesn't
+// count as a use, so it doesn't keep idle pools open.
+func (c *databaseConnections) ping(ctx context.Context) []models.DatabaseConnectionStatus {
+	c.mu.Lock()
+	names := make([]string, 0, len(c.pools))
+	pools := make([]*cachedPool, 0, len(c.pools))
+	for databaseName, pool := range c.pools {
+		pool.users++
+		names = append(names, databaseName)
+		pools = append(pools, pool)
+	}
+	c.mu.Unlock()
+
+	statuses := make([]models.DatabaseConnectionStatus, len(pools))
+	for i, pool := range pools {
+		status := models.DatabaseConnectionStatus{DatabaseName: names[i], Reachable: true}
+		if err := pool.sqlDB.PingContext(ctx); err != nil {
+			status.Reachable = false
+			status.Error = err.Error()
+		}
+		stats := pool.sqlDB.Stats()
+		status.OpenConnections, status.InUse, status.Idle = stats.OpenConnections, stats.InUse, stats.Idle
+		statuses[i] = status
+
+		c.mu.Lock()
+		pool.users--
+		if pool.evicted && pool.users == 0 {
+			closePool(pool)
+		}
+		c.mu.Unlock()
+	}
+	return statuses
+}
+
+// stats sums the connection counts of the cached pools
+func (c *databaseConnections) stats() models.DatabasePoolStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := models.DatabasePoolStats{Pools: len(c.pools)}
+	for _, pool := range c.pools {
+		poolStats := pool.sqlDB.Stats()
+		stats.OpenConnections += poolStats.OpenConnections
+		stats.InUse += poolStats.InUse
+		stats.Idle += poolStats.Idle
+	}
+	return stats
+}
+
+// evict closes the cached pool of a database, once no request uses it. It
+// must be called before the database is dropped or renamed.
+func (c *databaseConnections) evict(databaseName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if pool, exists := c.pools[databaseName]; exists {
+		c.evictLocked(databaseName, pool)
+	}
+}
+
+// close evicts every cached pool
+func (c *databaseConnections) close() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for databaseName, pool := range c.pools {
+		c.evictLocked(databaseName, pool)
+	}
+}
+
+// closeIdleLocked evicts the pools unused for longer than the idle timeout
+func (c *databaseConnections) closeIdleLocked() {
+	if c.idleTimeout <= 0 {
+		return
+	}
+	for databaseName, pool := range c.pools {
+		if pool.users == 0 && time.Since(pool.lastUsed) > c.idleTimeout {
+			c.evictLocked(databaseName, pool)
+		}
+	}
+}
+
+// evictLeastRecentlyUsedLocked evicts unused pools, least recently used
+// first, until at most maxCached are cached. Pools in use are kept even when
+// that exceeds the limit.
+func (c *databaseConnections) evictLeastRecentlyUsedLocked() {
+	for len(c.pools) > c.maxCached {
+		var oldestName string
+		var oldest *cachedPool
+		for databaseName, pool := range c.pools {
+			if pool.users == 0 && (oldest == nil || pool.lastUsed.Before(oldest.lastUsed)) {
+				oldestName, oldest = databaseName, pool
+			}
+		}
+		if oldest == nil {
+			return
+		}
+		c.evictLocked(oldestName, oldest)
+	}
+}
+
+// evictLocked removes a pool from the cache and closes it unless in use
+func (c *databaseConnections) evictLocked(databaseName string, pool *cachedPool) {
+	delete(c.pools, databaseName)
+	pool.evicted = true
+	if pool.users == 0 {
+		closePool(pool)
+	}
+}
+
+// closePool closes the connections of a pool
+func closePool(pool *cachedPool) {
+	if err := pool.sqlDB.Close(); err != nil {
+		log.Printf("Warning: failed to close database connections: %v", err)
+	}
+}
@@ -0,0 +1,87 @@
+package services
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	"vdt-dashboard-backend/config"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// openSQLite returns an open function for the connection cache that connects
+// to an in-memory SQLite database and counts how often it is called
+func openSQLite(opens *int) func() (*gorm.DB, *sql.DB, error) {
+	return func() (*gorm.DB, *sql.DB, error) {
+		*opens++
+		db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+		if err != nil {
+			return nil, nil, err
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, nil, err
+		}
+		return db, sqlDB, nil
+	}
+}
+
+func TestGetDatabaseStatusReusesConnections(t *testing.T) {
+	manager := NewDatabaseManagerService(&config.Config{DynamicDBMaxCached: 4, DynamicDBIdleTimeout: 300}).(*databaseManagerService)
+	t.Cleanup(func() { manager.Close() })
+
+	// Connect once through the cache, so status checks find the pool there
+	// instead of dialing PostgreSQL
+	opens := 0
+	_, release, err := manager.connections.acquire("schema_shop", openSQLite(&opens))
+	if err != nil {
+		t.Fatal(err)
+	}
+	release()
+	before := manager.PoolStats()
+
+	for range 20 {
+		status, err := manager.GetDatabaseStatus("schema_shop")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if status.Status != "healthy" {
+			t.Fatalf("status = %s, want healthy", status.Status)
+		}
+	}
+
+	after := manager.PoolStats()
+	if opens != 1 || after.Pools != 1 {
+		t.Fatalf("opened %d pools, %d cached, want 1", opens, after.Pools)
+	}
+	if after.OpenConnections > max(before.OpenConnections, 1) {
+		t.Fatalf("open connections grew from %d to %d", before.OpenConnections, after.OpenConnections)
+	}
+	if after.InUse != 0 {
+		t.Fatalf("%d connections still in use", after.InUse)
+	}
+}
+
+func TestDatabaseConnectionsEvictsLeastRecentlyUsed(t *testing.T) {
+	connections := newDatabaseConnections(2, time.Hour)
+	opens := 0
+	for _, name := range []string{"schema_a", "schema_b", "schema_a", "schema_c"} {
+		_, release, err := connections.acquire(name, openSQLite(&opens))
+		if err != nil {
+			t.Fatal(err)
+		}
+		release()
+	}
+
+	if opens != 3 {
+		t.Fatalf("opened %d pools, want 3", opens)
+	}
+	if stats := connections.stats(); stats.Pools != 2 {
+		t.Fatalf("%d pools cached, want 2", stats.Pools)
+	}
+	if _, cached := connections.pools["schema_b"]; cached {
+		t.Fatal("schema_b is still cached, want it evicted as least recently used")
+	}
+}
@@ -0,0 +1,246 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"vdt-dashboard-backend/config"
+	"vdt-dashboard-backend/models"
+)
+
+// DBMLGeneratorService defines the interface for DBML generation
+type DBMLGeneratorService interface {
+	GenerateDBML(schemaData models.SchemaData) (string, error)
+}
+
+// NewDBMLGeneratorService creates a new DBML generator service
+func NewDBMLGeneratorService(cfg *config.Config) DBMLGeneratorService {
+	return &dbmlGeneratorService{
+		config: cfg,
+	}
+}
+
+type dbmlGeneratorService struct {
+	config *config.Config
+}
+
+// dbmlIdentifier matches names usable unquoted in DBML
+var dbmlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// GenerateDBML renders a schema definition as DBML for dbdiagram.io: one
+// Table block per table, with indexes and the stored position as a note, and
+// one Ref per foreign key. Names follow the identifier case policy, so they
+// match the generated database.
+func (g *dbmlGeneratorService) GenerateDBML(schemaData models.SchemaData) (string, error) {
+	schemaData = applyIdentifierCase(schemaData, identifierCase(schemaData.IdentifierCase, g.config))
+	indexes, _ := resolveIndexes(schemaData)
+
+	var out strings.Builder
+	out.WriteString("// Generated from schema definition. Do not edit by hand.\n")
+
+	for _, table := range schemaData.Tables {
+		columns := orderedColumns(table.Columns)
+		var primaryKey []string
+		for _, column := range columns {
+			if column.PrimaryKey {
+				primaryKey = append(primaryKey, dbmlName(column.Name))
+			}
+		}
+
+		fmt.Fprintf(&out, "\nTable %s {\n", dbmlName(table.Name))
+		for _, column := range columns {
+			fmt.Fprintf(&out, "  %s %s", dbmlName(column.Name), dbmlType(column))
+			if settings := dbmlColumnSettings(column, len(primaryKey) == 1); len(settings) > 0 {
+				fmt.Fprintf(&out, " [%s]", strings.Join(settings, ", "))
+			}
+			out.WriteString("\n")
+		}
+
+		// Composite primary keys can only be declared as an index
+		var tableIndexes []string
+		if len(primaryKey) > 1 {
+			tableIndexes = append(tableIndexes, fmt.Sprintf("(%s) [pk]", strings.Join(primaryKey, ", ")))
+		}
+		for _, index := range indexes {
+			if index.tableID == table.ID {
+				tableIndexes = append(tableIndexes, dbmlIndex(index))
+			}
+		}
+		if len(tableIndexes) > 0 {
+			out.WriteString("\n  indexes {\n")
+			for _, index := range tableIndexes {
+				out.WriteString("    " + index + "\n")
+			}
+			out.WriteString("  }\n")
+		}
+
+		// DBML allows one note per table, so the position follows the comment
+		var note []string
+		if table.Comment != "" {
+			note = append(note, table.Comment)
+		}
+		if hasPosition(table) {
+			note = append(note, fmt.Sprintf("position: x=%s, y=%s", svgNumber(table.Position.X), svgNumber(table.Position.Y)))
+		}
+		if len(note) > 0 {
+			fmt.Fprintf(&out, "\n  Note: %s\n", dbmlString(strings.Join(note, "\n")))
+		}
+		out.WriteString("}\n")
+	}
+
+	refs := g.dbmlRefs(schemaData)
+	if len(refs) > 0 {
+		out.WriteString("\n")
+		for _, ref := range refs {
+			out.WriteString(ref + "\n")
+		}
+	}
+
+	return out.String(), nil
+}
+
+// dbmlRefs renders each valid foreign key as a many-to-one Ref with the
+// actions the generator applies
+func (g *dbmlGeneratorService) dbmlRefs(schemaData models.SchemaData) []string {
+	tableNames := make(map[string]string)
+	columnNames := make(map[string]string)
+	for _, table := range schemaData.Tables {
+		tableNames[table.ID] = dbmlName(table.Name)
+		for _, column := range table.Columns {
+			columnNames[column.ID] = dbmlName(column.Name)
+		}
+	}
+
+	var refs []string
+	for _, fk := range schemaData.ForeignKeys {
+		sourceTable, sourceTableExists := tableNames[fk.SourceTableId]
+		targetTable, targetTableExists := tableNames[fk.TargetTableId]
+		sourceColumns, sourceColumnsExist := columnNamesByID(fk.SourceColumns(), columnNames)
+		targetColumns, targetColumnsExist := columnNamesByID(fk.TargetColumns(), columnNames)
+		if !sourceTableExists || !targetTableExists || !sourceColumnsExist || !targetColumnsExist || len(sourceColumns) != len(targetColumns) {
+			continue
+		}
+
+		ref := "Ref"
+		if fk.Name != "" {
+			ref += " " + dbmlName(fk.Name)
+		}
+		refs = append(refs, fmt.Sprintf("%s: %s > %s [delete: %s, update: %s]",
+			ref,
+			dbmlColumnRef(sourceTable, sourceColumns),
+			dbmlColumnRef(targetTable, targetColumns),
+			strings.ToLower(effectiveForeignKeyAction(fk.OnDelete, g.config.DefaultFKOnDelete)),
+			strings.ToLower(effectiveForeignKeyAction(fk.OnUpdate, g.config.DefaultFKOnUpdate)),
+		))
+	}
+	return refs
+}
+
+// dbmlColumnRef formats columns as table.column or table.(a, b)
+func dbmlColumnRef(table string, columns []string) string {
+	if len(columns) == 1 {
+		return table + "." + columns[0]
+	}
+	return fmt.Sprintf("%s.(%s)", table, strings.Join(columns, ", "))
+}
+
+// dbmlType maps a column data type to the PostgreSQL type it is generated as
+func dbmlType(column models.Column) string {
+	if column.IsArray {
+		element := column
+		element.IsArray = false
+		return `"` + strings.Trim(dbmlType(element), `"`) + `[]"`
+	}
+
+	switch column.DataType {
+	case "INT":
+		return "integer"
+	case "VARCHAR", "DECIMAL":
+		return strings.ToLower(describeColumnType(column))
+	case "TIMESTAMP":
+		return "timestamptz"
+	case "FLOAT":
+		return "real"
+	case "DOUBLE":
+		return `"double precision"`
+	case "JSON":
+		return "jsonb"
+	default:
+		return strings.ToLower(column.DataType)
+	}
+}
+
+// dbmlColumnSettings returns the column settings in brackets. Only a
+// single-column primary key is marked on the column itself.
+func dbmlColumnSettings(column models.Column, singlePrimaryKey bool) []string {
+	var settings []string
+	if column.PrimaryKey && singlePrimaryKey {
+		settings = append(settings, "pk")
+	}
+	if column.AutoIncrement {
+		settings = append(settings, "increment")
+	}
+	if column.Unique && !column.PrimaryKey {
+		settings = append(settings, "unique")
+	}
+	if !column.IsNullable() {
+		settings = append(settings, "not null")
+	}
+
+	if column.DefaultExpression != "" {
+		settings = append(settings, "default: `"+column.DefaultExpression+"`")
+	} else {
+		switch v := column.DefaultValue.(type) {
+		case string:
+			if function := defaultFunction(v); function != "" {
+				settings = append(settings, "default: `"+function+"`")
+			} else if v != "" {
+				settings = append(settings, "default: "+dbmlString(v))
+			}
+		case bool, float64:
+			settings = append(settings, fmt.Sprintf("default: %v", v))
+		}
+	}
+
+	if column.Comment != "" {
+		settings = append(settings, "note: "+dbmlString(column.Comment))
+	}
+
+	return settings
+}
+
+// dbmlIndex renders a user-defined index inside an indexes block. Partial
+// index predicates have no DBML equivalent and are kept in the index's note.
+func dbmlIndex(index resolvedIndex) string {
+	columns := make([]string, len(index.columns))
+	for i, column := range index.columns {
+		columns[i] = dbmlName(column)
+	}
+
+	settings := []string{"name: " + dbmlString(index.name)}
+	if index.unique {
+		settings = append(settings, "unique")
+	}
+	if index.method != "" {
+		settings = append(settings, "type: "+index.method)
+	}
+	if index.where != nil {
+		settings = append(settings, "note: "+dbmlString("where "+*index.where))
+	}
+	return fmt.Sprintf("(%s) [%s]", strings.Join(columns, ", "), strings.Join(settings, ", "))
+}
+
+// dbmlName quotes a name that isn't a plain identifier
+func dbmlName(name string) string {
+	if dbmlIdentifier.MatchString(name) {
+		return name
+	}
+	return `"` + strings.ReplaceAll(name, `"`, `\"`) + `"`
+}
+
+// dbmlString renders a single-quoted DBML string
+func dbmlString(value string) string {
+	value = strings.NewReplacer(`\`, `\\`, "'", `\'`, "\n", `\n`).Replace(value)
+	return "'" + value + "'"
+}
@@ -0,0 +1,36 @@
+package services
+
+import "testing"
+
+func TestDBMLIndex(t *testing.T) {
+	active := "deleted_at IS NULL AND status = 'active'"
+	tests := []struct {
+		name  string
+		index resolvedIndex
+		want  string
+	}{
+		{
+			name:  "plain index",
+			index: resolvedIndex{name: "idx_users_email", columns: []string{"email"}},
+			want:  "(email) [name: 'idx_users_email']",
+		},
+		{
+			name:  "unique index with method",
+			index: resolvedIndex{name: "idx_users_tags", columns: []string{"tags", "email"}, unique: true, method: "gin"},
+			want:  "(tags, email) [name: 'idx_users_tags', unique, type: gin]",
+		},
+		{
+			name:  "partial index",
+			index: resolvedIndex{name: "idx_users_active", columns: []string{"email"}, unique: true, where: &active},
+			want:  `(email) [name: 'idx_users_active', unique, note: 'where deleted_at IS NULL AND status = \'active\'']`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := dbmlIndex(test.index); got != test.want {
+				t.Fatalf("dbmlIndex() = %s, want %s", got, test.want)
+			}
+		})
+	}
+}
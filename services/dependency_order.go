@@ -0,0 +1,164 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"vdt-dashboard-backend/models"
+)
+
+// tableDependencies maps each table ID to the IDs of the tables it references,
+// in foreign key order. Self references and unknown tables are ignored.
+func tableDependencies(schemaData models.SchemaData) map[string][]string {
+	dependencies := make(map[string][]string)
+	for _, table := range schemaData.Tables {
+		dependencies[table.ID] = nil
+	}
+	for _, fk := range schemaData.ForeignKeys {
+		_, sourceExists := dependencies[fk.SourceTableId]
+		_, targetExists := dependencies[fk.TargetTableId]
+		if !sourceExists || !targetExists || fk.SourceTableId == fk.TargetTableId {
+			continue
+		}
+		dependencies[fk.SourceTableId] = append(dependencies[fk.SourceTableId], fk.TargetTableId)
+	}
+	return dependencies
+}
+
+// tableDependencyOrder returns the tables of schemaData ordered so every table
+// follows the tables it references, keeping definition order otherwise.
+// Tables in a reference cycle, or referencing one, keep their definition
+// order after the tables that could be placed.
+func tableDependencyOrder(schemaData models.SchemaData) []models.Table {
+	remaining := make(map[string]map[string]bool)
+	for id, targets := range tableDependencies(schemaData) {
+		remaining[id] = make(map[string]bool)
+		for _, target := range targets {
+			remaining[id][target] = true
+		}
+	}
+
+	ordered := make([]models.Table, 0, len(schemaData.Tables))
+	placed := make(map[string]bool)
+	for progress := true; progress; {
+		progress = false
+		for _, table := range schemaData.Tables {
+			if placed[table.ID] || len(remaining[table.ID]) > 0 {
+				continue
+			}
+			ordered = append(ordered, table)
+			placed[table.ID] = true
+			progress = true
+			for _, deps := range remaining {
+				delete(deps, table.ID)
+			}
+		}
+	}
+
+	for _, table := range schemaData.Tables {
+		if !placed[table.ID] {
+			ordered = append(ordered, table)
+		}
+	}
+	return ordered
+}
+
+// foreignKeyCycles returns the groups of tables that reference each other
+// through foreign keys, each listing table IDs in definition order. These are
+// the strongly connected components of the reference graph with more than one
+// table, found with Tarjan's algorithm.
+func foreignKeyCycles(schemaData models.SchemaData) [][]string {
+	dependencies := tableDependencies(schemaData)
+	position := make(map[string]int)
+	for i, table := range schemaData.Tables {
+		position[table.ID] = i
+	}
+
+	index := make(map[string]int)
+	lowLink := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var cycles [][]string
+
+	var visit func(id string)
+	visit = func(id string) {
+		index[id] = len(index)
+		lowLink[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+
+		for _, target := range dependencies[id] {
+			if _, visited := index[target]; !visited {
+				visit(target)
+				lowLink[id] = min(lowLink[id], lowLink[target])
+			} else if onStack[target] {
+				lowLink[id] = min(lowLink[id], index[target])
+			}
+		}
+
+		if lowLink[id] != index[id] {
+			return
+		}
+		var component []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			component = append(component, top)
+			if top == id {
+				break
+			}
+		}
+		if len(component) > 1 {
+			sort.Slice(component, func(i, j int) bool { return position[component[i]] < position[component[j]] })
+			cycles = append(cycles, component)
+		}
+	}
+
+	for _, table := range schemaData.Tables {
+		if _, visited := index[table.ID]; !visited {
+			visit(table.ID)
+		}
+	}
+
+	sort.Slice(cycles, func(i, j int) bool { return position[cycles[i][0]] < position[cycles[j][0]] })
+	return cycles
+}
+
+// foreignKeyCycleWarnings describes every foreign key cycle of schemaData.
+// Rows in a cycle can only be inserted with nullable or deferred keys, and
+// cascading deletes that run all the way around it are easy to miss.
+// defaultOnDelete is the action used for foreign keys without a valid one.
+func foreignKeyCycleWarnings(schemaData models.SchemaData, defaultOnDelete string) []string {
+	names := make(map[string]string)
+	for _, table := range schemaData.Tables {
+		names[table.ID] = table.Name
+	}
+
+	var warnings []string
+	for _, cycle := range foreignKeyCycles(schemaData) {
+		members := make(map[string]bool)
+		quoted := make([]string, len(cycle))
+		for i, id := range cycle {
+			members[id] = true
+			quoted[i] = "'" + names[id] + "'"
+		}
+
+		cascades := true
+		for _, fk := range schemaData.ForeignKeys {
+			if members[fk.SourceTableId] && members[fk.TargetTableId] && fk.SourceTableId != fk.TargetTableId &&
+				effectiveForeignKeyAction(fk.OnDelete, defaultOnDelete) != "CASCADE" {
+				cascades = false
+				break
+			}
+		}
+
+		warning := fmt.Sprintf("Tables %s reference each other in a foreign key cycle, rows can only be inserted with nullable or deferred keys", strings.Join(quoted, ", "))
+		if cascades {
+			warning += ", and a delete cascades around the whole cycle"
+		}
+		warnings = append(warnings, warning)
+	}
+	return warnings
+}
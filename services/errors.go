@@ -0,0 +1,86 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// Sentinel errors returned by services. Handlers map them to HTTP statuses
+// with errors.Is, so services wrap them with context using %w.
+var (
+	ErrNotFound             = errors.New("not found")
+	ErrDuplicate            = errors.New("already exists")
+	ErrValidation           = errors.New("validation failed")
+	ErrQuotaExceeded        = errors.New("quota exceeded")
+	ErrDatabaseProvision    = errors.New("database provisioning failed")
+	ErrTooLarge             = errors.New("too large")
+	ErrGenerationInProgress = errors.New("database generation in progress")
+	ErrIdempotencyConflict  = errors.New("idempotency key conflict")
+	ErrForbidden            = errors.New("forbidden")
+	ErrForeignKeyViolation  = errors.New("existing rows violate a foreign key")
+	ErrQueryTimeout         = errors.New("query timed out")
+	ErrQueryCanceled        = errors.New("query canceled")
+)
+
+// schemaLookupError converts a repository error from loading a schema into a
+// service error
+func schemaLookupError(id uuid.UUID, err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return fmt.Errorf("%w: schema %s", ErrNotFound, id)
+	}
+	return fmt.Errorf("failed to get schema: %w", err)
+}
+
+// SchemaValidationError is returned when a schema definition has validation
+// errors. It wraps ErrValidation and carries the full validation result.
+type SchemaValidationError struct {
+	Result *models.ValidationResult
+}
+
+func (e *SchemaValidationError) Error() string {
+	return fmt.Sprintf("%s: schema definition has %d error(s)", ErrValidation, len(e.Result.Errors))
+}
+
+func (e *SchemaValidationError) Unwrap() error {
+	return ErrValidation
+}
+
+// StatementError is returned when a DDL statement fails while generating a
+// database. It wraps the database error and locates the failed statement.
+type StatementError struct {
+	Failure *models.StatementFailure
+	Err     error
+}
+
+// newStatementError describes the failure of a statement generating table,
+// taking the message and constraint from the PostgreSQL error when there is one
+func newStatementError(kind string, table models.Table, statement string, err error) *StatementError {
+	failure := &models.StatementFailure{
+		Kind:      kind,
+		TableID:   table.ID,
+		Table:     table.Name,
+		Statement: statement,
+		Message:   err.Error(),
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		failure.Constraint = pgErr.ConstraintName
+		failure.Message = pgErr.Message
+		failure.Detail = pgErr.Detail
+	}
+	return &StatementError{Failure: failure, Err: err}
+}
+
+func (e *StatementError) Error() string {
+	return fmt.Sprintf("failed to execute %s statement of table %s: %v\nStatement: %s", e.Failure.Kind, e.Failure.Table, e.Err, e.Failure.Statement)
+}
+
+func (e *StatementError) Unwrap() error {
+	return e.Err
+}
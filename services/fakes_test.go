@@ -0,0 +1,319 @@
+package services
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"vdt-dashboard-backend/config"
+	"vdt-dashboard-backend/models"
+	"vdt-dashboard-backend/repositories"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// fakeSchemaRepository keeps schemas in memory. Methods a test doesn't set
+// up panic through the nil embedded interface.
+type fakeSchemaRepository struct {
+	repositories.SchemaRepository
+	mu        sync.Mutex
+	schemas   map[uuid.UUID]models.Schema
+	updates   int
+	updateErr error
+}
+
+func newFakeSchemaRepository(schemas ...models.Schema) *fakeSchemaRepository {
+	repo := &fakeSchemaRepository{schemas: make(map[uuid.UUID]models.Schema)}
+	for _, schema := range schemas {
+		repo.schemas[schema.ID] = schema
+	}
+	return repo
+}
+
+// count returns the number of stored schemas
+func (r *fakeSchemaRepository) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.schemas)
+}
+
+func (r *fakeSchemaRepository) Create(schema *models.Schema) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if schema.ID == uuid.Nil {
+		schema.ID = uuid.New()
+	}
+	r.schemas[schema.ID] = *schema
+	return nil
+}
+
+func (r *fakeSchemaRepository) GetByID(id uuid.UUID) (*models.Schema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	schema, ok := r.schemas[id]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &schema, nil
+}
+
+func (r *fakeSchemaRepository) GetByIDAndUserID(id, userID uuid.UUID) (*models.Schema, error) {
+	schema, err := r.GetByID(id)
+	if err != nil || schema.UserID != userID || schema.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return schema, nil
+}
+
+func (r *fakeSchemaRepository) GetByNameAndUserID(name string, userID uuid.UUID) (*models.Schema, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, schema := range r.schemas {
+		if schema.Name == name && schema.UserID == userID && !schema.DeletedAt.Valid {
+			return &schema, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *fakeSchemaRepository) Update(schema *models.Schema) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.updates++
+	if r.updateErr != nil {
+		return r.updateErr
+	}
+	r.schemas[schema.ID] = *schema
+	return nil
+}
+
+func (r *fakeSchemaRepository) GetDeletedByIDAndUserID(id, userID uuid.UUID) (*models.Schema, error) {
+	schema, err := r.GetByID(id)
+	if err != nil || schema.UserID != userID || !schema.DeletedAt.Valid {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return schema, nil
+}
+
+func (r *fakeSchemaRepository) RestoreByIDAndUserID(id, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	schema, ok := r.schemas[id]
+	if !ok || schema.UserID != userID || !schema.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+	schema.DeletedAt = gorm.DeletedAt{}
+	r.schemas[id] = schema
+	return nil
+}
+
+func (r *fakeSchemaRepository) DeleteByIDAndUserID(id, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	schema, ok := r.schemas[id]
+	if !ok || schema.UserID != userID || schema.DeletedAt.Valid {
+		return gorm.ErrRecordNotFound
+	}
+	schema.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.schemas[id] = schema
+	return nil
+}
+
+// fakeSchemaVersionRepository keeps schema versions in memory
+type fakeSchemaVersionRepository struct {
+	repositories.SchemaVersionRepository
+	mu       sync.Mutex
+	versions []models.SchemaVersion
+}
+
+func (r *fakeSchemaVersionRepository) Create(version *models.SchemaVersion) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.versions = append(r.versions, *version)
+	return nil
+}
+
+func (r *fakeSchemaVersionRepository) Get(schemaID uuid.UUID, version int) (*models.SchemaVersion, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, stored := range r.versions {
+		if stored.SchemaID == schemaID && stored.Version == version {
+			return &stored, nil
+		}
+	}
+	return nil, gorm.ErrRecordNotFound
+}
+
+// fakeIdempotencyKeyRepository keeps idempotency keys in memory
+type fakeIdempotencyKeyRepository struct {
+	mu   sync.Mutex
+	keys map[string]models.IdempotencyKey
+}
+
+func newFakeIdempotencyKeyRepository() *fakeIdempotencyKeyRepository {
+	return &fakeIdempotencyKeyRepository{keys: make(map[string]models.IdempotencyKey)}
+}
+
+func (r *fakeIdempotencyKeyRepository) CreateIfAbsent(key *models.IdempotencyKey) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	id := key.UserID.String() + "/" + key.Key
+	if _, exists := r.keys[id]; exists {
+		return false, nil
+	}
+	if key.CreatedAt.IsZero() {
+		key.CreatedAt = time.Now()
+	}
+	r.keys[id] = *key
+	return true, nil
+}
+
+func (r *fakeIdempotencyKeyRepository) Get(key string, userID uuid.UUID) (*models.IdempotencyKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored, exists := r.keys[userID.String()+"/"+key]
+	if !exists {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return &stored, nil
+}
+
+func (r *fakeIdempotencyKeyRepository) SetSchemaID(key string, userID, schemaID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stored := r.keys[userID.String()+"/"+key]
+	stored.SchemaID = &schemaID
+	r.keys[userID.String()+"/"+key] = stored
+	return nil
+}
+
+func (r *fakeIdempotencyKeyRepository) Delete(key string, userID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.keys, userID.String()+"/"+key)
+	return nil
+}
+
+// fakeDatabaseManager records the databases it is asked to change instead of
+// touching PostgreSQL
+type fakeDatabaseManager struct {
+	DatabaseManagerService
+	mu          sync.Mutex
+	databases   map[string]bool
+	renames     [][2]string
+	regenerated []string
+	migrated    []string
+	dropped     []string
+	dropErr     error
+	rebuilt     []string
+	rebuildErr  error
+	onLock      func(schemaID uuid.UUID) // Runs when the generation lock is taken
+	lockHeld    int                      // Number of lock attempts that find the lock held
+}
+
+func newFakeDatabaseManager(databases ...string) *fakeDatabaseManager {
+	manager := &fakeDatabaseManager{databases: make(map[string]bool)}
+	for _, name := range databases {
+		manager.databases[name] = true
+	}
+	return manager
+}
+
+func (d *fakeDatabaseManager) LockGeneration(schemaID uuid.UUID) (func(), error) {
+	d.mu.Lock()
+	held := d.lockHeld > 0
+	if held {
+		d.lockHeld--
+	}
+	d.mu.Unlock()
+	if held {
+		return nil, ErrGenerationInProgress
+	}
+
+	if d.onLock != nil {
+		d.onLock(schemaID)
+	}
+	return func() {}, nil
+}
+
+func (d *fakeDatabaseManager) RenameDatabase(oldName, newName string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.databases[newName] {
+		return config.ErrDatabaseExists
+	}
+	delete(d.databases, oldName)
+	d.databases[newName] = true
+	d.renames = append(d.renames, [2]string{oldName, newName})
+	return nil
+}
+
+func (d *fakeDatabaseManager) DatabaseExists(databaseName string) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.databases[databaseName], nil
+}
+
+func (d *fakeDatabaseManager) DropDatabase(databaseName string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.dropErr != nil {
+		return d.dropErr
+	}
+	delete(d.databases, databaseName)
+	d.dropped = append(d.dropped, databaseName)
+	return nil
+}
+
+func (d *fakeDatabaseManager) RegenerateDatabase(ctx context.Context, schemaData models.SchemaData, databaseName string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.databases[databaseName] = true
+	d.regenerated = append(d.regenerated, databaseName)
+	return nil
+}
+
+func (d *fakeDatabaseManager) RebuildForeignKeys(schemaData models.SchemaData, databaseName string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.rebuildErr != nil {
+		return d.rebuildErr
+	}
+	d.rebuilt = append(d.rebuilt, databaseName)
+	return nil
+}
+
+func (d *fakeDatabaseManager) MigrateDatabase(ctx context.Context, old, new models.SchemaData, databaseName string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.migrated = append(d.migrated, databaseName)
+	return nil
+}
+
+// newTestSchemaService returns a schema service backed by repo and manager,
+// with the real validator and SQL generator
+func newTestSchemaService(t *testing.T, repo repositories.SchemaRepository, manager DatabaseManagerService) *schemaService {
+	t.Helper()
+
+	cfg := &config.Config{GenerationWorkers: 1}
+	service := NewSchemaService(repo, nil, nil, &fakeSchemaVersionRepository{}, nil, manager, NewValidatorService(cfg), NewSQLGeneratorService(cfg), cfg).(*schemaService)
+	t.Cleanup(func() {
+		service.WaitForJobs(context.Background())
+		service.CloseEvents()
+	})
+	return service
+}
+
+// testTables returns a minimal valid table definition
+func testTables() []models.Table {
+	return []models.Table{{
+		ID:   "t1",
+		Name: "users",
+		Columns: []models.Column{
+			{ID: "c1", Name: "id", DataType: "INT", PrimaryKey: true},
+			{ID: "c2", Name: "email", DataType: "VARCHAR", Unique: true},
+		},
+	}}
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"vdt-dashboard-backend/config"
+	"vdt-dashboard-backend/models"
+)
+
+// foreignKeyColumns identifies the referencing columns of a foreign key
+type foreignKeyColumns struct {
+	tableID string
+	table   string
+	columns []string
+}
+
+// label returns the columns as "table.column" or "table.(a, b)"
+func (f foreignKeyColumns) label() string {
+	if len(f.columns) == 1 {
+		return f.table + "." + f.columns[0]
+	}
+	return fmt.Sprintf("%s.(%s)", f.table, strings.Join(f.columns, ", "))
+}
+
+// GenerateForeignKeyIndexes creates an index on every foreign key column not
+// already covered by a primary key, unique constraint or index. PostgreSQL
+// does not index the referencing side of a foreign key, which makes joins and
+// cascading deletes scan the whole table. Nothing is generated unless
+// autoIndexForeignKeys is enabled for the schema or in the configuration.
+func (g *sqlGeneratorService) GenerateForeignKeyIndexes(schemaData models.SchemaData) ([]string, error) {
+	return g.generateForeignKeyIndexes(schemaData, ""), nil
+}
+
+// GenerateTableForeignKeyIndexes creates the foreign key indexes on the table
+// with tableID, named consistently with GenerateForeignKeyIndexes
+func (g *sqlGeneratorService) GenerateTableForeignKeyIndexes(tableID string, schemaData models.SchemaData) ([]string, error) {
+	return g.generateForeignKeyIndexes(schemaData, tableID), nil
+}
+
+// generateForeignKeyIndexes creates the foreign key indexes of schemaData,
+// limited to the table with tableID unless it is empty
+func (g *sqlGeneratorService) generateForeignKeyIndexes(schemaData models.SchemaData, tableID string) []string {
+	if !autoIndexForeignKeys(schemaData.AutoIndexForeignKeys, g.config) {
+		return nil
+	}
+	schemaData = applyIdentifierCase(schemaData, identifierCase(schemaData.IdentifierCase, g.config))
+
+	// Index names share a namespace with tables and user-defined indexes
+	_, usedNames := resolveIndexes(schemaData)
+
+	var statements []string
+	for _, fkColumns := range unindexedForeignKeyColumns(schemaData) {
+		indexName := uniqueIdentifier(fmt.Sprintf("idx_%s_%s", fkColumns.table, strings.Join(fkColumns.columns, "_")), usedNames)
+		usedNames[indexName] = true
+
+		if tableID != "" && fkColumns.tableID != tableID {
+			continue
+		}
+
+		statements = append(statements, fmt.Sprintf("CREATE INDEX %s ON %s (%s);", sqlName(indexName), sqlName(fkColumns.table), sqlNames(fkColumns.columns)))
+	}
+
+	return statements
+}
+
+// autoIndexForeignKeys reports whether foreign key columns should be indexed,
+// preferring the schema's own setting over the configured default
+func autoIndexForeignKeys(override *bool, cfg *config.Config) bool {
+	if override != nil {
+		return *override
+	}
+	return cfg != nil && cfg.AutoIndexForeignKeys
+}
+
+// unindexedForeignKeyColumns returns the distinct foreign key source column
+// lists that no primary key, unique constraint or index starts with
+func unindexedForeignKeyColumns(schemaData models.SchemaData) []foreignKeyColumns {
+	var result []foreignKeyColumns
+	seen := make(map[string]bool)
+	for _, fk := range schemaData.ForeignKeys {
+		var table models.Table
+		tableExists := false
+		for _, candidate := range schemaData.Tables {
+			if candidate.ID == fk.SourceTableId {
+				table, tableExists = candidate, true
+				break
+			}
+		}
+		if !tableExists {
+			continue
+		}
+		columns, columnsExist := columnsByID(table, fk.SourceColumns())
+		if !columnsExist || len(columns) == 0 {
+			continue
+		}
+
+		names := make([]string, len(columns))
+		for i, column := range columns {
+			names[i] = column.Name
+		}
+		key := table.ID + "\x00" + strings.Join(names, "\x00")
+		if seen[key] || isIndexedColumns(table, columns) {
+			continue
+		}
+		seen[key] = true
+		result = append(result, foreignKeyColumns{tableID: table.ID, table: table.Name, columns: names})
+	}
+
+	return result
+}
+
+// isIndexedColumns reports whether an index usable for lookups on columns
+// already exists on table, which is one whose leading columns they are
+func isIndexedColumns(table models.Table, columns []models.Column) bool {
+	if len(columns) == 1 && columns[0].Unique {
+		return true
+	}
+
+	// The primary key index is only usable when columns lead it
+	var primaryKey []string
+	for _, candidate := range orderedColumns(table.Columns) {
+		if candidate.PrimaryKey {
+			primaryKey = append(primaryKey, candidate.ID)
+		}
+	}
+	if hasColumnPrefix(primaryKey, columns) {
+		return true
+	}
+
+	// Partial indexes only cover some rows
+	for _, index := range table.Indexes {
+		if index.Where == nil && hasColumnPrefix(index.Columns, columns) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// hasColumnPrefix reports whether the index columns, given by ID or name,
+// start with columns in order
+func hasColumnPrefix(indexColumns []string, columns []models.Column) bool {
+	if len(indexColumns) < len(columns) {
+		return false
+	}
+	for i, column := range columns {
+		if indexColumns[i] != column.ID && indexColumns[i] != column.Name {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,300 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"vdt-dashboard-backend/metrics"
+	"vdt-dashboard-backend/models"
+	"vdt-dashboard-backend/tracing"
+
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// generationJobs runs database generation in the background on a bounded
+// number of workers. Jobs for the same database run one at a time, in the
+// order they were submitted. Job state is kept in memory, so only the latest
+// job of each schema since the process started is known.
+type generationJobs struct {
+	workers   chan struct{}
+	mu        sync.Mutex
+	jobs      map[uuid.UUID]*models.GenerationJob
+	databases map[string]*databaseQueue
+	running   sync.WaitGroup
+	events    *schemaEvents
+}
+
+// databaseQueue serializes the jobs of one database
+type databaseQueue struct {
+	mu      sync.Mutex
+	pending int
+}
+
+// newGenerationJobs creates a job runner with the given number of workers,
+// publishing each change of job state to events
+func newGenerationJobs(workers int, events *schemaEvents) *generationJobs {
+	return &generationJobs{
+		workers:   make(chan struct{}, max(workers, 1)),
+		jobs:      make(map[uuid.UUID]*models.GenerationJob),
+		databases: make(map[string]*databaseQueue),
+		events:    events,
+	}
+}
+
+// submit queues run as the latest job of a schema and returns its state
+// when queued. A failed run records its error on the job.
+func (g *generationJobs) submit(schemaID uuid.UUID, databaseName, operation string, run func() error) models.GenerationJob {
+	queuedAt := time.Now().UTC()
+	job := &models.GenerationJob{
+		SchemaID:     schemaID,
+		DatabaseName: databaseName,
+		Operation:    operation,
+		State:        models.GenerationJobQueued,
+		QueuedAt:     &queuedAt,
+	}
+
+	g.mu.Lock()
+	g.jobs[schemaID] = job
+	queue := g.databases[databaseName]
+	if queue == nil {
+		queue = &databaseQueue{}
+		g.databases[databaseName] = queue
+	}
+	queue.pending++
+	queued := *job
+	g.mu.Unlock()
+	g.publish(queued)
+
+	g.running.Add(1)
+	go func() {
+		defer g.running.Done()
+
+		// Wait for earlier jobs on the database before taking a worker, so
+		// waiting jobs don't hold up other databases
+		queue.mu.Lock()
+		defer g.release(databaseName, queue)
+
+		g.workers <- struct{}{}
+		defer func() { <-g.workers }()
+
+		g.update(job, func() {
+			startedAt := time.Now().UTC()
+			job.State = models.GenerationJobRunning
+			job.StartedAt = &startedAt
+		})
+
+		started := time.Now()
+		err := run()
+		metrics.ObserveGeneration(operation, err, time.Since(started))
+		if err != nil {
+			log.Printf("Database %s job for schema %s failed: %v", operation, schemaID, err)
+		}
+
+		g.update(job, func() {
+			finishedAt := time.Now().UTC()
+			job.FinishedAt = &finishedAt
+			job.State = models.GenerationJobSucceeded
+			if err != nil {
+				job.State = models.GenerationJobFailed
+				job.Error = err.Error()
+				var statementErr *StatementError
+				if errors.As(err, &statementErr) {
+					job.Failure = statementErr.Failure
+				}
+			}
+		})
+	}()
+
+	return queued
+}
+
+// wait blocks until every submitted job has finished, or ctx is done
+func (g *generationJobs) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		g.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("database jobs still running: %w", ctx.Err())
+	}
+}
+
+// get returns a copy of the latest job of a schema
+func (g *generationJobs) get(schemaID uuid.UUID) (models.GenerationJob, bool) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	job, exists := g.jobs[schemaID]
+	if !exists {
+		return models.GenerationJob{}, false
+	}
+	return *job, true
+}
+
+// locked runs change while holding the lock guarding job state
+func (g *generationJobs) locked(change func()) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	change()
+}
+
+// update changes the state of a job and publishes the changed state
+func (g *generationJobs) update(job *models.GenerationJob, change func()) {
+	var changed models.GenerationJob
+	g.locked(func() {
+		change()
+		changed = *job
+	})
+	g.publish(changed)
+}
+
+// publish tells the subscribers of a schema the state of its job changed
+func (g *generationJobs) publish(job models.GenerationJob) {
+	g.events.publish(models.SchemaEvent{
+		Type:     models.SchemaEventJob,
+		SchemaID: job.SchemaID,
+		Job:      &job,
+		Time:     time.Now().UTC(),
+	})
+}
+
+// release lets the next job on a database run and forgets the database once
+// nothing is waiting on it
+func (g *generationJobs) release(databaseName string, queue *databaseQueue) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	queue.pending--
+	if queue.pending == 0 {
+		delete(g.databases, databaseName)
+	}
+	queue.mu.Unlock()
+}
+
+// Jobs wait for a generation lock held by a request instead of failing,
+// retrying every generationLockRetryInterval for up to generationLockTimeout
+var (
+	generationLockRetryInterval = 500 * time.Millisecond
+	generationLockTimeout       = 2 * time.Minute
+)
+
+// submitGeneration queues the (re)generation of a schema's database. The job
+// waits for the generation lock and builds the database from the definition
+// stored when it runs, then sets the schema status to success, or to error
+// when it fails. The job keeps the trace of ctx but outlives its
+// cancellation, since the request returns before the job runs.
+func (s *schemaService) submitGeneration(ctx context.Context, schema *models.Schema, operation string, success models.SchemaStatus) models.GenerationJob {
+	ctx = context.WithoutCancel(ctx)
+	return s.jobs.submit(schema.ID, schema.DatabaseName, operation, func() error {
+		return s.generateDatabase(ctx, schema.ID, operation, success)
+	})
+}
+
+// generateDatabase builds the database of a schema from its stored definition
+func (s *schemaService) generateDatabase(ctx context.Context, schemaID uuid.UUID, operation string, success models.SchemaStatus) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "schema.generate", trace.WithAttributes(
+		attribute.String("schema.id", schemaID.String()),
+		attribute.String("schema.operation", operation),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	unlock, err := s.waitForGenerationLock(ctx, schemaID)
+	if err != nil {
+		s.markGenerationFailed(schemaID)
+		return err
+	}
+	defer unlock()
+
+	schema, err := s.repo.GetByID(schemaID)
+	if err != nil {
+		return schemaLookupError(schemaID, err)
+	}
+
+	if err := s.databaseManager.RegenerateDatabase(ctx, schema.SchemaDefinition, schema.DatabaseName); err != nil {
+		schema.Status = models.SchemaStatusError
+		if err := s.repo.Update(schema); err == nil {
+			s.publishStatus(schema)
+		}
+		return fmt.Errorf("%w: %w", ErrDatabaseProvision, err)
+	}
+
+	regeneratedAt := time.Now().UTC()
+	schema.Status = success
+	schema.LastRegeneratedAt = &regeneratedAt
+	if err := s.repo.Update(schema); err != nil {
+		return fmt.Errorf("failed to update schema status: %w", err)
+	}
+	s.publishStatus(schema)
+	return nil
+}
+
+// waitForGenerationLock takes the generation lock of a schema, retrying while
+// another request or instance holds it. It returns ErrGenerationInProgress
+// once generationLockTimeout has passed without getting the lock.
+func (s *schemaService) waitForGenerationLock(ctx context.Context, schemaID uuid.UUID) (func(), error) {
+	deadline := time.Now().Add(generationLockTimeout)
+	for {
+		unlock, err := s.databaseManager.LockGeneration(schemaID)
+		if !errors.Is(err, ErrGenerationInProgress) || time.Now().After(deadline) {
+			return unlock, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(generationLockRetryInterval):
+		}
+	}
+}
+
+// markGenerationFailed sets the status of a schema to error when its job
+// couldn't start
+func (s *schemaService) markGenerationFailed(schemaID uuid.UUID) {
+	schema, err := s.repo.GetByID(schemaID)
+	if err != nil {
+		return
+	}
+	schema.Status = models.SchemaStatusError
+	if err := s.repo.Update(schema); err != nil {
+		log.Printf("Warning: failed to update schema status: %v", err)
+		return
+	}
+	s.publishStatus(schema)
+}
+
+// WaitForJobs blocks until the queued and running database generation jobs
+// have finished, or ctx is done. It is called on shutdown so databases aren't
+// left half-built.
+func (s *schemaService) WaitForJobs(ctx context.Context) error {
+	return s.jobs.wait(ctx)
+}
+
+// GetGenerationJob returns the latest database generation job of a schema,
+// or a job in the none state when none ran since the service started
+func (s *schemaService) GetGenerationJob(id, userID uuid.UUID) (*models.GenerationJob, error) {
+	schema, err := s.repo.GetByIDAndUserID(id, userID)
+	if err != nil {
+		return nil, schemaLookupError(id, err)
+	}
+
+	job, exists := s.jobs.get(schema.ID)
+	if !exists {
+		job = models.GenerationJob{
+			SchemaID:     schema.ID,
+			DatabaseName: schema.DatabaseName,
+			State:        models.GenerationJobNone,
+		}
+	}
+	return &job, nil
+}
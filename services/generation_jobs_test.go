@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// useGenerationLockTiming shortens the generation lock waits for a test
+func useGenerationLockTiming(t *testing.T, interval, timeout time.Duration) {
+	t.Helper()
+
+	previousInterval, previousTimeout := generationLockRetryInterval, generationLockTimeout
+	generationLockRetryInterval, generationLockTimeout = interval, timeout
+	t.Cleanup(func() {
+		generationLockRetryInterval, generationLockTimeout = previousInterval, previousTimeout
+	})
+}
+
+func TestGenerateDatabaseWaitsForLock(t *testing.T) {
+	useGenerationLockTiming(t, time.Millisecond, time.Minute)
+
+	schema := models.Schema{ID: uuid.New(), Name: "shop", DatabaseName: "schema_shop", UserID: uuid.New(), Status: models.SchemaStatusRegenerating}
+	repo := newFakeSchemaRepository(schema)
+	manager := newFakeDatabaseManager()
+	manager.lockHeld = 3
+	service := newTestSchemaService(t, repo, manager)
+
+	if err := service.generateDatabase(context.Background(), schema.ID, models.GenerationOperationRegenerate, models.SchemaStatusRegenerated); err != nil {
+		t.Fatalf("generateDatabase() = %v, want nil", err)
+	}
+	if len(manager.regenerated) != 1 {
+		t.Fatalf("regenerated = %v, want one generation of %s", manager.regenerated, schema.DatabaseName)
+	}
+	if stored, _ := repo.GetByID(schema.ID); stored.Status != models.SchemaStatusRegenerated {
+		t.Fatalf("status = %s, want %s", stored.Status, models.SchemaStatusRegenerated)
+	}
+}
+
+func TestGenerateDatabaseLockTimeout(t *testing.T) {
+	useGenerationLockTiming(t, time.Millisecond, 10*time.Millisecond)
+
+	schema := models.Schema{ID: uuid.New(), Name: "shop", DatabaseName: "schema_shop", UserID: uuid.New(), Status: models.SchemaStatusRegenerating}
+	repo := newFakeSchemaRepository(schema)
+	manager := newFakeDatabaseManager()
+	manager.lockHeld = 1 << 30
+	service := newTestSchemaService(t, repo, manager)
+
+	err := service.generateDatabase(context.Background(), schema.ID, models.GenerationOperationRegenerate, models.SchemaStatusRegenerated)
+	if !errors.Is(err, ErrGenerationInProgress) {
+		t.Fatalf("generateDatabase() = %v, want ErrGenerationInProgress", err)
+	}
+	if len(manager.regenerated) != 0 {
+		t.Fatalf("regenerated = %v, want none", manager.regenerated)
+	}
+	if stored, _ := repo.GetByID(schema.ID); stored.Status != models.SchemaStatusError {
+		t.Fatalf("status = %s, want %s", stored.Status, models.SchemaStatusError)
+	}
+}
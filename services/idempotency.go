@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// CreateSchemaIdempotent creates a schema like CreateSchema, remembering the
+// result under the client's idempotency key. Repeating the same request with
+// the key within the configured TTL returns the schema created the first
+// time, reporting replayed, instead of creating another. Reusing the key for
+// a different request, or while the first one is still running, is rejected
+// with ErrIdempotencyConflict.
+func (s *schemaService) CreateSchemaIdempotent(ctx context.Context, request models.CreateSchemaRequest, userID uuid.UUID, key string) (*models.Schema, bool, error) {
+	requestHash, err := hashCreateSchemaRequest(request)
+	if err != nil {
+		return nil, false, err
+	}
+
+	for {
+		created, err := s.idempotencyKeys.CreateIfAbsent(&models.IdempotencyKey{
+			Key:         key,
+			UserID:      userID,
+			RequestHash: requestHash,
+		})
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to store idempotency key: %w", err)
+		}
+		if created {
+			break
+		}
+
+		existing, err := s.idempotencyKeys.Get(key, userID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Deleted since the insert; try storing it again
+			continue
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to get idempotency key: %w", err)
+		}
+
+		if time.Since(existing.CreatedAt) > s.config.IdempotencyKeyTTL {
+			if err := s.idempotencyKeys.Delete(key, userID); err != nil {
+				return nil, false, fmt.Errorf("failed to delete expired idempotency key: %w", err)
+			}
+			continue
+		}
+		if existing.RequestHash != requestHash {
+			return nil, false, fmt.Errorf("%w: key '%s' was used for a different request", ErrIdempotencyConflict, key)
+		}
+		if existing.SchemaID == nil {
+			return nil, false, fmt.Errorf("%w: a request with key '%s' is still in progress", ErrIdempotencyConflict, key)
+		}
+
+		schema, err := s.repo.GetByIDAndUserID(*existing.SchemaID, userID)
+		if err != nil {
+			return nil, false, schemaLookupError(*existing.SchemaID, err)
+		}
+		return schema, true, nil
+	}
+
+	schema, err := s.CreateSchema(ctx, request, userID)
+	if err != nil {
+		// Let the client retry a failed request with the same key
+		if deleteErr := s.idempotencyKeys.Delete(key, userID); deleteErr != nil {
+			log.Printf("Warning: failed to delete idempotency key: %v", deleteErr)
+		}
+		return nil, false, err
+	}
+
+	if err := s.idempotencyKeys.SetSchemaID(key, userID, schema.ID); err != nil {
+		log.Printf("Warning: failed to record schema for idempotency key: %v", err)
+	}
+
+	return schema, false, nil
+}
+
+// hashCreateSchemaRequest returns a fingerprint of a create request, used to
+// tell a retry from a different request reusing its idempotency key
+func hashCreateSchemaRequest(request models.CreateSchemaRequest) (string, error) {
+	data, err := json.Marshal(request)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash request: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,143 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateSchemaIdempotent(t *testing.T) {
+	request := models.CreateSchemaRequest{Name: "shop", Tables: testTables()}
+	changed := models.CreateSchemaRequest{Name: "shop", Description: "changed", Tables: testTables()}
+
+	tests := []struct {
+		name string
+		// stored is the key left by an earlier request, if any
+		stored       func(userID uuid.UUID, existing uuid.UUID) *models.IdempotencyKey
+		request      models.CreateSchemaRequest
+		wantErr      error
+		wantReplayed bool
+	}{
+		{
+			name: "first request",
+			stored: func(uuid.UUID, uuid.UUID) *models.IdempotencyKey {
+				return nil
+			},
+			request: request,
+		},
+		{
+			name: "replay",
+			stored: func(userID, existing uuid.UUID) *models.IdempotencyKey {
+				return &models.IdempotencyKey{Key: "retry-1", UserID: userID, RequestHash: mustHash(t, request), SchemaID: &existing, CreatedAt: time.Now()}
+			},
+			request:      request,
+			wantReplayed: true,
+		},
+		{
+			name: "different request",
+			stored: func(userID, existing uuid.UUID) *models.IdempotencyKey {
+				return &models.IdempotencyKey{Key: "retry-1", UserID: userID, RequestHash: mustHash(t, request), SchemaID: &existing, CreatedAt: time.Now()}
+			},
+			request: changed,
+			wantErr: ErrIdempotencyConflict,
+		},
+		{
+			name: "first request still running",
+			stored: func(userID, existing uuid.UUID) *models.IdempotencyKey {
+				return &models.IdempotencyKey{Key: "retry-1", UserID: userID, RequestHash: mustHash(t, request), CreatedAt: time.Now()}
+			},
+			request: request,
+			wantErr: ErrIdempotencyConflict,
+		},
+		{
+			name: "expired key",
+			stored: func(userID, existing uuid.UUID) *models.IdempotencyKey {
+				return &models.IdempotencyKey{Key: "retry-1", UserID: userID, RequestHash: mustHash(t, changed), SchemaID: &existing, CreatedAt: time.Now().Add(-2 * time.Hour)}
+			},
+			request: request,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			userID := uuid.New()
+			existing := models.Schema{ID: uuid.New(), Name: "earlier", DatabaseName: "schema_earlier", UserID: userID, Status: models.SchemaStatusCreated}
+			repo := newFakeSchemaRepository(existing)
+			keys := newFakeIdempotencyKeyRepository()
+			if stored := test.stored(userID, existing.ID); stored != nil {
+				keys.CreateIfAbsent(stored)
+			}
+			service := newTestSchemaService(t, repo, newFakeDatabaseManager())
+			service.idempotencyKeys = keys
+			service.config.IdempotencyKeyTTL = time.Hour
+
+			schema, replayed, err := service.CreateSchemaIdempotent(context.Background(), test.request, userID, "retry-1")
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("CreateSchemaIdempotent() = %v, want %v", err, test.wantErr)
+				}
+				if repo.count() != 1 {
+					t.Fatalf("%d schemas stored, want only the earlier one", repo.count())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if replayed != test.wantReplayed {
+				t.Fatalf("replayed = %v, want %v", replayed, test.wantReplayed)
+			}
+
+			if test.wantReplayed {
+				if schema.ID != existing.ID || repo.count() != 1 {
+					t.Fatalf("got schema %s with %d stored, want the earlier schema %s only", schema.ID, repo.count(), existing.ID)
+				}
+				return
+			}
+			if schema.ID == existing.ID || repo.count() != 2 {
+				t.Fatalf("got schema %s with %d stored, want a new schema", schema.ID, repo.count())
+			}
+			stored, err := keys.Get("retry-1", userID)
+			if err != nil || stored.SchemaID == nil || *stored.SchemaID != schema.ID {
+				t.Fatalf("stored key = %+v, %v, want it to point at %s", stored, err, schema.ID)
+			}
+		})
+	}
+}
+
+func TestCreateSchemaIdempotentReplaysAfterCreate(t *testing.T) {
+	userID := uuid.New()
+	repo := newFakeSchemaRepository()
+	service := newTestSchemaService(t, repo, newFakeDatabaseManager())
+	service.idempotencyKeys = newFakeIdempotencyKeyRepository()
+	service.config.IdempotencyKeyTTL = time.Hour
+	request := models.CreateSchemaRequest{Name: "shop", Tables: testTables()}
+
+	first, replayed, err := service.CreateSchemaIdempotent(context.Background(), request, userID, "retry-1")
+	if err != nil || replayed {
+		t.Fatalf("first CreateSchemaIdempotent() = replayed %v, %v", replayed, err)
+	}
+	second, replayed, err := service.CreateSchemaIdempotent(context.Background(), request, userID, "retry-1")
+	if err != nil || !replayed {
+		t.Fatalf("second CreateSchemaIdempotent() = replayed %v, %v, want a replay", replayed, err)
+	}
+	if second.ID != first.ID || repo.count() != 1 {
+		t.Fatalf("second request returned %s with %d stored, want %s only", second.ID, repo.count(), first.ID)
+	}
+}
+
+// mustHash returns the fingerprint of request
+func mustHash(t *testing.T, request models.CreateSchemaRequest) string {
+	t.Helper()
+
+	hash, err := hashCreateSchemaRequest(request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
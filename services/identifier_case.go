@@ -0,0 +1,151 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"vdt-dashboard-backend/config"
+	"vdt-dashboard-backend/models"
+)
+
+// identifierCase returns the identifier case policy for a schema, preferring
+// the schema's own setting over the configured default
+func identifierCase(override string, cfg *config.Config) string {
+	if override != "" {
+		return override
+	}
+	if cfg != nil && cfg.IdentifierCase != "" {
+		return cfg.IdentifierCase
+	}
+	return models.IdentifierCasePreserve
+}
+
+// convertIdentifier applies an identifier case policy to name
+func convertIdentifier(name, policy string) string {
+	switch policy {
+	case models.IdentifierCaseSnake:
+		return snakeCase(name)
+	case models.IdentifierCaseLower:
+		return strings.ToLower(name)
+	default:
+		return name
+	}
+}
+
+// snakeCase converts camelCase, PascalCase and space or dash separated names
+// to snake_case. Runs of capitals are kept together, so "userID" becomes
+// "user_id" and "HTTPServer" becomes "http_server".
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var out strings.Builder
+	for i, r := range runes {
+		if r == ' ' || r == '-' {
+			r = '_'
+		}
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextIsLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextIsLower) {
+				out.WriteRune('_')
+			}
+		}
+		out.WriteRune(unicode.ToLower(r))
+	}
+	return out.String()
+}
+
+// applyIdentifierCase returns a copy of schemaData with the identifier case
+// policy applied to table, column, index and foreign key names. Table and
+// column IDs are left untouched so references keep resolving.
+func applyIdentifierCase(schemaData models.SchemaData, policy string) models.SchemaData {
+	if policy == models.IdentifierCasePreserve || policy == "" {
+		return schemaData
+	}
+
+	converted := schemaData
+	converted.Tables = make([]models.Table, len(schemaData.Tables))
+	for i, table := range schemaData.Tables {
+		converted.Tables[i] = convertTableIdentifiers(table, policy)
+	}
+
+	converted.ForeignKeys = make([]models.ForeignKey, len(schemaData.ForeignKeys))
+	for i, fk := range schemaData.ForeignKeys {
+		fk.Name = convertIdentifier(fk.Name, policy)
+		converted.ForeignKeys[i] = fk
+	}
+
+	return converted
+}
+
+// convertTableIdentifiers returns a copy of table with the identifier case
+// policy applied to its name, columns and indexes
+func convertTableIdentifiers(table models.Table, policy string) models.Table {
+	if policy == models.IdentifierCasePreserve || policy == "" {
+		return table
+	}
+
+	columnNames := make(map[string]bool)
+	for _, column := range table.Columns {
+		columnNames[column.Name] = true
+	}
+
+	converted := table
+	converted.Name = convertIdentifier(table.Name, policy)
+
+	converted.Columns = make([]models.Column, len(table.Columns))
+	for i, column := range table.Columns {
+		column.Name = convertIdentifier(column.Name, policy)
+		converted.Columns[i] = column
+	}
+
+	converted.Indexes = make([]models.Index, len(table.Indexes))
+	for i, index := range table.Indexes {
+		columns := make([]string, len(index.Columns))
+		for j, column := range index.Columns {
+			// Index columns may hold column IDs, which must stay as they are
+			if columnNames[column] {
+				column = convertIdentifier(column, policy)
+			}
+			columns[j] = column
+		}
+		index.Name = convertIdentifier(index.Name, policy)
+		index.Columns = columns
+		converted.Indexes[i] = index
+	}
+
+	return converted
+}
+
+// identifierCaseWarnings lists the names the identifier case policy will
+// change, so the saved definition and the generated database don't silently
+// disagree
+func identifierCaseWarnings(request models.SchemaValidationRequest, policy string) []string {
+	if policy == models.IdentifierCasePreserve {
+		return nil
+	}
+
+	var warnings []string
+	warn := func(kind, label, name string) {
+		if converted := convertIdentifier(name, policy); converted != name {
+			warnings = append(warnings, fmt.Sprintf("%s '%s' will be created as '%s' (identifierCase %s)", kind, label, converted, policy))
+		}
+	}
+
+	for _, table := range request.Tables {
+		warn("Table", table.Name, table.Name)
+		for _, column := range table.Columns {
+			warn("Column", table.Name+"."+column.Name, column.Name)
+		}
+		for _, index := range table.Indexes {
+			warn("Index", index.Name, index.Name)
+		}
+	}
+	for _, fk := range request.ForeignKeys {
+		if fk.Name != "" {
+			warn("Foreign key", fk.Name, fk.Name)
+		}
+	}
+
+	return warnings
+}
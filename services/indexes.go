@@ -0,0 +1,257 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"vdt-dashboard-backend/models"
+)
+
+// resolvedIndex is a user-defined index with its final name and column names
+type resolvedIndex struct {
+	tableID string
+	table   string
+	name    string
+	columns []string
+	unique  bool
+	where   *string
+	method  string
+}
+
+// GenerateIndexes creates the user-defined indexes of every table. Indexes
+// without a name are named idx_<table>_<columns>.
+func (g *sqlGeneratorService) GenerateIndexes(schemaData models.SchemaData) ([]string, error) {
+	return g.generateIndexes(schemaData, ""), nil
+}
+
+// GenerateTableIndexes creates the user-defined indexes of the table with
+// tableID, named consistently with GenerateIndexes
+func (g *sqlGeneratorService) GenerateTableIndexes(tableID string, schemaData models.SchemaData) ([]string, error) {
+	return g.generateIndexes(schemaData, tableID), nil
+}
+
+// generateIndexes creates the user-defined indexes of schemaData, limited to
+// the table with tableID unless it is empty
+func (g *sqlGeneratorService) generateIndexes(schemaData models.SchemaData, tableID string) []string {
+	schemaData = applyIdentifierCase(schemaData, identifierCase(schemaData.IdentifierCase, g.config))
+	indexes, _ := resolveIndexes(schemaData)
+
+	var statements []string
+	for _, index := range indexes {
+		if tableID != "" && index.tableID != tableID {
+			continue
+		}
+
+		createIndex := "CREATE INDEX"
+		if index.unique {
+			createIndex = "CREATE UNIQUE INDEX"
+		}
+		statement := fmt.Sprintf("%s %s ON %s", createIndex, sqlName(index.name), sqlName(index.table))
+		if index.method != "" {
+			statement += " USING " + index.method
+		}
+		statement += fmt.Sprintf(" (%s)", sqlNames(index.columns))
+		if index.where != nil {
+			statement += " WHERE " + *index.where
+		}
+		statements = append(statements, statement+";")
+	}
+
+	return statements
+}
+
+// resolveIndexes names the user-defined indexes of schemaData and maps their
+// columns to column names. It also returns every relation name in use, since
+// tables and indexes share a namespace in PostgreSQL.
+func resolveIndexes(schemaData models.SchemaData) ([]resolvedIndex, map[string]bool) {
+	usedNames := make(map[string]bool)
+	for _, table := range schemaData.Tables {
+		usedNames[table.Name] = true
+		for _, index := range table.Indexes {
+			if index.Name != "" {
+				usedNames[index.Name] = true
+			}
+		}
+	}
+
+	var indexes []resolvedIndex
+	for _, table := range schemaData.Tables {
+		columnNames := make(map[string]string)
+		for _, column := range table.Columns {
+			columnNames[column.ID] = column.Name
+		}
+
+		for _, index := range table.Indexes {
+			if len(index.Columns) == 0 {
+				continue
+			}
+
+			// Index columns may reference columns by ID or by name
+			columns := make([]string, len(index.Columns))
+			for i, column := range index.Columns {
+				if name, ok := columnNames[column]; ok {
+					column = name
+				}
+				columns[i] = column
+			}
+
+			name := index.Name
+			if name == "" {
+				name = uniqueIdentifier(fmt.Sprintf("idx_%s_%s", table.Name, strings.Join(columns, "_")), usedNames)
+				usedNames[name] = true
+			}
+
+			indexes = append(indexes, resolvedIndex{
+				tableID: table.ID,
+				table:   table.Name,
+				name:    name,
+				columns: columns,
+				unique:  index.Unique,
+				where:   index.Where,
+				method:  strings.ToLower(index.Method),
+			})
+		}
+	}
+
+	return indexes, usedNames
+}
+
+// validateIndex checks a user-defined index. Partial index predicates are
+// passed through to PostgreSQL as written, so they are only checked for being
+// present and for not ending the statement they are pasted into.
+func validateIndex(tableIndex, indexIndex int, table models.Table, index models.Index) ([]models.ValidationError, []string) {
+	errors := validateIndexColumns(tableIndex, indexIndex, table, index)
+	var warnings []string
+
+	if index.Name != "" && strings.TrimSpace(index.Name) == "" {
+		errors = append(errors, models.ValidationError{
+			Field:   fmt.Sprintf("tables[%d].indexes[%d].name", tableIndex, indexIndex),
+			Message: "Index name cannot be blank, omit it to generate one",
+			Code:    "INVALID_INDEX",
+		})
+	}
+
+	if index.Where != nil {
+		if strings.TrimSpace(*index.Where) == "" {
+			errors = append(errors, models.ValidationError{
+				Field:   fmt.Sprintf("tables[%d].indexes[%d].where", tableIndex, indexIndex),
+				Message: "Partial index predicate cannot be empty",
+				Code:    "EMPTY_INDEX_PREDICATE",
+			})
+		} else if problem := verbatimSQLProblem(*index.Where); problem != "" {
+			errors = append(errors, models.ValidationError{
+				Field:   fmt.Sprintf("tables[%d].indexes[%d].where", tableIndex, indexIndex),
+				Message: "Partial index predicate " + problem,
+				Code:    "INVALID_INDEX_PREDICATE",
+			})
+		} else {
+			warnings = append(warnings, fmt.Sprintf("Predicate of partial index '%s' on table '%s' is not validated, check it against the table's columns", indexLabel(index), table.Name))
+		}
+	}
+
+	methodErrors, methodWarnings := validateIndexMethod(tableIndex, indexIndex, table, index)
+	return append(errors, methodErrors...), append(warnings, methodWarnings...)
+}
+
+// validateIndexColumns checks that an index covers at least one column and
+// that each of its columns exists in the table, by ID or by name, once
+func validateIndexColumns(tableIndex, indexIndex int, table models.Table, index models.Index) []models.ValidationError {
+	field := fmt.Sprintf("tables[%d].indexes[%d].columns", tableIndex, indexIndex)
+	if len(index.Columns) == 0 {
+		return []models.ValidationError{{
+			Field:   field,
+			Message: fmt.Sprintf("Indexes on table '%s' must cover at least one column", table.Name),
+			Code:    "INVALID_INDEX",
+		}}
+	}
+
+	columnIDs := make(map[string]string)
+	for _, column := range table.Columns {
+		columnIDs[column.ID] = column.ID
+		columnIDs[column.Name] = column.ID
+	}
+
+	var errors []models.ValidationError
+	seen := make(map[string]bool)
+	for k, name := range index.Columns {
+		columnID, exists := columnIDs[name]
+		switch {
+		case !exists:
+			errors = append(errors, models.ValidationError{
+				Field:   fmt.Sprintf("%s[%d]", field, k),
+				Message: fmt.Sprintf("Index '%s' references unknown column '%s' of table '%s'", indexLabel(index), name, table.Name),
+				Code:    "INVALID_INDEX",
+			})
+		case seen[columnID]:
+			errors = append(errors, models.ValidationError{
+				Field:   fmt.Sprintf("%s[%d]", field, k),
+				Message: fmt.Sprintf("Index '%s' lists column '%s' more than once", indexLabel(index), name),
+				Code:    "INVALID_INDEX",
+			})
+		}
+		seen[columnID] = true
+	}
+	return errors
+}
+
+// validateIndexMethod checks the index access method against the allow-list
+// and, on a best-effort basis, against the types of the indexed columns
+func validateIndexMethod(tableIndex, indexIndex int, table models.Table, index models.Index) ([]models.ValidationError, []string) {
+	field := fmt.Sprintf("tables[%d].indexes[%d].method", tableIndex, indexIndex)
+	method := strings.ToLower(index.Method)
+	if method == "" {
+		method = models.DefaultIndexMethod
+	}
+
+	if !models.ValidIndexMethods[method] {
+		return []models.ValidationError{{
+			Field:   field,
+			Message: fmt.Sprintf("Unsupported index method: %s", index.Method),
+			Code:    "INVALID_INDEX_METHOD",
+		}}, nil
+	}
+
+	// PostgreSQL hash indexes are single-column and cannot enforce uniqueness
+	if method == "hash" && (index.Unique || len(index.Columns) > 1) {
+		return []models.ValidationError{{
+			Field:   field,
+			Message: fmt.Sprintf("Hash index '%s' must be non-unique and cover a single column", indexLabel(index)),
+			Code:    "INVALID_INDEX_METHOD",
+		}}, nil
+	}
+
+	columns := make(map[string]models.Column)
+	for _, column := range table.Columns {
+		columns[column.ID] = column
+		columns[column.Name] = column
+	}
+
+	var warnings []string
+	for _, name := range index.Columns {
+		column, exists := columns[name]
+		if !exists {
+			continue
+		}
+
+		switch {
+		case method == "gin" && column.DataType != "JSON" && !column.IsArray:
+			warnings = append(warnings, fmt.Sprintf("Index '%s' uses gin, which doesn't support %s column '%s' without an extension", indexLabel(index), column.DataType, column.Name))
+		case method == "gist":
+			warnings = append(warnings, fmt.Sprintf("Index '%s' uses gist, which doesn't support %s column '%s' without an extension", indexLabel(index), describeColumnType(column), column.Name))
+		case method != "gin" && column.IsArray:
+			warnings = append(warnings, fmt.Sprintf("Index '%s' covers array column '%s', consider the gin method", indexLabel(index), column.Name))
+		case method != "gin" && column.DataType == "JSON":
+			warnings = append(warnings, fmt.Sprintf("Index '%s' covers JSON column '%s', consider the gin method", indexLabel(index), column.Name))
+		}
+	}
+
+	return nil, warnings
+}
+
+// indexLabel names an index in messages, falling back to its columns
+func indexLabel(index models.Index) string {
+	if index.Name != "" {
+		return index.Name
+	}
+	return strings.Join(index.Columns, ", ")
+}
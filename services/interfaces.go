@@ -1,16 +1,27 @@
 package services
 
 import (
+	"context"
+	"crypto/sha1"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"vdt-dashboard-backend/config"
 	"vdt-dashboard-backend/models"
 	"vdt-dashboard-backend/repositories"
+	"vdt-dashboard-backend/tracing"
 
 	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -18,12 +29,38 @@ import (
 
 // SchemaService defines the interface for schema business logic
 type SchemaService interface {
-	CreateSchema(request models.CreateSchemaRequest, userID uuid.UUID) (*models.Schema, error)
+	CreateSchema(ctx context.Context, request models.CreateSchemaRequest, userID uuid.UUID) (*models.Schema, error)
+	CreateSchemaIdempotent(ctx context.Context, request models.CreateSchemaRequest, userID uuid.UUID, key string) (*models.Schema, bool, error)
 	GetSchema(id, userID uuid.UUID) (*models.Schema, error)
-	UpdateSchema(id, userID uuid.UUID, request models.UpdateSchemaRequest) (*models.Schema, error)
-	DeleteSchema(id, userID uuid.UUID) error
+	GetEditableSchema(id, userID uuid.UUID) (*models.Schema, error)
+	UpdateSchema(ctx context.Context, id, userID uuid.UUID, request models.UpdateSchemaRequest) (*models.Schema, error)
+	DiffSchema(id, userID uuid.UUID, request models.UpdateSchemaRequest) (*models.SchemaDiffResponse, error)
+	DeleteSchema(id, userID uuid.UUID, options models.DeleteSchemaOptions) (databaseDropped bool, err error)
+	BulkDeleteSchemas(ids []string, userID uuid.UUID, options models.DeleteSchemaOptions) *models.BulkDeleteResponse
+	CloneSchema(ctx context.Context, id, userID uuid.UUID, newName string) (*models.Schema, error)
+	RestoreSchema(ctx context.Context, id, userID uuid.UUID) (*models.Schema, error)
 	ListSchemas(pagination models.PaginationRequest, userID uuid.UUID) ([]models.SchemaListResponse, *models.PaginationResponse, error)
-	ExportSQL(id, userID uuid.UUID) (*models.SQLExportResponse, error)
+	ExportSQL(id, userID uuid.UUID, options models.SQLExportOptions) (*models.SQLExportResponse, error)
+	PreviewSQL(id, userID uuid.UUID, request models.UpdateSchemaRequest) (*models.SQLPreviewResponse, error)
+	SetFavorite(id, userID uuid.UUID, request models.FavoriteSchemaRequest) (*models.Schema, error)
+	ShareSchema(id, userID uuid.UUID, request models.ShareSchemaRequest) (*models.SchemaCollaborator, error)
+	UnshareSchema(id, userID, collaboratorID uuid.UUID) error
+	ListVersions(id, userID uuid.UUID, pagination models.PaginationRequest) ([]models.SchemaVersion, *models.PaginationResponse, error)
+	RollbackSchema(ctx context.Context, id, userID uuid.UUID, version int) (*models.Schema, error)
+	ExportTypeScript(id, userID uuid.UUID, exporter TypeScriptExporter) (*models.TypeScriptExportResponse, error)
+	ExportDiagram(id, userID uuid.UUID) (string, error)
+	RegenerateDatabase(ctx context.Context, id, userID uuid.UUID) (*models.Schema, error)
+	RebuildForeignKeys(id, userID uuid.UUID) (*models.Schema, error)
+	GetGenerationJob(id, userID uuid.UUID) (*models.GenerationJob, error)
+	WaitForJobs(ctx context.Context) error
+	GetTableDDL(id, userID uuid.UUID, tableID string) (*models.TableDDLResponse, error)
+	ImportTableData(id, userID uuid.UUID, tableRef string, data []byte) (*models.TableDataImportResponse, error)
+	ExecuteQuery(ctx context.Context, id, userID uuid.UUID, request models.QueryRequest) (*models.QueryResponse, error)
+	ListTables(id, userID uuid.UUID, pagination models.PaginationRequest) ([]models.Table, *models.PaginationResponse, error)
+	ListRelationships(id, userID uuid.UUID, pagination models.PaginationRequest) ([]models.ForeignKey, *models.PaginationResponse, error)
+	ListLiveTables(id, userID uuid.UUID) (*models.LiveTablesResponse, error)
+	SubscribeEvents(id, userID uuid.UUID) (*models.Schema, <-chan models.SchemaEvent, func(), error)
+	CloseEvents()
 }
 
 // ValidatorService defines the interface for schema validation
@@ -34,238 +71,1435 @@ type ValidatorService interface {
 // SQLGeneratorService defines the interface for SQL generation
 type SQLGeneratorService interface {
 	GenerateCreateDatabase(databaseName string) (string, error)
+	GenerateDropStatements(schemaData models.SchemaData) ([]string, error)
 	GenerateCreateTables(schemaData models.SchemaData) ([]string, error)
+	GenerateCreateTable(table models.Table, schemaData models.SchemaData) (string, error)
+	GenerateTableComments(table models.Table, schemaData models.SchemaData) ([]string, error)
+	GenerateIndexes(schemaData models.SchemaData) ([]string, error)
+	GenerateTableIndexes(tableID string, schemaData models.SchemaData) ([]string, error)
 	GenerateForeignKeys(schemaData models.SchemaData) ([]string, error)
+	GenerateTableForeignKeys(tableID string, schemaData models.SchemaData) ([]string, error)
+	GenerateForeignKeyIndexes(schemaData models.SchemaData) ([]string, error)
+	GenerateTableForeignKeyIndexes(tableID string, schemaData models.SchemaData) ([]string, error)
 }
 
 // DatabaseManagerService defines the interface for database management
 type DatabaseManagerService interface {
 	CreateDatabase(databaseName string) error
 	DropDatabase(databaseName string) error
+	RenameDatabase(oldName, newName string) error
+	DatabaseExists(databaseName string) (bool, error)
+	LockGeneration(schemaID uuid.UUID) (unlock func(), err error)
 	GetDatabaseStatus(databaseName string) (*models.DatabaseStatus, error)
-	RegenerateDatabase(schemaData models.SchemaData, databaseName string) error
+	RegenerateDatabase(ctx context.Context, schemaData models.SchemaData, databaseName string) error
+	MigrateDatabase(ctx context.Context, old, new models.SchemaData, databaseName string) error
+	DumpData(databaseName string, w io.Writer) error
+	InsertRows(databaseName, table string, columns []string, rows [][]any) ([]error, error)
+	RunQuery(ctx context.Context, databaseName, query string, options QueryOptions) (*models.QueryResponse, error)
+	RebuildForeignKeys(schemaData models.SchemaData, databaseName string) error
+	IntrospectDatabase(databaseName string) (models.SchemaData, error)
+	ListLiveTables(databaseName string) ([]models.LiveTable, error)
+	DetectDrift(schemaData models.SchemaData, databaseName string) (*models.DriftReport, error)
+	CheckConnections(ctx context.Context) []models.DatabaseConnectionStatus
+	PoolStats() models.DatabasePoolStats
+	Close() error
 }
 
 // NewSchemaService creates a new schema service
-func NewSchemaService(repo repositories.SchemaRepository, databaseManager DatabaseManagerService, cfg *config.Config) SchemaService {
+func NewSchemaService(repo repositories.SchemaRepository, idempotencyKeys repositories.IdempotencyKeyRepository, collaborators repositories.SchemaCollaboratorRepository, versions repositories.SchemaVersionRepository, users repositories.UserRepository, databaseManager DatabaseManagerService, validator ValidatorService, sqlGenerator SQLGeneratorService, cfg *config.Config) SchemaService {
+	events := newSchemaEvents()
 	return &schemaService{
 		repo:            repo,
+		idempotencyKeys: idempotencyKeys,
+		collaborators:   collaborators,
+		versions:        versions,
+		users:           users,
 		databaseManager: databaseManager,
+		validator:       validator,
+		sqlGenerator:    sqlGenerator,
+		jobs:            newGenerationJobs(cfg.GenerationWorkers, events),
+		events:          events,
 		config:          cfg,
 	}
 }
 
 // NewValidatorService creates a new validator service
-func NewValidatorService() ValidatorService {
-	return &validatorService{}
+func NewValidatorService(cfg *config.Config) ValidatorService {
+	return &validatorService{
+		config: cfg,
+	}
 }
 
 // NewSQLGeneratorService creates a new SQL generator service
-func NewSQLGeneratorService() SQLGeneratorService {
-	return &sqlGeneratorService{}
+func NewSQLGeneratorService(cfg *config.Config) SQLGeneratorService {
+	return &sqlGeneratorService{
+		config: cfg,
+	}
 }
 
 // NewDatabaseManagerService creates a new database manager service
 func NewDatabaseManagerService(cfg *config.Config) DatabaseManagerService {
 	return &databaseManagerService{
-		config: cfg,
+		config:      cfg,
+		connections: newDatabaseConnections(cfg.DynamicDBMaxCached, time.Duration(cfg.DynamicDBIdleTimeout)*time.Second),
 	}
 }
 
 // Service implementations
 type schemaService struct {
 	repo            repositories.SchemaRepository
+	idempotencyKeys repositories.IdempotencyKeyRepository
+	collaborators   repositories.SchemaCollaboratorRepository
+	versions        repositories.SchemaVersionRepository
+	users           repositories.UserRepository
 	databaseManager DatabaseManagerService
+	validator       ValidatorService
+	sqlGenerator    SQLGeneratorService
+	jobs            *generationJobs
+	events          *schemaEvents
 	config          *config.Config
 }
 
-type validatorService struct{}
+type validatorService struct {
+	config *config.Config
+}
 
-type sqlGeneratorService struct{}
+type sqlGeneratorService struct {
+	config *config.Config
+}
 
 type databaseManagerService struct {
-	config *config.Config
+	config      *config.Config
+	connections *databaseConnections
 }
 
 // SchemaService implementation
-func (s *schemaService) CreateSchema(request models.CreateSchemaRequest, userID uuid.UUID) (*models.Schema, error) {
+func (s *schemaService) CreateSchema(ctx context.Context, request models.CreateSchemaRequest, userID uuid.UUID) (*models.Schema, error) {
 	// Check if schema name already exists for this user
 	if _, err := s.repo.GetByNameAndUserID(request.Name, userID); err == nil {
-		return nil, fmt.Errorf("schema with name '%s' already exists", request.Name)
+		return nil, fmt.Errorf("%w: schema with name '%s'", ErrDuplicate, request.Name)
 	}
 
 	// Generate unique database name
-	databaseName := fmt.Sprintf("schema_%s", strings.ReplaceAll(uuid.New().String(), "-", "_"))
+	databaseName := schemaDatabasePrefix + strings.ReplaceAll(uuid.New().String(), "-", "_")
 
 	schema := &models.Schema{
 		ID:           uuid.New(),
 		Name:         request.Name,
 		Description:  request.Description,
 		DatabaseName: databaseName,
-		Status:       "creating",
-		Version:      "1.0",
+		Status:       models.SchemaStatusCreating,
+		Version:      1,
 		UserID:       userID,
 		SchemaDefinition: models.SchemaData{
-			Tables:      request.Tables,
-			ForeignKeys: request.ForeignKeys,
-			Version:     "1.0",
-			ExportedAt:  time.Now().Format(time.RFC3339),
+			Tables:               request.Tables,
+			ForeignKeys:          request.ForeignKeys,
+			AutoIndexForeignKeys: request.AutoIndexForeignKeys,
+			IdentifierCase:       request.IdentifierCase,
+			Version:              "1",
+			ExportedAt:           time.Now().UTC(),
 		},
 	}
 
+	if _, err := s.validateDefinition(schema); err != nil {
+		return nil, err
+	}
+
 	// Create schema metadata first
 	if err := s.repo.Create(schema); err != nil {
 		return nil, fmt.Errorf("failed to create schema: %w", err)
 	}
+	s.recordVersion(schema, userID)
+	s.publishStatus(schema)
 
-	// Generate the actual database
-	if err := s.databaseManager.RegenerateDatabase(schema.SchemaDefinition, schema.DatabaseName); err != nil {
-		// Update status to error
-		schema.Status = "error"
-		s.repo.Update(schema)
-		return nil, fmt.Errorf("failed to generate database: %w", err)
+	// Generate the actual database in the background; the schema stays in
+	// creating status until the job sets it to created or error
+	job := s.submitGeneration(ctx, schema, models.GenerationOperationCreate, models.SchemaStatusCreated)
+	schema.GenerationJob = &job
+
+	return schema, nil
+}
+
+// validateDefinition validates the schema definition and records the outcome
+// on the schema. Invalid definitions are rejected with a SchemaValidationError.
+func (s *schemaService) validateDefinition(schema *models.Schema) (*models.ValidationResult, error) {
+	result, err := s.validator.ValidateSchema(models.SchemaValidationRequest{
+		Name:                 schema.Name,
+		Tables:               schema.SchemaDefinition.Tables,
+		ForeignKeys:          schema.SchemaDefinition.ForeignKeys,
+		AutoIndexForeignKeys: schema.SchemaDefinition.AutoIndexForeignKeys,
+		IdentifierCase:       schema.SchemaDefinition.IdentifierCase,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to validate schema: %w", err)
 	}
 
-	// Update status to created
-	schema.Status = "created"
-	if err := s.repo.Update(schema); err != nil {
-		log.Printf("Warning: failed to update schema status: %v", err)
+	now := time.Now().UTC()
+	schema.LastValidatedAt = &now
+	schema.ValidationStatus = models.ValidationStatusInvalid
+	if result.Valid {
+		schema.ValidationStatus = models.ValidationStatusValid
 	}
+	schema.ValidationWarnings = result.Warnings
 
-	return schema, nil
+	// Never provision a database from a definition with hard errors
+	if !result.Valid {
+		return result, &SchemaValidationError{Result: result}
+	}
+
+	return result, nil
 }
 
 func (s *schemaService) GetSchema(id, userID uuid.UUID) (*models.Schema, error) {
-	return s.repo.GetByIDAndUserID(id, userID)
+	schema, err := s.repo.GetByIDAndUserID(id, userID)
+	if err != nil {
+		return nil, schemaLookupError(id, err)
+	}
+	return schema, nil
 }
 
-func (s *schemaService) UpdateSchema(id, userID uuid.UUID, request models.UpdateSchemaRequest) (*models.Schema, error) {
-	schema, err := s.repo.GetByIDAndUserID(id, userID)
+func (s *schemaService) UpdateSchema(ctx context.Context, id, userID uuid.UUID, request models.UpdateSchemaRequest) (*models.Schema, error) {
+	if _, err := s.GetEditableSchema(id, userID); err != nil {
+		return nil, err
+	}
+
+	unlock, err := s.databaseManager.LockGeneration(id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Read the schema again under the lock, so a change saved by the request
+	// that held it before isn't overwritten
+	schema, err := s.GetEditableSchema(id, userID)
 	if err != nil {
 		return nil, err
 	}
 
-	// Check if new name conflicts with existing schema for this user (excluding current schema)
+	// Check if new name conflicts with another schema of the owner
 	if schema.Name != request.Name {
-		if existing, err := s.repo.GetByNameAndUserID(request.Name, userID); err == nil && existing.ID != id {
-			return nil, fmt.Errorf("schema with name '%s' already exists", request.Name)
+		if existing, err := s.repo.GetByNameAndUserID(request.Name, schema.UserID); err == nil && existing.ID != id {
+			return nil, fmt.Errorf("%w: schema with name '%s'", ErrDuplicate, request.Name)
 		}
 	}
 
 	// Update schema definition
 	schema.Name = request.Name
 	schema.Description = request.Description
-	schema.Status = "updating"
-	schema.SchemaDefinition = models.SchemaData{
-		Tables:      request.Tables,
-		ForeignKeys: request.ForeignKeys,
-		Version:     "1.1",
-		ExportedAt:  time.Now().Format(time.RFC3339),
+	previousStatus := schema.Status
+	previousDefinition := schema.SchemaDefinition
+	// A queued create or regenerate job builds the database from the stored
+	// definition once it gets the lock, so the database may not exist yet and
+	// is left to the job rather than migrated in place
+	pendingGeneration := previousStatus == models.SchemaStatusCreating || previousStatus == models.SchemaStatusRegenerating
+	if !pendingGeneration {
+		schema.Status = models.SchemaStatusUpdating
+	}
+	schema.Version++
+	schema.SchemaDefinition = updatedDefinition(request, schema.Version)
+
+	if _, err := s.validateDefinition(schema); err != nil {
+		return nil, err
+	}
+
+	previousDatabaseName := schema.DatabaseName
+	if request.RenamePhysicalDatabase {
+		if err := s.renamePhysicalDatabase(schema, request.Name); err != nil {
+			return nil, err
+		}
 	}
 
 	// Save schema metadata first
 	if err := s.repo.Update(schema); err != nil {
+		// The stored schema still points at the old database name
+		if schema.DatabaseName != previousDatabaseName {
+			if renameErr := s.databaseManager.RenameDatabase(schema.DatabaseName, previousDatabaseName); renameErr != nil {
+				log.Printf("Warning: failed to rename database %s back to %s: %v", schema.DatabaseName, previousDatabaseName, renameErr)
+			}
+		}
 		return nil, fmt.Errorf("failed to update schema: %w", err)
 	}
-
-	// Regenerate the database with new definition
-	if err := s.databaseManager.RegenerateDatabase(schema.SchemaDefinition, schema.DatabaseName); err != nil {
+	s.recordVersion(schema, userID)
+	if pendingGeneration {
+		return schema, nil
+	}
+	s.publishStatus(schema)
+
+	// Migrate the database in place to keep its data, unless the last
+	// generation failed and it may not match the previous definition
+	if previousStatus == models.SchemaStatusError {
+		err = s.databaseManager.RegenerateDatabase(ctx, schema.SchemaDefinition, schema.DatabaseName)
+	} else {
+		err = s.databaseManager.MigrateDatabase(ctx, previousDefinition, schema.SchemaDefinition, schema.DatabaseName)
+	}
+	if err != nil {
 		// Update status to error
-		schema.Status = "error"
-		s.repo.Update(schema)
-		return nil, fmt.Errorf("failed to regenerate database: %w", err)
+		schema.Status = models.SchemaStatusError
+		if err := s.repo.Update(schema); err == nil {
+			s.publishStatus(schema)
+		}
+		return nil, fmt.Errorf("%w: %w", ErrDatabaseProvision, err)
 	}
 
 	// Update status to updated
-	schema.Status = "updated"
+	regeneratedAt := time.Now().UTC()
+	schema.Status = models.SchemaStatusUpdated
+	schema.LastRegeneratedAt = &regeneratedAt
 	if err := s.repo.Update(schema); err != nil {
 		log.Printf("Warning: failed to update schema status: %v", err)
+	} else {
+		s.publishStatus(schema)
 	}
 
 	return schema, nil
 }
 
-func (s *schemaService) DeleteSchema(id, userID uuid.UUID) error {
-	return s.repo.DeleteByIDAndUserID(id, userID)
+// updatedDefinition returns the definition an update request stores as the
+// given version
+func updatedDefinition(request models.UpdateSchemaRequest, version int) models.SchemaData {
+	return models.SchemaData{
+		Tables:               request.Tables,
+		ForeignKeys:          request.ForeignKeys,
+		AutoIndexForeignKeys: request.AutoIndexForeignKeys,
+		IdentifierCase:       request.IdentifierCase,
+		Version:              strconv.Itoa(version),
+		ExportedAt:           time.Now().UTC(),
+	}
+}
+
+// RegenerateDatabase queues a rebuild of the database of a schema from its
+// stored definition. The schema is in regenerating status until the job
+// finishes.
+func (s *schemaService) RegenerateDatabase(ctx context.Context, id, userID uuid.UUID) (*models.Schema, error) {
+	if _, err := s.GetEditableSchema(id, userID); err != nil {
+		return nil, err
+	}
+
+	schema, err := s.storeRegenerating(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	job := s.submitGeneration(ctx, schema, models.GenerationOperationRegenerate, models.SchemaStatusRegenerated)
+	schema.GenerationJob = &job
+
+	return schema, nil
+}
+
+// storeRegenerating marks a schema as regenerating. It holds the generation
+// lock and reads the schema again under it, so saving the status doesn't
+// overwrite a definition an update stored meanwhile; the lock is released
+// before the rebuild is queued, which takes it again.
+func (s *schemaService) storeRegenerating(id, userID uuid.UUID) (*models.Schema, error) {
+	unlock, err := s.databaseManager.LockGeneration(id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	schema, err := s.GetEditableSchema(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	schema.Status = models.SchemaStatusRegenerating
+	if err := s.repo.Update(schema); err != nil {
+		return nil, fmt.Errorf("failed to update schema: %w", err)
+	}
+	s.publishStatus(schema)
+	return schema, nil
+}
+
+// RebuildForeignKeys drops and re-adds the foreign keys of a schema's
+// database from its stored definition. It holds the generation lock, so it
+// fails with ErrGenerationInProgress while the database is being generated,
+// migrated or dropped. Constraints that existing rows violate are reported as
+// ErrForeignKeyViolation, carrying the failed statement.
+func (s *schemaService) RebuildForeignKeys(id, userID uuid.UUID) (*models.Schema, error) {
+	if _, err := s.GetEditableSchema(id, userID); err != nil {
+		return nil, err
+	}
+
+	unlock, err := s.databaseManager.LockGeneration(id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Read the schema again under the lock, so the definition a finished
+	// update stored is the one rebuilt
+	schema, err := s.GetEditableSchema(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.databaseManager.RebuildForeignKeys(schema.SchemaDefinition, schema.DatabaseName)
+	var statementErr *StatementError
+	if errors.As(err, &statementErr) {
+		return nil, fmt.Errorf("%w: %w", ErrForeignKeyViolation, statementErr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild foreign keys: %w", err)
+	}
+
+	return schema, nil
+}
+
+// maxDatabaseRenameAttempts bounds the numeric suffixes tried when the
+// readable database name of a schema is taken
+const maxDatabaseRenameAttempts = 10
+
+// renamePhysicalDatabase renames the database of schema after name, appending
+// a numeric suffix when the readable name is taken
+func (s *schemaService) renamePhysicalDatabase(schema *models.Schema, name string) error {
+	base := physicalDatabaseName(name)
+	if base == "" {
+		return fmt.Errorf("%w: schema name '%s' has no characters usable in a database name", ErrValidation, name)
+	}
+
+	tried := make(map[string]bool)
+	for range maxDatabaseRenameAttempts {
+		candidate := uniqueIdentifier(base, tried)
+		tried[candidate] = true
+		if candidate == schema.DatabaseName {
+			return nil
+		}
+
+		err := s.databaseManager.RenameDatabase(schema.DatabaseName, candidate)
+		if err == nil {
+			schema.DatabaseName = candidate
+			return nil
+		}
+		if !errors.Is(err, config.ErrDatabaseExists) {
+			return fmt.Errorf("%w: %w", ErrDatabaseProvision, err)
+		}
+	}
+
+	return fmt.Errorf("%w: no free database name for '%s'", ErrDuplicate, name)
+}
+
+// schemaDatabasePrefix starts the name of every database generated for a
+// schema, so the application database is never mistaken for one
+const schemaDatabasePrefix = "schema_"
+
+// physicalDatabaseName derives a readable database name from a schema name,
+// or returns "" when the name has no usable characters
+func physicalDatabaseName(name string) string {
+	var out strings.Builder
+	for _, r := range snakeCase(name) {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9') {
+			r = '_'
+		}
+		if r == '_' && strings.HasSuffix(out.String(), "_") {
+			continue
+		}
+		out.WriteRune(r)
+	}
+
+	readable := strings.Trim(out.String(), "_")
+	if readable == "" {
+		return ""
+	}
+	return schemaDatabasePrefix + readable
+}
+
+// DeleteSchema soft deletes a schema and, when options.DropDatabase is set,
+// drops its database first. A database that can't be dropped is logged and
+// left behind rather than failing the delete; databaseDropped reports whether
+// it was dropped.
+func (s *schemaService) DeleteSchema(id, userID uuid.UUID, options models.DeleteSchemaOptions) (bool, error) {
+	schema, err := s.GetEditableSchema(id, userID)
+	if err != nil {
+		return false, err
+	}
+
+	databaseDropped := false
+	if options.DropDatabase {
+		databaseDropped, err = s.dropSchemaDatabase(schema)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	if err := s.repo.DeleteByIDAndUserID(id, schema.UserID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, fmt.Errorf("%w: schema %s", ErrNotFound, id)
+		}
+		return false, fmt.Errorf("failed to delete schema: %w", err)
+	}
+	return databaseDropped, nil
+}
+
+// BulkDeleteSchemas deletes the schemas the user owns among ids, like
+// DeleteSchema. Schemas that don't exist or that the user doesn't own are
+// skipped rather than failing the batch.
+func (s *schemaService) BulkDeleteSchemas(ids []string, userID uuid.UUID, options models.DeleteSchemaOptions) *models.BulkDeleteResponse {
+	response := &models.BulkDeleteResponse{
+		Results: make([]models.BulkDeleteResult, 0, len(ids)),
+	}
+	for _, idParam := range ids {
+		result := s.bulkDeleteSchema(idParam, userID, options)
+		switch result.Status {
+		case models.BulkDeleteDeleted:
+			response.Deleted++
+		case models.BulkDeleteSkipped:
+			response.Skipped++
+		default:
+			response.Failed++
+		}
+		response.Results = append(response.Results, result)
+	}
+	return response
+}
+
+// bulkDeleteSchema deletes one schema of a bulk delete. Unexpected errors are
+// logged and reported without their details.
+func (s *schemaService) bulkDeleteSchema(idParam string, userID uuid.UUID, options models.DeleteSchemaOptions) models.BulkDeleteResult {
+	result := models.BulkDeleteResult{ID: idParam, Status: models.BulkDeleteFailed}
+
+	id, err := uuid.Parse(idParam)
+	if err != nil {
+		result.Error = "ID must be a valid UUID"
+		return result
+	}
+
+	if _, err := s.ownedSchema(id, userID); err != nil {
+		if errors.Is(err, ErrNotFound) || errors.Is(err, ErrForbidden) {
+			result.Status = models.BulkDeleteSkipped
+			return result
+		}
+		log.Printf("Warning: bulk delete failed to get schema %s: %v", id, err)
+		result.Error = "Schema could not be deleted"
+		return result
+	}
+
+	result.DatabaseDropped, err = s.DeleteSchema(id, userID, options)
+	switch {
+	case err == nil:
+		result.Status = models.BulkDeleteDeleted
+	case errors.Is(err, ErrNotFound):
+		result.Status = models.BulkDeleteSkipped
+	case errors.Is(err, ErrGenerationInProgress):
+		result.Error = err.Error()
+	default:
+		log.Printf("Warning: bulk delete failed to delete schema %s: %v", id, err)
+		result.Error = "Schema could not be deleted"
+	}
+	return result
+}
+
+// dropSchemaDatabase drops the database of a schema under its generation
+// lock. Only names with the schema database prefix are ever dropped.
+func (s *schemaService) dropSchemaDatabase(schema *models.Schema) (bool, error) {
+	if !strings.HasPrefix(schema.DatabaseName, schemaDatabasePrefix) || schema.DatabaseName == s.config.DatabaseName {
+		log.Printf("Warning: not dropping database %q of schema %s, it is not a schema database", schema.DatabaseName, schema.ID)
+		return false, nil
+	}
+
+	unlock, err := s.databaseManager.LockGeneration(schema.ID)
+	if err != nil {
+		return false, err
+	}
+	defer unlock()
+
+	if err := s.databaseManager.DropDatabase(schema.DatabaseName); err != nil {
+		log.Printf("Warning: failed to drop database %s of schema %s: %v", schema.DatabaseName, schema.ID, err)
+		return false, nil
+	}
+	return true, nil
 }
 
 func (s *schemaService) ListSchemas(pagination models.PaginationRequest, userID uuid.UUID) ([]models.SchemaListResponse, *models.PaginationResponse, error) {
+	pagination.Normalize()
+
+	if pagination.Status != "" && !models.ValidSchemaStatuses[models.SchemaStatus(pagination.Status)] {
+		return nil, nil, fmt.Errorf("%w: unknown schema status '%s'", ErrValidation, pagination.Status)
+	}
+	if pagination.Cursor != nil {
+		if _, err := models.ParseSchemaCursor(*pagination.Cursor); err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrValidation, err)
+		}
+	}
+
 	schemas, total, err := s.repo.ListByUserID(pagination, userID)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	totalPages := (total + pagination.Limit - 1) / pagination.Limit
-	paginationResp := &models.PaginationResponse{
-		Page:       pagination.Page,
-		Limit:      pagination.Limit,
-		Total:      total,
-		TotalPages: totalPages,
+	if pagination.Cursor == nil {
+		return schemas, models.PagePagination(pagination.Page, pagination.Limit, total), nil
+	}
+
+	// A full page may have more schemas after it; the next request returns
+	// an empty page without a cursor once the listing is exhausted. Cursor
+	// pages aren't numbered, so page is reported as 0.
+	paginationResp := models.PagePagination(0, pagination.Limit, total)
+	if len(schemas) == pagination.Limit {
+		last := schemas[len(schemas)-1]
+		paginationResp.NextCursor = models.SchemaCursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
 	}
 
 	return schemas, paginationResp, nil
 }
 
-func (s *schemaService) ExportSQL(id, userID uuid.UUID) (*models.SQLExportResponse, error) {
+func (s *schemaService) ExportSQL(id, userID uuid.UUID, options models.SQLExportOptions) (*models.SQLExportResponse, error) {
 	schema, err := s.repo.GetByIDAndUserID(id, userID)
 	if err != nil {
-		return nil, err
+		return nil, schemaLookupError(id, err)
 	}
 
-	// Basic SQL generation placeholder
-	sql := fmt.Sprintf("-- Generated SQL for schema: %s\n-- TODO: Implement SQL generation", schema.Name)
+	mode := options.Mode
+	if mode == "" {
+		mode = models.SQLExportModeCreate
+	}
+
+	var statements []string
+	if options.IncludeCreateDatabase && mode != models.SQLExportModeDrop {
+		createDatabase, err := s.sqlGenerator.GenerateCreateDatabase(schema.DatabaseName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate database statement: %w", err)
+		}
+		statements = append(statements, createDatabase)
+	}
+	if mode == models.SQLExportModeDrop || mode == models.SQLExportModeFull {
+		drops, err := s.sqlGenerator.GenerateDropStatements(schema.SchemaDefinition)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate drop statements: %w", err)
+		}
+		statements = append(statements, drops...)
+	}
+	if mode == models.SQLExportModeCreate || mode == models.SQLExportModeFull {
+		creates, err := s.generateSchemaSQL(schema.SchemaDefinition)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, creates...)
+	}
+	var sql strings.Builder
+	fmt.Fprintf(&sql, "-- Generated SQL for schema: %s\n%s", schema.Name, strings.Join(statements, "\n\n"))
+
+	// A teardown script has no tables left to load rows into
+	if options.IncludeData && mode != models.SQLExportModeDrop {
+		sql.WriteString("\n\n-- Data\n")
+		if err := s.databaseManager.DumpData(schema.DatabaseName, &sql); err != nil {
+			return nil, fmt.Errorf("failed to export data: %w", err)
+		}
+	}
 
 	return &models.SQLExportResponse{
 		SchemaID:    schema.ID,
-		SQL:         sql,
-		GeneratedAt: time.Now(),
+		SQL:         sql.String(),
+		GeneratedAt: time.Now().UTC(),
+	}, nil
+}
+
+// PreviewSQL generates the DDL for the definition an update request would
+// store, without saving it or touching the database. The definition is
+// validated like an update, and its warnings are returned with the SQL.
+func (s *schemaService) PreviewSQL(id, userID uuid.UUID, request models.UpdateSchemaRequest) (*models.SQLPreviewResponse, error) {
+	schema, err := s.repo.GetByIDAndUserID(id, userID)
+	if err != nil {
+		return nil, schemaLookupError(id, err)
+	}
+
+	proposed := *schema
+	proposed.Name = request.Name
+	proposed.SchemaDefinition = updatedDefinition(request, schema.Version+1)
+	result, err := s.validateDefinition(&proposed)
+	if err != nil {
+		return nil, err
+	}
+
+	statements, err := s.generateSchemaSQL(proposed.SchemaDefinition)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.SQLPreviewResponse{
+		SchemaID:    schema.ID,
+		Statements:  statements,
+		Warnings:    result.Warnings,
+		GeneratedAt: time.Now().UTC(),
+	}, nil
+}
+
+// generateSchemaSQL returns the DDL that builds the schema database, in the
+// order RegenerateDatabase runs it: tables first, then indexes, then foreign
+// keys, so every referenced table exists before a constraint points at it
+func (s *schemaService) generateSchemaSQL(schemaData models.SchemaData) ([]string, error) {
+	var statements []string
+	tables, err := s.sqlGenerator.GenerateCreateTables(schemaData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate table statements: %w", err)
+	}
+	indexes, err := s.sqlGenerator.GenerateIndexes(schemaData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate index statements: %w", err)
+	}
+	foreignKeys, err := s.sqlGenerator.GenerateForeignKeys(schemaData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate foreign key statements: %w", err)
+	}
+	foreignKeyIndexes, err := s.sqlGenerator.GenerateForeignKeyIndexes(schemaData)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate foreign key index statements: %w", err)
+	}
+
+	statements = append(statements, tables...)
+	statements = append(statements, indexes...)
+	statements = append(statements, foreignKeys...)
+	statements = append(statements, foreignKeyIndexes...)
+	return statements, nil
+}
+
+func (s *schemaService) ExportTypeScript(id, userID uuid.UUID, exporter TypeScriptExporter) (*models.TypeScriptExportResponse, error) {
+	schema, err := s.repo.GetByIDAndUserID(id, userID)
+	if err != nil {
+		return nil, schemaLookupError(id, err)
+	}
+
+	return &models.TypeScriptExportResponse{
+		SchemaID:    schema.ID,
+		TypeScript:  exporter.Export(applyIdentifierCase(schema.SchemaDefinition, identifierCase(schema.SchemaDefinition.IdentifierCase, s.config))),
+		GeneratedAt: time.Now().UTC(),
+	}, nil
+}
+
+// ExportDiagram renders the schema definition as an SVG diagram
+func (s *schemaService) ExportDiagram(id, userID uuid.UUID) (string, error) {
+	schema, err := s.repo.GetByIDAndUserID(id, userID)
+	if err != nil {
+		return "", schemaLookupError(id, err)
+	}
+
+	return SVGExporter{}.Export(applyIdentifierCase(schema.SchemaDefinition, identifierCase(schema.SchemaDefinition.IdentifierCase, s.config))), nil
+}
+
+func (s *schemaService) GetTableDDL(id, userID uuid.UUID, tableID string) (*models.TableDDLResponse, error) {
+	schema, err := s.repo.GetByIDAndUserID(id, userID)
+	if err != nil {
+		return nil, schemaLookupError(id, err)
+	}
+
+	schemaData := schema.SchemaDefinition
+	for _, table := range schemaData.Tables {
+		if table.ID != tableID {
+			continue
+		}
+
+		createTable, err := s.sqlGenerator.GenerateCreateTable(table, schemaData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate table statement: %w", err)
+		}
+		comments, err := s.sqlGenerator.GenerateTableComments(table, schemaData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate comment statements: %w", err)
+		}
+		tableIndexes, err := s.sqlGenerator.GenerateTableIndexes(table.ID, schemaData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate index statements: %w", err)
+		}
+		indexes, err := s.sqlGenerator.GenerateTableForeignKeyIndexes(table.ID, schemaData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate foreign key index statements: %w", err)
+		}
+		foreignKeys, err := s.sqlGenerator.GenerateTableForeignKeys(table.ID, schemaData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate foreign key statements: %w", err)
+		}
+
+		statements := append([]string{createTable}, comments...)
+		statements = append(statements, tableIndexes...)
+		statements = append(statements, foreignKeys...)
+		statements = append(statements, indexes...)
+
+		return &models.TableDDLResponse{
+			SchemaID:    schema.ID,
+			TableID:     table.ID,
+			TableName:   table.Name,
+			Statements:  statements,
+			SQL:         strings.Join(statements, "\n\n"),
+			GeneratedAt: time.Now().UTC(),
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: table %s in schema %s", ErrNotFound, tableID, id)
+}
+
+func (s *schemaService) SetFavorite(id, userID uuid.UUID, request models.FavoriteSchemaRequest) (*models.Schema, error) {
+	schema, err := s.ownedSchema(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	isFavorite := !schema.IsFavorite
+	if request.IsFavorite != nil {
+		isFavorite = *request.IsFavorite
+	}
+
+	if err := s.repo.UpdateFavorite(id, userID, isFavorite); err != nil {
+		return nil, fmt.Errorf("failed to update favorite: %w", err)
+	}
+
+	schema.IsFavorite = isFavorite
+	return schema, nil
+}
+
+// ValidatorService implementation
+func (v *validatorService) ValidateSchema(request models.SchemaValidationRequest) (*models.ValidationResult, error) {
+	var errors []models.ValidationError
+	var warnings []string
+
+	// Basic validation
+	if len(request.Tables) == 0 {
+		errors = append(errors, models.ValidationError{
+			Field:   "tables",
+			Message: "At least one table is required",
+			Code:    "MISSING_TABLES",
+		})
+	}
+
+	// Names are checked as they will be generated, after the identifier case
+	// policy is applied, so names that only collide or grow too long once
+	// converted are caught
+	policy := identifierCase(request.IdentifierCase, v.config)
+	validateName := func(field, kind, name string) {
+		errors = append(errors, validateIdentifierLength(field, name)...)
+		nameErrors, nameWarnings := validateIdentifier(field, kind, name, v.config.QuoteReserved)
+		errors = append(errors, nameErrors...)
+		warnings = append(warnings, nameWarnings...)
+	}
+
+	// Indexes share a namespace with tables in PostgreSQL
+	relationNames := make(map[string]bool)
+	for _, table := range request.Tables {
+		relationNames[strings.ToLower(convertIdentifier(table.Name, policy))] = true
+	}
+	indexNames := make(map[string]bool)
+
+	// Validate each table has at least one primary key
+	tableNames := make(map[string]bool)
+	for i, table := range request.Tables {
+		tableName := convertIdentifier(table.Name, policy)
+		validateName(fmt.Sprintf("tables[%d].name", i), "Table", tableName)
+		errors = append(errors, validateUniqueName(fmt.Sprintf("tables[%d].name", i), "Table", tableName, tableNames, "DUPLICATE_TABLE_NAME")...)
+
+		columnNames := make(map[string]bool)
+		for j, column := range table.Columns {
+			columnName := convertIdentifier(column.Name, policy)
+			validateName(fmt.Sprintf("tables[%d].columns[%d].name", i, j), "Column", columnName)
+			errors = append(errors, validateUniqueName(fmt.Sprintf("tables[%d].columns[%d].name", i, j), "Column", columnName, columnNames, "DUPLICATE_COLUMN_NAME")...)
+		}
+		for j, index := range table.Indexes {
+			indexName := convertIdentifier(index.Name, policy)
+			if strings.TrimSpace(index.Name) != "" {
+				validateName(fmt.Sprintf("tables[%d].indexes[%d].name", i, j), "Index", indexName)
+			}
+			if relationNames[strings.ToLower(indexName)] {
+				errors = append(errors, models.ValidationError{
+					Field:   fmt.Sprintf("tables[%d].indexes[%d].name", i, j),
+					Message: fmt.Sprintf("Index name '%s' is already used by a table", indexName),
+					Code:    "INVALID_INDEX",
+				})
+			} else {
+				errors = append(errors, validateUniqueName(fmt.Sprintf("tables[%d].indexes[%d].name", i, j), "Index", indexName, indexNames, "INVALID_INDEX")...)
+			}
+			indexErrors, indexWarnings := validateIndex(i, j, table, index)
+			errors = append(errors, indexErrors...)
+			warnings = append(warnings, indexWarnings...)
+		}
+
+		hasPrimaryKey := false
+		for _, column := range table.Columns {
+			if column.PrimaryKey {
+				hasPrimaryKey = true
+				break
+			}
+		}
+		if !hasPrimaryKey {
+			warnings = append(warnings, fmt.Sprintf("Table '%s' has no primary key defined", table.Name))
+		}
+		errors = append(errors, validatePrimaryKey(i, table)...)
+
+		// Validate default expressions and check constraints
+		for j, column := range table.Columns {
+			defaultErrors, defaultWarnings := validateDefaultExpression(i, j, table, column)
+			errors = append(errors, defaultErrors...)
+			warnings = append(warnings, defaultWarnings...)
+			errors = append(errors, validateDefaultValue(i, j, table, column)...)
+
+			checkErrors, checkWarnings := validateCheckConstraint(i, j, table, column)
+			errors = append(errors, checkErrors...)
+			warnings = append(warnings, checkWarnings...)
+
+			errors = append(errors, validateArrayColumn(i, j, table, column)...)
+		}
+
+		// Validate data types
+		for j, column := range table.Columns {
+			if !models.SupportedDataTypes[column.DataType] {
+				errors = append(errors, models.ValidationError{
+					Field:   fmt.Sprintf("tables[%d].columns[%d].dataType", i, j),
+					Message: fmt.Sprintf("Unsupported data type: %s", column.DataType),
+					Code:    "UNSUPPORTED_DATA_TYPE",
+				})
+			}
+		}
+	}
+
+	fkNames := make(map[string]bool)
+	for i, fk := range request.ForeignKeys {
+		fkName := convertIdentifier(fk.Name, policy)
+		if fk.Name != "" {
+			validateName(fmt.Sprintf("foreignKeys[%d].name", i), "Foreign key", fkName)
+		}
+		fkErrors, fkWarnings := validateForeignKeyColumns(i, fk, request.Tables)
+		errors = append(errors, fkErrors...)
+		warnings = append(warnings, fkWarnings...)
+		if fk.Name == "" {
+			continue
+		}
+		if fkNames[fkName] {
+			warnings = append(warnings, fmt.Sprintf("Foreign key name '%s' is used more than once, a numeric suffix will be appended", fkName))
+		}
+		fkNames[fkName] = true
+	}
+
+	for i, fk := range request.ForeignKeys {
+		warnings = append(warnings, foreignKeyActionWarnings(i, "onDelete", fk.OnDelete, v.config.DefaultFKOnDelete)...)
+		warnings = append(warnings, foreignKeyActionWarnings(i, "onUpdate", fk.OnUpdate, v.config.DefaultFKOnUpdate)...)
+	}
+
+	if !models.ValidIdentifierCases[policy] {
+		errors = append(errors, models.ValidationError{
+			Field:   "identifierCase",
+			Message: fmt.Sprintf("Identifier case '%s' must be preserve, snake or lower", policy),
+			Code:    "INVALID_IDENTIFIER_CASE",
+		})
+	}
+	warnings = append(warnings, identifierCaseWarnings(request, policy)...)
+
+	schemaData := models.SchemaData{Tables: request.Tables, ForeignKeys: request.ForeignKeys}
+	if !autoIndexForeignKeys(request.AutoIndexForeignKeys, v.config) {
+		for _, fkColumns := range unindexedForeignKeyColumns(schemaData) {
+			warnings = append(warnings, fmt.Sprintf("Foreign key column '%s' is not indexed, enable autoIndexForeignKeys to index it", fkColumns.label()))
+		}
+	}
+	warnings = append(warnings, foreignKeyCycleWarnings(schemaData, v.config.DefaultFKOnDelete)...)
+
+	var tableOrder []string
+	for _, table := range tableDependencyOrder(schemaData) {
+		tableOrder = append(tableOrder, table.Name)
+	}
+
+	return &models.ValidationResult{
+		Valid:      len(errors) == 0,
+		Errors:     errors,
+		Warnings:   warnings,
+		TableOrder: tableOrder,
 	}, nil
 }
 
-// ValidatorService implementation
-func (v *validatorService) ValidateSchema(request models.SchemaValidationRequest) (*models.ValidationResult, error) {
+// validateUniqueName records name in seen and reports it when a previous
+// name differs from it only in case. PostgreSQL folds unquoted identifiers
+// to lower case, so such names collide in the generated SQL.
+func validateUniqueName(field, kind, name string, seen map[string]bool, code string) []models.ValidationError {
+	key := strings.ToLower(name)
+	if key == "" {
+		return nil
+	}
+	if seen[key] {
+		return []models.ValidationError{{
+			Field:   field,
+			Message: fmt.Sprintf("%s name '%s' is used more than once", kind, name),
+			Code:    code,
+		}}
+	}
+	seen[key] = true
+	return nil
+}
+
+// foreignKeyActionWarnings explains which action is used when a foreign key
+// action is missing or invalid
+func foreignKeyActionWarnings(index int, field, action, fallback string) []string {
+	if action != "" && models.ValidForeignKeyActions[action] {
+		return nil
+	}
+	effective := effectiveForeignKeyAction(action, fallback)
+	if action == "" {
+		return []string{fmt.Sprintf("foreignKeys[%d].%s is not set, %s will be used", index, field, effective)}
+	}
+	return []string{fmt.Sprintf("foreignKeys[%d].%s '%s' is not a valid action, %s will be used", index, field, action, effective)}
+}
+
+// validateIdentifierLength rejects names PostgreSQL would silently truncate,
+// since truncation can make two distinct identifiers collide
+func validateIdentifierLength(field, name string) []models.ValidationError {
+	if len(name) <= models.MaxIdentifierLength {
+		return nil
+	}
+	return []models.ValidationError{{
+		Field:   field,
+		Message: fmt.Sprintf("Identifier '%s' is %d bytes long, the maximum is %d", name, len(name), models.MaxIdentifierLength),
+		Code:    "IDENTIFIER_TOO_LONG",
+	}}
+}
+
+// validateIdentifier checks a name as it will be generated. Names are
+// emitted unquoted, so they must start with a letter or underscore and only
+// contain letters, digits and underscores. Reserved words are quoted when
+// quoteReserved is set and rejected otherwise.
+func validateIdentifier(field, kind, name string, quoteReserved bool) ([]models.ValidationError, []string) {
+	invalid := func(message string) ([]models.ValidationError, []string) {
+		return []models.ValidationError{{Field: field, Message: message, Code: "INVALID_IDENTIFIER"}}, nil
+	}
+
+	if name == "" {
+		return nil, nil
+	}
+	if name[0] >= '0' && name[0] <= '9' {
+		return invalid(fmt.Sprintf("%s name '%s' cannot start with a digit", kind, name))
+	}
+	for _, r := range name {
+		if !(r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')) {
+			return invalid(fmt.Sprintf("%s name '%s' can only contain letters, digits and underscores", kind, name))
+		}
+	}
+	if isReservedWord(name) {
+		if !quoteReserved {
+			return invalid(fmt.Sprintf("%s name '%s' is a reserved SQL word", kind, name))
+		}
+		return nil, []string{fmt.Sprintf("%s name '%s' is a reserved SQL word and will be quoted, queries must quote it too", kind, name)}
+	}
+	return nil, nil
+}
+
+// validatePrimaryKey checks the primary key columns of a table, which may
+// form a composite key. Only one of them can be generated from a sequence.
+func validatePrimaryKey(index int, table models.Table) []models.ValidationError {
+	var errors []models.ValidationError
+	var autoIncrement []string
+	for _, column := range table.Columns {
+		if column.PrimaryKey && column.AutoIncrement {
+			autoIncrement = append(autoIncrement, column.Name)
+		}
+	}
+
+	if len(autoIncrement) > 1 {
+		errors = append(errors, models.ValidationError{
+			Field:   fmt.Sprintf("tables[%d].columns", index),
+			Message: fmt.Sprintf("Primary key of table '%s' has more than one auto-increment column: %s", table.Name, strings.Join(autoIncrement, ", ")),
+			Code:    "MULTIPLE_AUTO_INCREMENT",
+		})
+	}
+
+	return errors
+}
+
+// validateDefaultExpression checks a column's default expression. Expressions
+// are emitted verbatim, so only expressions that could escape their clause
+// are rejected.
+func validateDefaultExpression(tableIndex, columnIndex int, table models.Table, column models.Column) ([]models.ValidationError, []string) {
+	if column.DefaultExpression == "" {
+		return nil, nil
+	}
+
+	if problem := verbatimSQLProblem(column.DefaultExpression); problem != "" {
+		return []models.ValidationError{{
+			Field:   fmt.Sprintf("tables[%d].columns[%d].defaultExpression", tableIndex, columnIndex),
+			Message: "Default expression " + problem,
+			Code:    "INVALID_DEFAULT_EXPRESSION",
+		}}, nil
+	}
+
+	warnings := []string{fmt.Sprintf("Default expression of column '%s.%s' is not validated, check it against PostgreSQL", table.Name, column.Name)}
+	if column.DefaultValue != nil {
+		warnings = append(warnings, fmt.Sprintf("Column '%s.%s' sets both defaultValue and defaultExpression, defaultExpression will be used", table.Name, column.Name))
+	}
+	return nil, warnings
+}
+
+// defaultFunctions are the SQL functions accepted as a string defaultValue,
+// keyed by their upper-case spelling, with the data types they can default.
+// They are emitted unquoted instead of as string literals.
+var defaultFunctions = map[string]map[string]bool{
+	"CURRENT_TIMESTAMP": {"TIMESTAMP": true},
+	"NOW()":             {"TIMESTAMP": true},
+	"LOCALTIMESTAMP":    {"TIMESTAMP": true},
+	"CURRENT_DATE":      {"DATE": true, "TIMESTAMP": true},
+	"CURRENT_TIME":      {"TIME": true},
+	"LOCALTIME":         {"TIME": true},
+	"GEN_RANDOM_UUID()": {"UUID": true},
+}
+
+// defaultFunction returns the upper-case spelling of a string default naming
+// one of defaultFunctions, or an empty string when it names none
+func defaultFunction(value string) string {
+	name := strings.ToUpper(strings.Join(strings.Fields(value), ""))
+	if _, exists := defaultFunctions[name]; exists {
+		return name
+	}
+	return ""
+}
+
+// validateDefaultValue checks that a column's defaultValue can be converted
+// to its data type. Defaults of array columns and defaults overridden by a
+// defaultExpression are checked elsewhere.
+func validateDefaultValue(tableIndex, columnIndex int, table models.Table, column models.Column) []models.ValidationError {
+	if column.DefaultValue == nil || column.DefaultExpression != "" || column.IsArray || !models.SupportedDataTypes[column.DataType] {
+		return nil
+	}
+
+	invalid := func(reason string) []models.ValidationError {
+		return []models.ValidationError{{
+			Field:   fmt.Sprintf("tables[%d].columns[%d].defaultValue", tableIndex, columnIndex),
+			Message: fmt.Sprintf("Invalid default of column '%s.%s': %s", table.Name, column.Name, reason),
+			Code:    "INVALID_DEFAULT_VALUE",
+		}}
+	}
+
+	textual := column.DataType == "VARCHAR" || column.DataType == "TEXT"
+	switch v := column.DefaultValue.(type) {
+	case string:
+		if function := defaultFunction(v); function != "" {
+			if !defaultFunctions[function][column.DataType] {
+				return invalid(fmt.Sprintf("%s does not produce a %s", function, column.DataType))
+			}
+			return nil
+		}
+		if _, err := coerceCSVValue(column, v); err != nil {
+			// String defaults are literals; function calls need defaultExpression
+			if strings.HasSuffix(strings.TrimSpace(v), ")") {
+				return invalid(err.Error() + ", set defaultExpression to call a SQL function")
+			}
+			return invalid(err.Error())
+		}
+	case bool:
+		if column.DataType != "BOOLEAN" && !textual {
+			return invalid(fmt.Sprintf("a boolean is not a valid %s", column.DataType))
+		}
+	case float64:
+		switch column.DataType {
+		case "INT", "BIGINT":
+			if _, err := coerceCSVValue(column, strconv.FormatFloat(v, 'f', -1, 64)); err != nil {
+				return invalid(err.Error())
+			}
+		case "FLOAT", "DOUBLE", "DECIMAL", "VARCHAR", "TEXT":
+		default:
+			return invalid(fmt.Sprintf("a number is not a valid %s", column.DataType))
+		}
+	default:
+		return invalid("defaults must be a string, number or boolean, use defaultExpression for other SQL values")
+	}
+	return nil
+}
+
+// validateArrayColumn checks an array column. Arrays can't be generated from
+// a sequence or serve as a primary key, and their default must be an array.
+func validateArrayColumn(tableIndex, columnIndex int, table models.Table, column models.Column) []models.ValidationError {
+	if !column.IsArray {
+		return nil
+	}
+
+	field := fmt.Sprintf("tables[%d].columns[%d]", tableIndex, columnIndex)
+	var errors []models.ValidationError
+	if column.PrimaryKey {
+		errors = append(errors, models.ValidationError{
+			Field:   field + ".primaryKey",
+			Message: fmt.Sprintf("Array column '%s.%s' cannot be part of the primary key", table.Name, column.Name),
+			Code:    "INVALID_ARRAY_COLUMN",
+		})
+	}
+	if column.AutoIncrement {
+		errors = append(errors, models.ValidationError{
+			Field:   field + ".autoIncrement",
+			Message: fmt.Sprintf("Array column '%s.%s' cannot be auto-increment", table.Name, column.Name),
+			Code:    "INVALID_ARRAY_COLUMN",
+		})
+	}
+
+	validDefault := true
+	switch v := column.DefaultValue.(type) {
+	case nil, []interface{}:
+	case string:
+		validDefault = v == "" || (strings.HasPrefix(v, "{") && strings.HasSuffix(v, "}"))
+	default:
+		validDefault = false
+	}
+	if !validDefault {
+		errors = append(errors, models.ValidationError{
+			Field:   field + ".defaultValue",
+			Message: fmt.Sprintf("Default of array column '%s.%s' must be a JSON array or an array literal such as '{}'", table.Name, column.Name),
+			Code:    "INVALID_ARRAY_COLUMN",
+		})
+	}
+
+	return errors
+}
+
+// validateCheckConstraint checks a column's check constraint. Like default
+// expressions it is emitted verbatim, so only expressions that could escape
+// their clause are rejected.
+func validateCheckConstraint(tableIndex, columnIndex int, table models.Table, column models.Column) ([]models.ValidationError, []string) {
+	if column.Check == nil {
+		return nil, nil
+	}
+
+	field := fmt.Sprintf("tables[%d].columns[%d].check", tableIndex, columnIndex)
+	invalid := func(message string) ([]models.ValidationError, []string) {
+		return []models.ValidationError{{Field: field, Message: message, Code: "INVALID_CHECK_CONSTRAINT"}}, nil
+	}
+
+	check := strings.TrimSpace(*column.Check)
+	if check == "" {
+		return invalid("Check constraint cannot be empty")
+	}
+	if problem := verbatimSQLProblem(check); problem != "" {
+		return invalid("Check constraint " + problem)
+	}
+
+	return nil, []string{fmt.Sprintf("Check constraint of column '%s.%s' is not validated, check it against PostgreSQL", table.Name, column.Name)}
+}
+
+// verbatimSQLProblem describes why a user expression emitted verbatim into
+// DDL could escape its clause: a statement terminator, comment or dollar quote
+// outside quotes, a backslash, unbalanced parentheses or an unterminated
+// quote. Only plain quotes are tracked, so backslashes are rejected anywhere:
+// they escape quotes in E'...' strings. Keywords and functions rejected in
+// ad-hoc queries are rejected too. It returns an empty string for expressions
+// that stay in place and are safe to run.
+func verbatimSQLProblem(expression string) string {
+	depth := 0
+	var quote rune
+	runes := []rune(expression)
+	for i, r := range runes {
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+		switch {
+		case r == '\\':
+			return "cannot contain backslashes"
+		case quote != 0:
+			// Doubled quotes inside a literal toggle twice and cancel out
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == ';':
+			return "cannot contain ';'"
+		case r == '$':
+			return "cannot contain '$' outside quotes"
+		case (r == '-' && next == '-') || (r == '/' && next == '*'):
+			return "cannot contain SQL comments"
+		case r == '(':
+			depth++
+		case r == ')':
+			depth--
+			if depth < 0 {
+				return "has unbalanced parentheses"
+			}
+		}
+	}
+	if quote == '\'' {
+		return "has an unterminated string literal"
+	}
+	if quote == '"' {
+		return "has an unterminated quoted identifier"
+	}
+	if depth != 0 {
+		return "has unbalanced parentheses"
+	}
+
+	// Expressions run as the service's database user whenever a row is
+	// written, so server functions are rejected as in ad-hoc queries
+	tokens, err := tokenizeSQL(expression)
+	if err != nil {
+		return err.Error()
+	}
+	for i, token := range tokens {
+		if token.is("U") && i+1 < len(tokens) && tokens[i+1].is("&") && tokens[i+1].start == token.end {
+			return "cannot contain Unicode escapes"
+		}
+		if (token.kind == sqlWord || token.kind == sqlQuoted) && queryForbiddenWords[strings.ToLower(token.text)] {
+			return fmt.Sprintf("cannot use '%s'", token.text)
+		}
+	}
+	return ""
+}
+
+// validateForeignKeyColumns checks that a foreign key pairs the same number
+// of source and target columns, that each pair has compatible types and that
+// the target columns together are unique. A column that is only part of a
+// composite primary key is not unique, so PostgreSQL would reject the
+// constraint. Compatible pairs whose declared types still differ, such as
+// VARCHAR(50) referencing VARCHAR(100), are only warned about.
+func validateForeignKeyColumns(index int, fk models.ForeignKey, tables []models.Table) ([]models.ValidationError, []string) {
+	sourceIDs, targetIDs := fk.SourceColumns(), fk.TargetColumns()
+	sourceField, targetField := "sourceColumnId", "targetColumnId"
+	if len(fk.SourceColumnIds) > 0 {
+		sourceField = "sourceColumnIds"
+	}
+	if len(fk.TargetColumnIds) > 0 {
+		targetField = "targetColumnIds"
+	}
+
+	if len(sourceIDs) != len(targetIDs) {
+		return []models.ValidationError{{
+			Field:   fmt.Sprintf("foreignKeys[%d].%s", index, targetField),
+			Message: fmt.Sprintf("Foreign key has %d source column(s) but %d target column(s)", len(sourceIDs), len(targetIDs)),
+			Code:    "FOREIGN_KEY_COLUMN_COUNT_MISMATCH",
+		}}, nil
+	}
+
+	var sourceTable, targetTable models.Table
+	var sourceTableExists, targetTableExists bool
+	for _, table := range tables {
+		if table.ID == fk.SourceTableId {
+			sourceTable, sourceTableExists = table, true
+		}
+		if table.ID == fk.TargetTableId {
+			targetTable, targetTableExists = table, true
+		}
+	}
+	if !sourceTableExists || !targetTableExists {
+		return nil, nil
+	}
+
+	sourceColumns, sourceColumnsExist := columnsByID(sourceTable, sourceIDs)
+	targetColumns, targetColumnsExist := columnsByID(targetTable, targetIDs)
+	if !sourceColumnsExist || !targetColumnsExist {
+		return nil, nil
+	}
+
 	var errors []models.ValidationError
 	var warnings []string
+	for i := range sourceColumns {
+		sourceType, targetType := describeColumnType(sourceColumns[i]), describeColumnType(targetColumns[i])
+		switch {
+		case dataTypeFamily(sourceColumns[i].DataType) != dataTypeFamily(targetColumns[i].DataType),
+			sourceColumns[i].IsArray != targetColumns[i].IsArray:
+			errors = append(errors, models.ValidationError{
+				Field: fmt.Sprintf("foreignKeys[%d].%s", index, sourceField),
+				Message: fmt.Sprintf("Column '%s.%s' (%s) cannot reference '%s.%s' (%s)",
+					sourceTable.Name, sourceColumns[i].Name, sourceType,
+					targetTable.Name, targetColumns[i].Name, targetType),
+				Code: "FOREIGN_KEY_TYPE_MISMATCH",
+			})
+		case sourceType != targetType:
+			warnings = append(warnings, fmt.Sprintf("Column '%s.%s' (%s) references '%s.%s' (%s), values are converted between the types and may not fit",
+				sourceTable.Name, sourceColumns[i].Name, sourceType,
+				targetTable.Name, targetColumns[i].Name, targetType))
+		}
+	}
 
-	// Basic validation
-	if len(request.Tables) == 0 {
+	if !isUniqueColumnSet(targetTable, targetColumns) {
+		names := make([]string, len(targetColumns))
+		for i, column := range targetColumns {
+			names[i] = column.Name
+		}
 		errors = append(errors, models.ValidationError{
-			Field:   "tables",
-			Message: "At least one table is required",
-			Code:    "MISSING_TABLES",
+			Field:   fmt.Sprintf("foreignKeys[%d].%s", index, targetField),
+			Message: fmt.Sprintf("Referenced columns '%s.(%s)' are not a primary key or unique together", targetTable.Name, strings.Join(names, ", ")),
+			Code:    "FOREIGN_KEY_TARGET_NOT_UNIQUE",
 		})
 	}
 
-	// Validate each table has at least one primary key
-	for i, table := range request.Tables {
-		hasPrimaryKey := false
+	return errors, warnings
+}
+
+// columnsByID returns the columns of table with the given IDs in order,
+// reporting false when one doesn't exist
+func columnsByID(table models.Table, ids []string) ([]models.Column, bool) {
+	columns := make([]models.Column, 0, len(ids))
+	for _, id := range ids {
+		found := false
 		for _, column := range table.Columns {
-			if column.PrimaryKey {
-				hasPrimaryKey = true
+			if column.ID == id {
+				columns = append(columns, column)
+				found = true
 				break
 			}
 		}
-		if !hasPrimaryKey {
-			warnings = append(warnings, fmt.Sprintf("Table '%s' has no primary key defined", table.Name))
+		if !found {
+			return nil, false
 		}
+	}
+	return columns, true
+}
 
-		// Validate data types
-		for j, column := range table.Columns {
-			if !models.SupportedDataTypes[column.DataType] {
-				errors = append(errors, models.ValidationError{
-					Field:   fmt.Sprintf("tables[%d].columns[%d].dataType", i, j),
-					Message: fmt.Sprintf("Unsupported data type: %s", column.DataType),
-					Code:    "UNSUPPORTED_DATA_TYPE",
-				})
+// dataTypeFamily groups data types PostgreSQL can compare in a foreign key
+func dataTypeFamily(dataType string) string {
+	switch dataType {
+	case "INT", "BIGINT":
+		return "INTEGER"
+	case "VARCHAR", "TEXT":
+		return "TEXT"
+	case "FLOAT", "DOUBLE":
+		return "FLOAT"
+	default:
+		return dataType
+	}
+}
+
+// isUniqueColumnSet reports whether columns together are guaranteed unique in
+// table: they are exactly its primary key, a unique column or the columns of
+// a non-partial unique index, in any order
+func isUniqueColumnSet(table models.Table, columns []models.Column) bool {
+	if len(columns) == 1 && columns[0].Unique {
+		return true
+	}
+
+	wanted := make(map[string]bool)
+	for _, column := range columns {
+		wanted[column.ID] = true
+	}
+
+	var primaryKey []string
+	for _, column := range table.Columns {
+		if column.PrimaryKey {
+			primaryKey = append(primaryKey, column.ID)
+		}
+	}
+	if sameColumnSet(primaryKey, wanted) {
+		return true
+	}
+
+	names := make(map[string]string)
+	for _, column := range table.Columns {
+		names[column.Name] = column.ID
+	}
+	for _, index := range table.Indexes {
+		if !index.Unique || index.Where != nil {
+			continue
+		}
+		ids := make([]string, len(index.Columns))
+		for i, column := range index.Columns {
+			ids[i] = column
+			if id, exists := names[column]; exists {
+				ids[i] = id
 			}
 		}
+		if sameColumnSet(ids, wanted) {
+			return true
+		}
 	}
 
-	return &models.ValidationResult{
-		Valid:    len(errors) == 0,
-		Errors:   errors,
-		Warnings: warnings,
-	}, nil
+	return false
+}
+
+// sameColumnSet reports whether ids holds exactly the column IDs in wanted
+func sameColumnSet(ids []string, wanted map[string]bool) bool {
+	if len(ids) != len(wanted) {
+		return false
+	}
+	for _, id := range ids {
+		if !wanted[id] {
+			return false
+		}
+	}
+	return true
 }
 
 // SQLGeneratorService implementation
@@ -273,55 +1507,120 @@ func (g *sqlGeneratorService) GenerateCreateDatabase(databaseName string) (strin
 	return fmt.Sprintf("CREATE DATABASE %s;", databaseName), nil
 }
 
+// GenerateDropStatements creates a DROP TABLE statement for every table,
+// referencing tables first. CASCADE also removes constraints and views that
+// point at a table from outside the schema definition.
+func (g *sqlGeneratorService) GenerateDropStatements(schemaData models.SchemaData) ([]string, error) {
+	schemaData = applyIdentifierCase(schemaData, identifierCase(schemaData.IdentifierCase, g.config))
+
+	tables := tableDependencyOrder(schemaData)
+	statements := make([]string, 0, len(tables))
+	for i := len(tables) - 1; i >= 0; i-- {
+		statements = append(statements, fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE;", sqlName(tables[i].Name)))
+	}
+	return statements, nil
+}
+
 func (g *sqlGeneratorService) GenerateCreateTables(schemaData models.SchemaData) ([]string, error) {
 	var statements []string
 
-	for _, table := range schemaData.Tables {
-		var columns []string
-		var primaryKeys []string
-		var uniqueConstraints []string
+	// Referenced tables come first so the script reads top to bottom
+	for _, table := range tableDependencyOrder(schemaData) {
+		statement, err := g.GenerateCreateTable(table, schemaData)
+		if err != nil {
+			return nil, err
+		}
+		comments, err := g.GenerateTableComments(table, schemaData)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, statement)
+		statements = append(statements, comments...)
+	}
 
-		// Generate column definitions
-		for _, column := range table.Columns {
-			columnDef := g.generateColumnDefinition(column)
-			columns = append(columns, columnDef)
+	return statements, nil
+}
 
-			if column.PrimaryKey {
-				primaryKeys = append(primaryKeys, column.Name)
-			}
+// GenerateCreateTable creates the CREATE TABLE statement for a single table of
+// schemaData
+func (g *sqlGeneratorService) GenerateCreateTable(table models.Table, schemaData models.SchemaData) (string, error) {
+	table = convertTableIdentifiers(table, identifierCase(schemaData.IdentifierCase, g.config))
 
-			if column.Unique && !column.PrimaryKey {
-				uniqueConstraints = append(uniqueConstraints, fmt.Sprintf("UNIQUE (%s)", column.Name))
-			}
-		}
+	var columns []string
+	var primaryKeys []string
+	var uniqueConstraints []string
 
-		// Build CREATE TABLE statement
-		statement := fmt.Sprintf("CREATE TABLE %s (\n", table.Name)
-		statement += "    " + strings.Join(columns, ",\n    ")
+	// Generate column definitions
+	for _, column := range orderedColumns(table.Columns) {
+		columnDef := g.generateColumnDefinition(column)
+		columns = append(columns, columnDef)
 
-		// Add primary key constraint
-		if len(primaryKeys) > 0 {
-			statement += fmt.Sprintf(",\n    PRIMARY KEY (%s)", strings.Join(primaryKeys, ", "))
+		if column.PrimaryKey {
+			primaryKeys = append(primaryKeys, sqlName(column.Name))
 		}
 
-		// Add unique constraints
-		for _, constraint := range uniqueConstraints {
-			statement += fmt.Sprintf(",\n    %s", constraint)
+		if column.Unique && !column.PrimaryKey {
+			uniqueConstraints = append(uniqueConstraints, fmt.Sprintf("UNIQUE (%s)", sqlName(column.Name)))
 		}
+	}
 
-		statement += "\n);"
-		statements = append(statements, statement)
+	// Build CREATE TABLE statement
+	statement := fmt.Sprintf("CREATE TABLE %s (\n", sqlName(table.Name))
+	statement += "    " + strings.Join(columns, ",\n    ")
+
+	// Add primary key constraint
+	if len(primaryKeys) > 0 {
+		statement += fmt.Sprintf(",\n    PRIMARY KEY (%s)", strings.Join(primaryKeys, ", "))
+	}
+
+	// Add unique constraints
+	for _, constraint := range uniqueConstraints {
+		statement += fmt.Sprintf(",\n    %s", constraint)
 	}
 
+	statement += "\n);"
+	return statement, nil
+}
+
+// GenerateTableComments creates the COMMENT ON statements for a table of
+// schemaData and its columns, in column order. Tables and columns without a
+// comment get none.
+func (g *sqlGeneratorService) GenerateTableComments(table models.Table, schemaData models.SchemaData) ([]string, error) {
+	table = convertTableIdentifiers(table, identifierCase(schemaData.IdentifierCase, g.config))
+
+	var statements []string
+	if table.Comment != "" {
+		statements = append(statements, fmt.Sprintf("COMMENT ON TABLE %s IS %s;", sqlName(table.Name), quoteLiteral(table.Comment)))
+	}
+	for _, column := range orderedColumns(table.Columns) {
+		if column.Comment != "" {
+			statements = append(statements, fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s;", sqlName(table.Name), sqlName(column.Name), quoteLiteral(column.Comment)))
+		}
+	}
 	return statements, nil
 }
 
 func (g *sqlGeneratorService) GenerateForeignKeys(schemaData models.SchemaData) ([]string, error) {
+	return g.generateForeignKeys(schemaData, ""), nil
+}
+
+// GenerateTableForeignKeys creates the foreign key statements originating from
+// the table with tableID. Constraint names are resolved against the whole
+// schema, so they match the ones GenerateForeignKeys produces.
+func (g *sqlGeneratorService) GenerateTableForeignKeys(tableID string, schemaData models.SchemaData) ([]string, error) {
+	return g.generateForeignKeys(schemaData, tableID), nil
+}
+
+// generateForeignKeys creates the foreign key statements of schemaData,
+// limited to those originating from sourceTableID unless it is empty
+func (g *sqlGeneratorService) generateForeignKeys(schemaData models.SchemaData, sourceTableID string) []string {
+	schemaData = applyIdentifierCase(schemaData, identifierCase(schemaData.IdentifierCase, g.config))
 	var statements []string
 
 	// First, create a map of table IDs to table names for lookup
 	tableMap := make(map[string]string)
 	columnMap := make(map[string]string)
+	usedNames := make(map[string]bool)
 
 	for _, table := range schemaData.Tables {
 		tableMap[table.ID] = table.Name
@@ -333,166 +1632,287 @@ func (g *sqlGeneratorService) GenerateForeignKeys(schemaData models.SchemaData)
 	for _, fk := range schemaData.ForeignKeys {
 		sourceTable, sourceTableExists := tableMap[fk.SourceTableId]
 		targetTable, targetTableExists := tableMap[fk.TargetTableId]
-		sourceColumn, sourceColumnExists := columnMap[fk.SourceColumnId]
-		targetColumn, targetColumnExists := columnMap[fk.TargetColumnId]
+		sourceColumns, sourceColumnsExist := columnNamesByID(fk.SourceColumns(), columnMap)
+		targetColumns, targetColumnsExist := columnNamesByID(fk.TargetColumns(), columnMap)
 
-		if !sourceTableExists || !targetTableExists || !sourceColumnExists || !targetColumnExists {
+		if !sourceTableExists || !targetTableExists || !sourceColumnsExist || !targetColumnsExist || len(sourceColumns) != len(targetColumns) {
 			continue // Skip invalid foreign keys
 		}
 
-		constraintName := fk.Name
-		if constraintName == "" {
-			constraintName = fmt.Sprintf("fk_%s_%s", sourceTable, sourceColumn)
+		baseName := fk.Name
+		if baseName == "" {
+			baseName = fmt.Sprintf("fk_%s_%s", sourceTable, strings.Join(sourceColumns, "_"))
 		}
+		constraintName := uniqueIdentifier(baseName, usedNames)
+		usedNames[constraintName] = true
 
-		onDelete := "RESTRICT"
-		if fk.OnDelete != "" && models.ValidForeignKeyActions[fk.OnDelete] {
-			onDelete = fk.OnDelete
+		if sourceTableID != "" && fk.SourceTableId != sourceTableID {
+			continue
 		}
 
-		onUpdate := "RESTRICT"
-		if fk.OnUpdate != "" && models.ValidForeignKeyActions[fk.OnUpdate] {
-			onUpdate = fk.OnUpdate
-		}
+		onDelete := effectiveForeignKeyAction(fk.OnDelete, g.config.DefaultFKOnDelete)
+		onUpdate := effectiveForeignKeyAction(fk.OnUpdate, g.config.DefaultFKOnUpdate)
 
 		statement := fmt.Sprintf(
 			"ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s) ON DELETE %s ON UPDATE %s;",
-			sourceTable,
-			constraintName,
-			sourceColumn,
-			targetTable,
-			targetColumn,
+			sqlName(sourceTable),
+			sqlName(constraintName),
+			sqlNames(sourceColumns),
+			sqlName(targetTable),
+			sqlNames(targetColumns),
 			onDelete,
 			onUpdate,
 		)
 		statements = append(statements, statement)
 	}
 
-	return statements, nil
+	return statements
+}
+
+// columnNamesByID resolves column IDs to names in order, reporting false when
+// the list is empty or a column doesn't exist
+func columnNamesByID(ids []string, columnMap map[string]string) ([]string, bool) {
+	if len(ids) == 0 {
+		return nil, false
+	}
+	names := make([]string, 0, len(ids))
+	for _, id := range ids {
+		name, exists := columnMap[id]
+		if !exists {
+			return nil, false
+		}
+		names = append(names, name)
+	}
+	return names, true
+}
+
+// shortenIdentifier keeps name within PostgreSQL's identifier limit. Long
+// names are cut and suffixed with a hash of the full name, so distinct long
+// names stay distinct after truncation.
+func shortenIdentifier(name string) string {
+	if len(name) <= models.MaxIdentifierLength {
+		return name
+	}
+	hash := sha1.Sum([]byte(name))
+	suffix := "_" + hex.EncodeToString(hash[:])[:8]
+	return name[:models.MaxIdentifierLength-len(suffix)] + suffix
+}
+
+// uniqueIdentifier returns a shortened form of base that is not in used,
+// appending a numeric suffix on collision
+func uniqueIdentifier(base string, used map[string]bool) string {
+	name := shortenIdentifier(base)
+	for i := 2; used[name]; i++ {
+		name = shortenIdentifier(fmt.Sprintf("%s_%d", base, i))
+	}
+	return name
+}
+
+// orderedColumns returns the columns sorted by their explicit Order. Columns
+// without an Order keep their array order and follow the ordered ones, so the
+// generated DDL does not depend on how the client serialized the array.
+func orderedColumns(columns []models.Column) []models.Column {
+	ordered := make([]models.Column, len(columns))
+	copy(ordered, columns)
+
+	sort.SliceStable(ordered, func(i, j int) bool {
+		a, b := ordered[i].Order, ordered[j].Order
+		if a == 0 || b == 0 {
+			return a != 0 && b == 0
+		}
+		return a < b
+	})
+
+	return ordered
 }
 
 // generateColumnDefinition creates SQL column definition from column model
 func (g *sqlGeneratorService) generateColumnDefinition(column models.Column) string {
 	var def strings.Builder
 
-	def.WriteString(column.Name)
+	def.WriteString(sqlName(column.Name))
 	def.WriteString(" ")
 
-	// Data type mapping
+	def.WriteString(postgresColumnType(column))
+
+	// Nullable constraint
+	if !column.IsNullable() {
+		def.WriteString(" NOT NULL")
+	}
+
+	// Default value
+	if defaultValue := columnDefault(column); defaultValue != "" {
+		def.WriteString(" DEFAULT " + defaultValue)
+	}
+
+	// Check constraint
+	if column.Check != nil {
+		def.WriteString(" CHECK (" + strings.TrimSpace(*column.Check) + ")")
+	}
+
+	return def.String()
+}
+
+// columnDefault returns the DEFAULT expression generated for a column, or an
+// empty string when it has none
+func columnDefault(column models.Column) string {
+	switch {
+	case column.DefaultExpression != "":
+		return column.DefaultExpression
+	case column.IsArray:
+		// Element type defaults don't apply to arrays
+		return arrayDefault(column.DefaultValue)
+	case column.DefaultValue != nil:
+		switch v := column.DefaultValue.(type) {
+		case string:
+			if function := defaultFunction(v); function != "" {
+				return function
+			}
+			if v != "" {
+				return quoteLiteral(v)
+			}
+		case bool:
+			return fmt.Sprintf("%t", v)
+		case float64:
+			return fmt.Sprintf("%v", v)
+		}
+	case column.DataType == "UUID":
+		// UUID default for UUID columns
+		return "gen_random_uuid()"
+	case column.DataType == "TIMESTAMP":
+		// Timestamp defaults
+		return "CURRENT_TIMESTAMP"
+	}
+	return ""
+}
+
+// arrayDefault renders the default of an array column as an array literal
+// such as '{}' or '{"a","b"}'. A string is taken as a literal already in
+// that form, a JSON array is converted element by element.
+func arrayDefault(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		if v != "" {
+			return quoteLiteral(v)
+		}
+	case []interface{}:
+		elements := make([]string, len(v))
+		for i, element := range v {
+			switch e := element.(type) {
+			case nil:
+				elements[i] = "NULL"
+			case string:
+				elements[i] = `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(e) + `"`
+			default:
+				elements[i] = fmt.Sprintf("%v", e)
+			}
+		}
+		return quoteLiteral("{" + strings.Join(elements, ",") + "}")
+	}
+	return ""
+}
+
+// postgresColumnType maps a column data type to the PostgreSQL type it is
+// generated as. Unknown types fall back to TEXT.
+func postgresColumnType(column models.Column) string {
+	if column.IsArray {
+		element := column
+		element.IsArray = false
+		return postgresColumnType(element) + "[]"
+	}
+
 	switch column.DataType {
 	case "INT":
 		if column.AutoIncrement {
-			def.WriteString("SERIAL")
-		} else {
-			def.WriteString("INTEGER")
+			return "SERIAL"
 		}
+		return "INTEGER"
 	case "BIGINT":
 		if column.AutoIncrement {
-			def.WriteString("BIGSERIAL")
-		} else {
-			def.WriteString("BIGINT")
+			return "BIGSERIAL"
 		}
-	case "VARCHAR":
-		length := 255
-		if column.Length != nil && *column.Length > 0 {
-			length = *column.Length
-		}
-		def.WriteString(fmt.Sprintf("VARCHAR(%d)", length))
+		return "BIGINT"
+	case "VARCHAR", "DECIMAL":
+		return describeColumnType(column)
 	case "TEXT":
-		def.WriteString("TEXT")
+		return "TEXT"
 	case "BOOLEAN":
-		def.WriteString("BOOLEAN")
+		return "BOOLEAN"
 	case "TIMESTAMP":
-		def.WriteString("TIMESTAMP WITH TIME ZONE")
+		return "TIMESTAMP WITH TIME ZONE"
 	case "DATE":
-		def.WriteString("DATE")
+		return "DATE"
 	case "TIME":
-		def.WriteString("TIME")
-	case "DECIMAL":
-		precision := 10
-		scale := 2
-		if column.Precision != nil {
-			precision = *column.Precision
-		}
-		if column.Scale != nil {
-			scale = *column.Scale
-		}
-		def.WriteString(fmt.Sprintf("DECIMAL(%d,%d)", precision, scale))
+		return "TIME"
 	case "FLOAT":
-		def.WriteString("REAL")
+		return "REAL"
 	case "DOUBLE":
-		def.WriteString("DOUBLE PRECISION")
+		return "DOUBLE PRECISION"
 	case "JSON":
-		def.WriteString("JSONB")
+		return "JSONB"
 	case "UUID":
-		def.WriteString("UUID")
+		return "UUID"
 	default:
-		def.WriteString("TEXT") // Fallback
+		return "TEXT" // Fallback
 	}
+}
 
-	// Nullable constraint
-	if !column.Nullable {
-		def.WriteString(" NOT NULL")
-	}
+// DatabaseManagerService implementation
+func (d *databaseManagerService) CreateDatabase(databaseName string) error {
+	return d.createDatabase(context.Background(), databaseName)
+}
 
-	// Default value
-	if column.DefaultValue != nil {
-		switch v := column.DefaultValue.(type) {
-		case string:
-			if v != "" {
-				def.WriteString(fmt.Sprintf(" DEFAULT '%s'", v))
-			}
-		case bool:
-			def.WriteString(fmt.Sprintf(" DEFAULT %t", v))
-		case float64:
-			def.WriteString(fmt.Sprintf(" DEFAULT %v", v))
-		}
-	}
+func (d *databaseManagerService) DropDatabase(databaseName string) error {
+	return d.dropDatabase(context.Background(), databaseName)
+}
 
-	// UUID default for UUID columns
-	if column.DataType == "UUID" && column.DefaultValue == nil {
-		def.WriteString(" DEFAULT gen_random_uuid()")
-	}
+// createDatabase creates a database as a child span of ctx
+func (d *databaseManagerService) createDatabase(ctx context.Context, databaseName string) error {
+	return config.CreateDynamicDatabase(ctx, d.config, databaseName)
+}
 
-	// Timestamp defaults
-	if column.DataType == "TIMESTAMP" && column.DefaultValue == nil {
-		def.WriteString(" DEFAULT CURRENT_TIMESTAMP")
-	}
+// dropDatabase drops a database as a child span of ctx
+func (d *databaseManagerService) dropDatabase(ctx context.Context, databaseName string) error {
+	d.connections.evict(databaseName)
+	return config.DropDynamicDatabase(ctx, d.config, databaseName)
+}
 
-	return def.String()
+func (d *databaseManagerService) RenameDatabase(oldName, newName string) error {
+	d.connections.evict(oldName)
+	return config.RenameDynamicDatabase(d.config, oldName, newName)
 }
 
-// DatabaseManagerService implementation
-func (d *databaseManagerService) CreateDatabase(databaseName string) error {
-	return config.CreateDynamicDatabase(d.config, databaseName)
+// DatabaseExists reports whether the generated database databaseName exists
+func (d *databaseManagerService) DatabaseExists(databaseName string) (bool, error) {
+	return config.DynamicDatabaseExists(context.Background(), d.config, databaseName)
 }
 
-func (d *databaseManagerService) DropDatabase(databaseName string) error {
-	return config.DropDynamicDatabase(d.config, databaseName)
+// LockGeneration takes the generation lock of a schema, returning
+// ErrGenerationInProgress when another request holds it. The lock is keyed by
+// schema ID rather than database name so it survives database renames.
+func (d *databaseManagerService) LockGeneration(schemaID uuid.UUID) (func(), error) {
+	unlock, err := config.TryAdvisoryLock(context.Background(), d.config, "schema_generation:"+schemaID.String())
+	if errors.Is(err, config.ErrLockHeld) {
+		return nil, fmt.Errorf("%w: schema %s", ErrGenerationInProgress, schemaID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock schema generation: %w", err)
+	}
+	return unlock, nil
 }
 
 func (d *databaseManagerService) GetDatabaseStatus(databaseName string) (*models.DatabaseStatus, error) {
 	// Connect to the user's database to check status
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		d.config.DatabaseHost,
-		d.config.DatabasePort,
-		d.config.DatabaseUser,
-		d.config.DatabasePass,
-		databaseName,
-	)
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Silent),
-	})
+	db, release, err := d.openDatabase(databaseName, logger.Silent)
 	if err != nil {
 		return &models.DatabaseStatus{
 			DatabaseName: databaseName,
 			Status:       "error",
 			TableCount:   0,
-			LastChecked:  time.Now(),
+			LastChecked:  time.Now().UTC(),
 		}, nil
 	}
+	defer release()
 
 	// Count tables
 	var tableCount int64
@@ -513,67 +1933,245 @@ func (d *databaseManagerService) GetDatabaseStatus(databaseName string) (*models
 		DatabaseName:     databaseName,
 		Status:           "healthy",
 		TableCount:       int(tableCount),
-		LastChecked:      time.Now(),
+		LastChecked:      time.Now().UTC(),
 		ConnectionString: connectionString,
 	}, nil
 }
 
-func (d *databaseManagerService) RegenerateDatabase(schemaData models.SchemaData, databaseName string) error {
-	// Create SQL generator
-	sqlGen := &sqlGeneratorService{}
+func (d *databaseManagerService) RegenerateDatabase(ctx context.Context, schemaData models.SchemaData, databaseName string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "database.regenerate", trace.WithAttributes(
+		attribute.String("db.name", databaseName),
+		attribute.Int("schema.table_count", len(schemaData.Tables)),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	// Generate every statement before touching the existing database
+	sqlGen := &sqlGeneratorService{config: d.config}
+	statements, err := sqlGen.generationStatements(schemaData)
+	if err != nil {
+		return err
+	}
+
+	span.SetAttributes(attribute.Int("db.statement_count", len(statements)))
 
 	// Drop existing database
-	if err := d.DropDatabase(databaseName); err != nil {
+	if err := d.dropDatabase(ctx, databaseName); err != nil {
 		// Ignore error if database doesn't exist
 		log.Printf("Warning: Failed to drop database %s: %v", databaseName, err)
 	}
 
-	// Create new database
-	if err := d.CreateDatabase(databaseName); err != nil {
+	// Create new database. CREATE DATABASE cannot run inside a transaction.
+	if err := d.createDatabase(ctx, databaseName); err != nil {
 		return fmt.Errorf("failed to create database: %w", err)
 	}
 
 	// Connect to the new database
-	dsn := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		d.config.DatabaseHost,
-		d.config.DatabasePort,
-		d.config.DatabaseUser,
-		d.config.DatabasePass,
-		databaseName,
-	)
-
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{
-		Logger: logger.Default.LogMode(logger.Info),
-	})
+	db, release, err := d.openDatabase(databaseName, logger.Info)
 	if err != nil {
 		return fmt.Errorf("failed to connect to new database: %w", err)
 	}
+	defer release()
+
+	if err := execStatements(ctx, db, statements); err != nil {
+		return err
+	}
+
+	log.Printf("Successfully regenerated database %s with %d tables", databaseName, len(schemaData.Tables))
+	return nil
+}
+
+// execStatements runs statements in a single transaction. PostgreSQL DDL is
+// transactional, so a failing statement rolls back every table, index and
+// constraint created before it.
+func execStatements(ctx context.Context, db *gorm.DB, statements []tableStatement) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := execTraced(ctx, tx, statement.kind, statement.sql); err != nil {
+				return newStatementError(statement.kind, statement.table, statement.sql, err)
+			}
+		}
+		return nil
+	})
+}
+
+// tableStatement is a DDL statement generating part of a table
+type tableStatement struct {
+	kind  string
+	table models.Table
+	sql   string
+}
+
+// generationStatements returns the statements building a database for
+// schemaData, each with the table it belongs to: the tables and their
+// comments in dependency order, then indexes, foreign keys and foreign key
+// indexes
+func (g *sqlGeneratorService) generationStatements(schemaData models.SchemaData) ([]tableStatement, error) {
+	var statements []tableStatement
+	add := func(kind string, table models.Table, sqls []string) {
+		for _, sql := range sqls {
+			statements = append(statements, tableStatement{kind: kind, table: table, sql: sql})
+		}
+	}
+
+	for _, table := range tableDependencyOrder(schemaData) {
+		createTable, err := g.GenerateCreateTable(table, schemaData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate table statements: %w", err)
+		}
+		comments, err := g.GenerateTableComments(table, schemaData)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate table statements: %w", err)
+		}
+		add("table", table, append([]string{createTable}, comments...))
+	}
+	for _, table := range schemaData.Tables {
+		add("index", table, g.generateIndexes(schemaData, table.ID))
+	}
+	for _, table := range schemaData.Tables {
+		add("foreign key", table, g.generateForeignKeys(schemaData, table.ID))
+	}
+	for _, table := range schemaData.Tables {
+		add("foreign key index", table, g.generateForeignKeyIndexes(schemaData, table.ID))
+	}
+
+	return statements, nil
+}
+
+// RebuildForeignKeys drops every foreign key of a generated database and adds
+// the ones of schemaData again. A constraint that can't be added, e.g. because
+// existing rows violate it, is returned as a StatementError.
+func (d *databaseManagerService) RebuildForeignKeys(schemaData models.SchemaData, databaseName string) error {
+	sqlGen := &sqlGeneratorService{config: d.config}
+
+	type tableStatements struct {
+		table      models.Table
+		statements []string
+	}
+	var foreignKeys []tableStatements
+	count := 0
+	for _, table := range schemaData.Tables {
+		statements, err := sqlGen.GenerateTableForeignKeys(table.ID, schemaData)
+		if err != nil {
+			return fmt.Errorf("failed to generate foreign key statements: %w", err)
+		}
+		foreignKeys = append(foreignKeys, tableStatements{table: table, statements: statements})
+		count += len(statements)
+	}
 
-	// Generate and execute table creation statements
-	tableStatements, err := sqlGen.GenerateCreateTables(schemaData)
+	db, release, err := d.openDatabase(databaseName, logger.Silent)
 	if err != nil {
-		return fmt.Errorf("failed to generate table statements: %w", err)
+		return fmt.Errorf("failed to connect to database: %w", err)
 	}
+	defer release()
 
-	for _, statement := range tableStatements {
-		if err := db.Exec(statement).Error; err != nil {
-			return fmt.Errorf("failed to execute table statement: %w\nStatement: %s", err, statement)
+	// Drop and re-add every constraint atomically so table data is never left
+	// without its relationships
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := dropForeignKeyConstraints(tx); err != nil {
+			return err
 		}
+
+		for _, foreignKey := range foreignKeys {
+			for _, statement := range foreignKey.statements {
+				if err := tx.Exec(statement).Error; err != nil {
+					return newStatementError("foreign key", foreignKey.table, statement, err)
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
 	}
 
-	// Generate and execute foreign key statements
-	fkStatements, err := sqlGen.GenerateForeignKeys(schemaData)
+	log.Printf("Successfully rebuilt %d foreign keys in database %s", count, databaseName)
+	return nil
+}
+
+// dropForeignKeyConstraints drops every foreign key in the public schema of
+// the database tx is connected to
+func dropForeignKeyConstraints(tx *gorm.DB) error {
+	var constraints []struct {
+		TableName      string
+		ConstraintName string
+	}
+	err := tx.Raw(`SELECT table_name, constraint_name FROM information_schema.table_constraints
+		WHERE constraint_type = 'FOREIGN KEY' AND table_schema = 'public'`).Scan(&constraints).Error
 	if err != nil {
-		return fmt.Errorf("failed to generate foreign key statements: %w", err)
+		return fmt.Errorf("failed to list foreign keys: %w", err)
 	}
 
-	for _, statement := range fkStatements {
-		if err := db.Exec(statement).Error; err != nil {
-			return fmt.Errorf("failed to execute foreign key statement: %w\nStatement: %s", err, statement)
+	for _, constraint := range constraints {
+		statement := fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;",
+			quoteIdentifier(constraint.TableName), quoteIdentifier(constraint.ConstraintName))
+		if err := tx.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to drop foreign key: %w\nStatement: %s", err, statement)
 		}
 	}
+	return nil
+}
 
-	log.Printf("Successfully regenerated database %s with %d tables", databaseName, len(schemaData.Tables))
+// openDatabase returns a connection to a generated database, reusing the
+// cached pool of the database when there is one. Callers must call release
+// once they are done with the connection.
+func (d *databaseManagerService) openDatabase(databaseName string, logLevel logger.LogLevel) (db *gorm.DB, release func(), err error) {
+	db, release, err = d.connections.acquire(databaseName, func() (*gorm.DB, *sql.DB, error) {
+		db, err := gorm.Open(postgres.Open(config.BuildDSN(d.config, databaseName)), &gorm.Config{
+			Logger: logger.Default.LogMode(logger.Silent),
+			NowFunc: func() time.Time {
+				return time.Now().UTC()
+			},
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, nil, err
+		}
+		sqlDB.SetMaxIdleConns(d.config.DynamicDBMaxIdleConns)
+		sqlDB.SetMaxOpenConns(d.config.DynamicDBMaxOpenConns)
+		sqlDB.SetConnMaxIdleTime(time.Duration(d.config.DynamicDBIdleTimeout) * time.Second)
+		return db, sqlDB, nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// The pool is shared, so the log level is set per session
+	return db.Session(&gorm.Session{Logger: logger.Default.LogMode(logLevel)}), release, nil
+}
+
+// CheckConnections pings the cached connection pools to generated databases.
+// Databases without an open pool aren't checked.
+func (d *databaseManagerService) CheckConnections(ctx context.Context) []models.DatabaseConnectionStatus {
+	return d.connections.ping(ctx)
+}
+
+// PoolStats sums the connection pools open to generated databases
+func (d *databaseManagerService) PoolStats() models.DatabasePoolStats {
+	return d.connections.stats()
+}
+
+// execTraced executes a DDL statement in a span of its own
+func execTraced(ctx context.Context, tx *gorm.DB, kind, statement string) (err error) {
+	ctx, span := tracing.Tracer().Start(ctx, "database.exec", trace.WithAttributes(
+		attribute.String("db.statement_kind", kind),
+		attribute.String("db.statement", statement),
+	))
+	defer func() { tracing.End(span, err) }()
+
+	return tx.WithContext(ctx).Exec(statement).Error
+}
+
+// Close closes the cached connections to generated databases
+func (d *databaseManagerService) Close() error {
+	d.connections.close()
 	return nil
 }
+
+// quoteIdentifier quotes a PostgreSQL identifier, escaping embedded quotes
+func quoteIdentifier(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
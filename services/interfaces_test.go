@@ -0,0 +1,703 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"testing"
+
+	"vdt-dashboard-backend/config"
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+)
+
+func TestGenerateCreateTablesColumnOrder(t *testing.T) {
+	generator := NewSQLGeneratorService(&config.Config{})
+	columns := []models.Column{
+		{ID: "c1", Name: "id", DataType: "INT", PrimaryKey: true, Order: 1},
+		{ID: "c2", Name: "email", DataType: "VARCHAR", Order: 2},
+		{ID: "c3", Name: "name", DataType: "TEXT", Order: 3},
+		{ID: "c4", Name: "created_at", DataType: "TIMESTAMP", Order: 4},
+		{ID: "c5", Name: "active", DataType: "BOOLEAN", Order: 5},
+	}
+	generate := func(columns []models.Column) string {
+		statements, err := generator.GenerateCreateTables(models.SchemaData{
+			Tables: []models.Table{{ID: "t1", Name: "users", Columns: columns}},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.Join(statements, "\n")
+	}
+
+	want := generate(columns)
+	random := rand.New(rand.NewSource(1))
+	for i := 0; i < 20; i++ {
+		shuffled := make([]models.Column, len(columns))
+		for j, k := range random.Perm(len(columns)) {
+			shuffled[j] = columns[k]
+		}
+		if got := generate(shuffled); got != want {
+			t.Fatalf("shuffled columns generated\n%s\nwant\n%s", got, want)
+		}
+	}
+}
+
+func TestGenerateForeignKeysSameSourceColumn(t *testing.T) {
+	generator := NewSQLGeneratorService(&config.Config{DefaultFKOnDelete: "NO ACTION", DefaultFKOnUpdate: "NO ACTION"})
+	schemaData := models.SchemaData{
+		Tables: []models.Table{
+			{ID: "t1", Name: "orders", Columns: []models.Column{{ID: "c1", Name: "owner_id", DataType: "INT"}}},
+			{ID: "t2", Name: "users", Columns: []models.Column{{ID: "c2", Name: "id", DataType: "INT", PrimaryKey: true}}},
+			{ID: "t3", Name: "accounts", Columns: []models.Column{{ID: "c3", Name: "id", DataType: "INT", PrimaryKey: true}}},
+		},
+		ForeignKeys: []models.ForeignKey{
+			{ID: "fk1", SourceTableId: "t1", SourceColumnId: "c1", TargetTableId: "t2", TargetColumnId: "c2"},
+			{ID: "fk2", SourceTableId: "t1", SourceColumnId: "c1", TargetTableId: "t3", TargetColumnId: "c3"},
+		},
+	}
+
+	statements, err := generator.GenerateForeignKeys(schemaData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(statements) != 2 {
+		t.Fatalf("got %d statements, want 2: %v", len(statements), statements)
+	}
+	for i, name := range []string{"fk_orders_owner_id", "fk_orders_owner_id_2"} {
+		if !strings.Contains(statements[i], "ADD CONSTRAINT "+sqlName(name)+" ") {
+			t.Errorf("statement %d = %q, want constraint %s", i, statements[i], name)
+		}
+	}
+}
+
+func TestGenerateColumnDefinitionNullable(t *testing.T) {
+	generator := &sqlGeneratorService{config: &config.Config{}}
+	tests := []struct {
+		name        string
+		column      string
+		wantNotNull bool
+	}{
+		{name: "nullable omitted", column: `{"id":"c1","name":"bio","dataType":"TEXT"}`, wantNotNull: false},
+		{name: "nullable true", column: `{"id":"c1","name":"bio","dataType":"TEXT","nullable":true}`, wantNotNull: false},
+		{name: "nullable false", column: `{"id":"c1","name":"bio","dataType":"TEXT","nullable":false}`, wantNotNull: true},
+		{name: "primary key with nullable omitted", column: `{"id":"c1","name":"id","dataType":"INTEGER","primaryKey":true}`, wantNotNull: true},
+		{name: "primary key with nullable true", column: `{"id":"c1","name":"id","dataType":"INTEGER","primaryKey":true,"nullable":true}`, wantNotNull: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var column models.Column
+			if err := json.Unmarshal([]byte(test.column), &column); err != nil {
+				t.Fatal(err)
+			}
+
+			definition := generator.generateColumnDefinition(column)
+			if got := strings.Contains(definition, "NOT NULL"); got != test.wantNotNull {
+				t.Fatalf("definition = %q, want NOT NULL %v", definition, test.wantNotNull)
+			}
+			if column.IsNullable() == test.wantNotNull {
+				t.Fatalf("IsNullable() = %v, want %v", column.IsNullable(), !test.wantNotNull)
+			}
+		})
+	}
+}
+
+func TestUpdateSchemaRenameRolledBackWhenSaveFails(t *testing.T) {
+	userID := uuid.New()
+	schema := models.Schema{ID: uuid.New(), Name: "shop", DatabaseName: "schema_shop", UserID: userID, Status: models.SchemaStatusCreated}
+	repo := newFakeSchemaRepository(schema)
+	repo.updateErr = errors.New("connection reset")
+	manager := newFakeDatabaseManager(schema.DatabaseName)
+	service := newTestSchemaService(t, repo, manager)
+
+	_, err := service.UpdateSchema(context.Background(), schema.ID, userID, models.UpdateSchemaRequest{
+		Name:                   "store",
+		Tables:                 testTables(),
+		RenamePhysicalDatabase: true,
+	})
+	if err == nil {
+		t.Fatal("UpdateSchema() = nil, want the save error")
+	}
+	if !strings.Contains(err.Error(), "connection reset") {
+		t.Fatalf("UpdateSchema() = %v, want the save error", err)
+	}
+
+	want := [][2]string{{"schema_shop", "schema_store"}, {"schema_store", "schema_shop"}}
+	if len(manager.renames) != len(want) || manager.renames[0] != want[0] || manager.renames[1] != want[1] {
+		t.Fatalf("renames = %v, want %v", manager.renames, want)
+	}
+	if !manager.databases["schema_shop"] {
+		t.Fatal("database schema_shop no longer exists")
+	}
+}
+
+func TestUpdateSchemaReadsSchemaUnderLock(t *testing.T) {
+	userID := uuid.New()
+	schema := models.Schema{ID: uuid.New(), Name: "shop", DatabaseName: "schema_shop", UserID: userID, Status: models.SchemaStatusCreated, Version: 1}
+	repo := newFakeSchemaRepository(schema)
+	manager := newFakeDatabaseManager(schema.DatabaseName)
+	// Another update is saved while this one waits for the lock
+	manager.onLock = func(schemaID uuid.UUID) {
+		concurrent := schema
+		concurrent.Version = 2
+		concurrent.Description = "saved concurrently"
+		repo.Update(&concurrent)
+	}
+	service := newTestSchemaService(t, repo, manager)
+
+	updated, err := service.UpdateSchema(context.Background(), schema.ID, userID, models.UpdateSchemaRequest{
+		Name:        "shop",
+		Description: "latest",
+		Tables:      testTables(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Version != 3 {
+		t.Fatalf("version = %d, want 3", updated.Version)
+	}
+}
+
+func TestRegenerateDatabaseWhileGenerating(t *testing.T) {
+	userID := uuid.New()
+	schema := models.Schema{ID: uuid.New(), Name: "shop", DatabaseName: "schema_shop", UserID: userID, Status: models.SchemaStatusUpdating, Version: 2}
+	repo := newFakeSchemaRepository(schema)
+	manager := newFakeDatabaseManager(schema.DatabaseName)
+	manager.lockHeld = 1
+	service := newTestSchemaService(t, repo, manager)
+
+	if _, err := service.RegenerateDatabase(context.Background(), schema.ID, userID); !errors.Is(err, ErrGenerationInProgress) {
+		t.Fatalf("RegenerateDatabase() = %v, want ErrGenerationInProgress", err)
+	}
+	if repo.updates != 0 {
+		t.Fatalf("schema saved %d times while an update held the lock, want 0", repo.updates)
+	}
+}
+
+func TestUpdateSchemaWithPendingGeneration(t *testing.T) {
+	tests := []struct {
+		status       models.SchemaStatus
+		wantStatus   models.SchemaStatus
+		wantMigrated bool
+	}{
+		{status: models.SchemaStatusCreating, wantStatus: models.SchemaStatusCreating},
+		{status: models.SchemaStatusRegenerating, wantStatus: models.SchemaStatusRegenerating},
+		{status: models.SchemaStatusCreated, wantStatus: models.SchemaStatusUpdated, wantMigrated: true},
+	}
+
+	for _, test := range tests {
+		t.Run(string(test.status), func(t *testing.T) {
+			userID := uuid.New()
+			schema := models.Schema{ID: uuid.New(), Name: "shop", DatabaseName: "schema_shop", UserID: userID, Status: test.status, Version: 1}
+			repo := newFakeSchemaRepository(schema)
+			manager := newFakeDatabaseManager()
+			service := newTestSchemaService(t, repo, manager)
+
+			updated, err := service.UpdateSchema(context.Background(), schema.ID, userID, models.UpdateSchemaRequest{Name: "shop", Tables: testTables()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if updated.Status != test.wantStatus {
+				t.Fatalf("status = %s, want %s", updated.Status, test.wantStatus)
+			}
+			if got := len(manager.migrated) > 0; got != test.wantMigrated {
+				t.Fatalf("migrated = %v, want migrated %v", manager.migrated, test.wantMigrated)
+			}
+
+			// The queued job builds the database from the definition stored here
+			stored, err := repo.GetByID(schema.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Version != 2 || len(stored.SchemaDefinition.Tables) != 1 {
+				t.Fatalf("stored version %d with %d tables, want the update stored", stored.Version, len(stored.SchemaDefinition.Tables))
+			}
+		})
+	}
+}
+
+func TestUpdateSchemaIncrementsVersion(t *testing.T) {
+	userID := uuid.New()
+	schema := models.Schema{ID: uuid.New(), Name: "shop", DatabaseName: "schema_shop", UserID: userID, Status: models.SchemaStatusCreated, Version: 1}
+	repo := newFakeSchemaRepository(schema)
+	service := newTestSchemaService(t, repo, newFakeDatabaseManager(schema.DatabaseName))
+
+	for want := 2; want <= 4; want++ {
+		updated, err := service.UpdateSchema(context.Background(), schema.ID, userID, models.UpdateSchemaRequest{
+			Name:        "shop",
+			Description: fmt.Sprintf("edit %d", want),
+			Tables:      testTables(),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if updated.Version != want {
+			t.Fatalf("version = %d, want %d", updated.Version, want)
+		}
+		if got := updated.SchemaDefinition.Version; got != strconv.Itoa(want) {
+			t.Fatalf("definition version = %q, want %q", got, strconv.Itoa(want))
+		}
+	}
+
+	stored, err := repo.GetByID(schema.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Version != 4 {
+		t.Fatalf("stored version = %d, want 4", stored.Version)
+	}
+	if versions := service.versions.(*fakeSchemaVersionRepository).versions; len(versions) != 3 {
+		t.Fatalf("recorded %d versions, want 3", len(versions))
+	}
+}
+
+// validateTables runs the validator over tables with the default config
+func validateTables(t *testing.T, tables []models.Table) *models.ValidationResult {
+	t.Helper()
+
+	result, err := NewValidatorService(&config.Config{}).ValidateSchema(models.SchemaValidationRequest{Name: "test", Tables: tables})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result
+}
+
+// hasValidationError reports whether result has an error with code on field
+func hasValidationError(result *models.ValidationResult, code, field string) bool {
+	for _, err := range result.Errors {
+		if err.Code == code && err.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+func TestValidateSchemaDuplicateNames(t *testing.T) {
+	table := func(id, name string, columns ...string) models.Table {
+		table := models.Table{ID: id, Name: name}
+		for i, column := range columns {
+			table.Columns = append(table.Columns, models.Column{ID: fmt.Sprintf("%s_c%d", id, i), Name: column, DataType: "INT", PrimaryKey: i == 0})
+		}
+		return table
+	}
+
+	tests := []struct {
+		name   string
+		tables []models.Table
+		code   string
+		field  string
+	}{
+		{
+			name:   "exact duplicate column",
+			tables: []models.Table{table("t1", "users", "id", "email", "email")},
+			code:   "DUPLICATE_COLUMN_NAME",
+			field:  "tables[0].columns[2].name",
+		},
+		{
+			name:   "duplicate column differing in case",
+			tables: []models.Table{table("t1", "users", "id", "Email", "email")},
+			code:   "DUPLICATE_COLUMN_NAME",
+			field:  "tables[0].columns[2].name",
+		},
+		{
+			name:   "exact duplicate table",
+			tables: []models.Table{table("t1", "users", "id"), table("t2", "users", "id")},
+			code:   "DUPLICATE_TABLE_NAME",
+			field:  "tables[1].name",
+		},
+		{
+			name:   "duplicate table differing in case",
+			tables: []models.Table{table("t1", "Users", "id"), table("t2", "users", "id")},
+			code:   "DUPLICATE_TABLE_NAME",
+			field:  "tables[1].name",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := validateTables(t, test.tables)
+			if result.Valid || !hasValidationError(result, test.code, test.field) {
+				t.Fatalf("errors = %+v, want %s on %s", result.Errors, test.code, test.field)
+			}
+		})
+	}
+
+	if result := validateTables(t, []models.Table{table("t1", "users", "id", "email"), table("t2", "posts", "id", "email")}); !result.Valid {
+		t.Fatalf("same column name in different tables: errors = %+v, want none", result.Errors)
+	}
+}
+
+func TestValidateSchemaConvertedNames(t *testing.T) {
+	validate := func(columns ...string) *models.ValidationResult {
+		table := models.Table{ID: "t1", Name: "users"}
+		for i, column := range columns {
+			table.Columns = append(table.Columns, models.Column{ID: fmt.Sprintf("c%d", i), Name: column, DataType: "INT", PrimaryKey: i == 0})
+		}
+		result, err := NewValidatorService(&config.Config{}).ValidateSchema(models.SchemaValidationRequest{
+			Name:           "test",
+			Tables:         []models.Table{table},
+			IdentifierCase: models.IdentifierCaseSnake,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	if result := validate("id", "firstName", "first_name"); result.Valid || !hasValidationError(result, "DUPLICATE_COLUMN_NAME", "tables[0].columns[2].name") {
+		t.Fatalf("firstName and first_name: errors = %+v, want DUPLICATE_COLUMN_NAME", result.Errors)
+	}
+
+	// 60 bytes as written, 70 once an underscore is inserted before each capital
+	long := strings.Repeat("aaaaaA", 10)
+	if result := validate("id", long); result.Valid || !hasValidationError(result, "IDENTIFIER_TOO_LONG", "tables[0].columns[1].name") {
+		t.Fatalf("%s: errors = %+v, want IDENTIFIER_TOO_LONG", long, result.Errors)
+	}
+}
+
+func TestValidateCompositePrimaryKey(t *testing.T) {
+	orderItems := models.Table{ID: "t1", Name: "order_items", Columns: []models.Column{
+		{ID: "c1", Name: "order_id", DataType: "INT", PrimaryKey: true, AutoIncrement: true},
+		{ID: "c2", Name: "line", DataType: "INT", PrimaryKey: true, AutoIncrement: true},
+	}}
+	result := validateTables(t, []models.Table{orderItems})
+	if result.Valid || !hasValidationError(result, "MULTIPLE_AUTO_INCREMENT", "tables[0].columns") {
+		t.Fatalf("two auto-increment key columns: errors = %+v, want MULTIPLE_AUTO_INCREMENT", result.Errors)
+	}
+
+	orderItems.Columns[1].AutoIncrement = false
+	if result := validateTables(t, []models.Table{orderItems}); !result.Valid {
+		t.Fatalf("one auto-increment key column: errors = %+v, want none", result.Errors)
+	}
+}
+
+func TestValidateForeignKeyCompositeTarget(t *testing.T) {
+	tables := []models.Table{
+		{ID: "t1", Name: "order_items", Columns: []models.Column{
+			{ID: "c1", Name: "order_id", DataType: "INT", PrimaryKey: true},
+			{ID: "c2", Name: "line", DataType: "INT", PrimaryKey: true},
+		}},
+		{ID: "t2", Name: "shipments", Columns: []models.Column{
+			{ID: "c3", Name: "id", DataType: "INT", PrimaryKey: true},
+			{ID: "c4", Name: "order_id", DataType: "INT"},
+			{ID: "c5", Name: "line", DataType: "INT"},
+		}},
+	}
+
+	tests := []struct {
+		name   string
+		source []string
+		target []string
+		valid  bool
+	}{
+		{name: "one column of the key", source: []string{"c4"}, target: []string{"c1"}},
+		{name: "whole key", source: []string{"c4", "c5"}, target: []string{"c1", "c2"}, valid: true},
+		{name: "whole key in another order", source: []string{"c5", "c4"}, target: []string{"c2", "c1"}, valid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := NewValidatorService(&config.Config{}).ValidateSchema(models.SchemaValidationRequest{
+				Name:   "test",
+				Tables: tables,
+				ForeignKeys: []models.ForeignKey{{
+					ID:              "fk1",
+					SourceTableId:   "t2",
+					SourceColumnIds: test.source,
+					TargetTableId:   "t1",
+					TargetColumnIds: test.target,
+				}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.valid {
+				if !result.Valid {
+					t.Fatalf("errors = %+v, want none", result.Errors)
+				}
+				return
+			}
+			if result.Valid || !hasValidationError(result, "FOREIGN_KEY_TARGET_NOT_UNIQUE", "foreignKeys[0].targetColumnIds") {
+				t.Fatalf("errors = %+v, want FOREIGN_KEY_TARGET_NOT_UNIQUE", result.Errors)
+			}
+		})
+	}
+}
+
+func TestExecStatementsRollsBackOnFailure(t *testing.T) {
+	db := openTestDatabase(t)
+	users := models.Table{ID: "t1", Name: "users"}
+	posts := models.Table{ID: "t2", Name: "posts"}
+	statements := []tableStatement{
+		{kind: "table", table: users, sql: `CREATE TABLE "users" ("id" INTEGER PRIMARY KEY)`},
+		{kind: "table", table: posts, sql: `CREATE TABLE "posts" ("id" INTEGER PRIMARY KEY, "user_id" INTEGER)`},
+		{kind: "index", table: posts, sql: `CREATE INDEX "idx_posts_user_id" ON "posts" ("user_id")`},
+		{kind: "foreign key", table: posts, sql: `ALTER TABLE "comments" ADD COLUMN "post_id" INTEGER`},
+	}
+
+	err := execStatements(context.Background(), db, statements)
+	var statementErr *StatementError
+	if !errors.As(err, &statementErr) {
+		t.Fatalf("execStatements() = %v, want a StatementError", err)
+	}
+	if statementErr.Failure.Kind != "foreign key" || statementErr.Failure.Table != "posts" {
+		t.Fatalf("failure = %+v, want the foreign key of posts", statementErr.Failure)
+	}
+
+	var remaining []string
+	if err := db.Raw(`SELECT name FROM sqlite_master WHERE type IN ('table', 'index') AND name NOT LIKE 'sqlite_%'`).Scan(&remaining).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(remaining) > 0 {
+		t.Fatalf("tables and indexes left after the failure: %v", remaining)
+	}
+}
+
+func TestValidateCheckConstraint(t *testing.T) {
+	tests := []struct {
+		name  string
+		check string
+		valid bool
+	}{
+		{name: "comparison", check: "age >= 0", valid: true},
+		{name: "string literal", check: "status IN ('active', 'disabled')", valid: true},
+		{name: "quoted semicolon", check: "note <> ';'", valid: true},
+		{name: "empty", check: "", valid: false},
+		{name: "blank", check: "   ", valid: false},
+		{name: "statement terminator", check: "age >= 0); DROP TABLE users; --", valid: false},
+		{name: "unbalanced parentheses", check: "(age >= 0", valid: false},
+		{name: "closing parenthesis first", check: "age >= 0) OR (age < 0", valid: false},
+		{name: "unterminated string", check: "status = 'active", valid: false},
+		{name: "comment", check: "age >= 0 -- adults", valid: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			check := test.check
+			table := models.Table{ID: "t1", Name: "users", Columns: []models.Column{
+				{ID: "c1", Name: "id", DataType: "INT", PrimaryKey: true},
+				{ID: "c2", Name: "age", DataType: "INT", Check: &check},
+			}}
+
+			result := validateTables(t, []models.Table{table})
+			invalid := hasValidationError(result, "INVALID_CHECK_CONSTRAINT", "tables[0].columns[1].check")
+			if invalid == test.valid {
+				t.Fatalf("errors = %+v, want valid %v", result.Errors, test.valid)
+			}
+		})
+	}
+}
+
+func TestGenerateColumnDefinitionDefault(t *testing.T) {
+	generator := &sqlGeneratorService{config: &config.Config{}}
+	tests := []struct {
+		name   string
+		column string
+		want   string // Expression after DEFAULT
+	}{
+		{name: "string literal", column: `{"name":"status","dataType":"VARCHAR","defaultValue":"pending"}`, want: `'pending'`},
+		{name: "literal with a quote", column: `{"name":"owner","dataType":"VARCHAR","defaultValue":"O'Brien"}`, want: `'O''Brien'`},
+		{name: "literal that looks like SQL", column: `{"name":"note","dataType":"TEXT","defaultValue":"upper('x')"}`, want: `'upper(''x'')'`},
+		{name: "number", column: `{"name":"stock","dataType":"INT","defaultValue":5}`, want: `5`},
+		{name: "boolean", column: `{"name":"active","dataType":"BOOLEAN","defaultValue":true}`, want: `true`},
+		{name: "known function", column: `{"name":"created_at","dataType":"TIMESTAMP","defaultValue":"now()"}`, want: `NOW()`},
+		{name: "expression", column: `{"name":"token","dataType":"UUID","defaultExpression":"uuid_generate_v4()"}`, want: `uuid_generate_v4()`},
+		{name: "expression overrides value", column: `{"name":"code","dataType":"VARCHAR","defaultValue":"lower('A')","defaultExpression":"lower('A')"}`, want: `lower('A')`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var column models.Column
+			if err := json.Unmarshal([]byte(test.column), &column); err != nil {
+				t.Fatal(err)
+			}
+
+			definition := generator.generateColumnDefinition(column)
+			if !strings.HasSuffix(definition, " DEFAULT "+test.want) {
+				t.Fatalf("definition = %q, want a DEFAULT %s suffix", definition, test.want)
+			}
+		})
+	}
+}
+
+func TestVerbatimSQLProblem(t *testing.T) {
+	tests := []struct {
+		name       string
+		expression string
+		valid      bool
+	}{
+		{name: "function call", expression: "now() + interval '1 day'", valid: true},
+		{name: "quoted semicolon and comment markers", expression: "'a;b -- c /* d'", valid: true},
+		{name: "doubled quote", expression: "'O''Brien'", valid: true},
+		{name: "dollar inside a string", expression: "code ~ '^[A-Z]+$'", valid: true},
+		{name: "dollar inside a quoted identifier", expression: `"price$" > 0`, valid: true},
+		{name: "semicolon", expression: "0; DROP TABLE users", valid: false},
+		{name: "line comment", expression: "0 -- rest of the statement", valid: false},
+		{name: "block comment", expression: "0 /* rest of the statement", valid: false},
+		{name: "escape string", expression: `E'\''; DROP TABLE users; --'`, valid: false},
+		{name: "backslash in a plain string", expression: `'a\b'`, valid: false},
+		{name: "dollar quote", expression: `$$"$$; DROP TABLE users; --"`, valid: false},
+		{name: "tagged dollar quote", expression: `$x$"$x$); DROP TABLE users; --"`, valid: false},
+		{name: "unterminated string", expression: "'open", valid: false},
+		{name: "unbalanced parentheses", expression: "lower(name", valid: false},
+		{name: "server function", expression: "pg_read_file('/etc/passwd')", valid: false},
+		{name: "quoted server function", expression: `"pg_read_file"('/etc/passwd')`, valid: false},
+		{name: "query in a string", expression: "query_to_xml('DELETE FROM users RETURNING id', true, false, '') IS NOT NULL", valid: false},
+		{name: "unicode escaped identifier", expression: `U&"pg!005fread!005ffile" UESCAPE '!'('/etc/passwd')`, valid: false},
+		{name: "server function name in a string", expression: "'pg_read_file'", valid: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			problem := verbatimSQLProblem(test.expression)
+			if (problem == "") != test.valid {
+				t.Fatalf("verbatimSQLProblem(%q) = %q, want valid %v", test.expression, problem, test.valid)
+			}
+		})
+	}
+}
+
+func TestValidateDefaultExpression(t *testing.T) {
+	for _, expression := range []string{"0; DROP TABLE users", `E'\''; DROP TABLE users; --'`, `$$"$$; DROP TABLE users; --"`} {
+		t.Run(expression, func(t *testing.T) {
+			table := models.Table{ID: "t1", Name: "users", Columns: []models.Column{
+				{ID: "c1", Name: "id", DataType: "INT", PrimaryKey: true},
+				{ID: "c2", Name: "note", DataType: "TEXT", DefaultExpression: expression},
+			}}
+
+			result := validateTables(t, []models.Table{table})
+			if !hasValidationError(result, "INVALID_DEFAULT_EXPRESSION", "tables[0].columns[1].defaultExpression") {
+				t.Fatalf("errors = %+v, want INVALID_DEFAULT_EXPRESSION", result.Errors)
+			}
+		})
+	}
+}
+
+func TestGenerateColumnDefinitionCheck(t *testing.T) {
+	check := " age >= 0 "
+	column := models.Column{ID: "c1", Name: "age", DataType: "INT", Check: &check}
+
+	definition := (&sqlGeneratorService{config: &config.Config{}}).generateColumnDefinition(column)
+	if !strings.HasSuffix(definition, " CHECK (age >= 0)") {
+		t.Fatalf("definition = %q, want a CHECK (age >= 0) suffix", definition)
+	}
+
+	// The check survives storing the definition as JSON
+	stored, err := models.SchemaData{Tables: []models.Table{{ID: "t1", Name: "users", Columns: []models.Column{column}}}}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var loaded models.SchemaData
+	if err := loaded.Scan(stored); err != nil {
+		t.Fatal(err)
+	}
+	if got := loaded.Tables[0].Columns[0].Check; got == nil || *got != check {
+		t.Fatalf("loaded check = %v, want %q", got, check)
+	}
+}
+
+func TestListSchemasUnknownStatus(t *testing.T) {
+	service := newTestSchemaService(t, newFakeSchemaRepository(), newFakeDatabaseManager())
+
+	_, _, err := service.ListSchemas(models.PaginationRequest{Status: "broken", Search: "shop"}, uuid.New())
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("ListSchemas() = %v, want ErrValidation", err)
+	}
+}
+
+func TestDeleteSchemaDropsDatabase(t *testing.T) {
+	tests := []struct {
+		name         string
+		databaseName string
+		dropDatabase bool
+		dropErr      error
+		wantDropped  bool
+	}{
+		{name: "drop requested", databaseName: "schema_shop", dropDatabase: true, wantDropped: true},
+		{name: "drop not requested", databaseName: "schema_shop", dropDatabase: false},
+		{name: "drop fails", databaseName: "schema_shop", dropDatabase: true, dropErr: errors.New("connection refused")},
+		{name: "name without the schema prefix", databaseName: "shop", dropDatabase: true},
+		{name: "application database", databaseName: "schema_app", dropDatabase: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			userID := uuid.New()
+			schema := models.Schema{ID: uuid.New(), Name: "shop", DatabaseName: test.databaseName, UserID: userID, Status: models.SchemaStatusCreated}
+			repo := newFakeSchemaRepository(schema)
+			manager := newFakeDatabaseManager(test.databaseName)
+			manager.dropErr = test.dropErr
+			service := newTestSchemaService(t, repo, manager)
+			service.config.DatabaseName = "schema_app"
+
+			dropped, err := service.DeleteSchema(schema.ID, userID, models.DeleteSchemaOptions{DropDatabase: test.dropDatabase})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dropped != test.wantDropped {
+				t.Fatalf("databaseDropped = %v, want %v", dropped, test.wantDropped)
+			}
+			if manager.databases[test.databaseName] == test.wantDropped {
+				t.Fatalf("database exists = %v after delete, want %v", manager.databases[test.databaseName], !test.wantDropped)
+			}
+			if _, err := repo.GetByIDAndUserID(schema.ID, userID); err == nil {
+				t.Fatal("schema is still active after delete")
+			}
+		})
+	}
+}
+
+func TestRebuildForeignKeys(t *testing.T) {
+	userID := uuid.New()
+	schema := models.Schema{ID: uuid.New(), Name: "shop", DatabaseName: "schema_shop", UserID: userID, Status: models.SchemaStatusCreated}
+	violation := newStatementError("foreign key", models.Table{ID: "t2", Name: "orders"}, `ALTER TABLE "orders" ADD CONSTRAINT ...`, errors.New("violates foreign key constraint"))
+
+	tests := []struct {
+		name       string
+		lockHeld   int
+		rebuildErr error
+		wantErr    error
+	}{
+		{name: "rebuilt"},
+		{name: "generation in progress", lockHeld: 1, wantErr: ErrGenerationInProgress},
+		{name: "existing rows violate a foreign key", rebuildErr: violation, wantErr: ErrForeignKeyViolation},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			manager := newFakeDatabaseManager(schema.DatabaseName)
+			manager.lockHeld = test.lockHeld
+			manager.rebuildErr = test.rebuildErr
+			service := newTestSchemaService(t, newFakeSchemaRepository(schema), manager)
+
+			rebuilt, err := service.RebuildForeignKeys(schema.ID, userID)
+			if test.wantErr != nil {
+				if !errors.Is(err, test.wantErr) {
+					t.Fatalf("RebuildForeignKeys() = %v, want %v", err, test.wantErr)
+				}
+				if test.rebuildErr != nil {
+					var statementErr *StatementError
+					if !errors.As(err, &statementErr) || statementErr.Failure.Table != "orders" {
+						t.Fatalf("RebuildForeignKeys() = %v, want the failed statement of orders", err)
+					}
+				}
+				if len(manager.rebuilt) != 0 {
+					t.Fatalf("rebuilt = %v, want none", manager.rebuilt)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rebuilt.ID != schema.ID || len(manager.rebuilt) != 1 {
+				t.Fatalf("rebuilt %v for schema %s, want one rebuild of %s", manager.rebuilt, rebuilt.ID, schema.DatabaseName)
+			}
+		})
+	}
+}
@@ -0,0 +1,406 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"vdt-dashboard-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// introspectedColumn is a column row from information_schema.columns
+type introspectedColumn struct {
+	TableName              string
+	ColumnName             string
+	DataType               string
+	UdtName                string
+	IsNullable             string
+	ColumnDefault          *string
+	CharacterMaximumLength *int
+	NumericPrecision       *int
+	NumericScale           *int
+}
+
+// introspectedForeignKey is a foreign key read from pg_catalog. Its columns
+// are comma-separated in key order.
+type introspectedForeignKey struct {
+	ConstraintName string
+	SourceTable    string
+	SourceColumns  string
+	TargetTable    string
+	TargetColumns  string
+	DeleteAction   string
+	UpdateAction   string
+}
+
+// pgForeignKeyActions maps pg_constraint action codes to foreign key actions
+var pgForeignKeyActions = map[string]string{
+	"a": "NO ACTION",
+	"r": "RESTRICT",
+	"c": "CASCADE",
+	"n": "SET NULL",
+	"d": "SET DEFAULT",
+}
+
+// IntrospectDatabase reads the tables, columns and foreign keys of a generated
+// database. Table IDs are table names and column IDs are "table.column".
+func (d *databaseManagerService) IntrospectDatabase(databaseName string) (models.SchemaData, error) {
+	db, release, err := d.openDatabase(databaseName, logger.Silent)
+	if err != nil {
+		return models.SchemaData{}, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer release()
+
+	var tableNames []string
+	err = db.Raw(`SELECT table_name FROM information_schema.tables
+		WHERE table_schema = 'public' AND table_type = 'BASE TABLE'
+		ORDER BY table_name`).Scan(&tableNames).Error
+	if err != nil {
+		return models.SchemaData{}, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	var columns []introspectedColumn
+	err = db.Raw(`SELECT table_name, column_name, data_type, udt_name, is_nullable, column_default,
+			character_maximum_length, numeric_precision, numeric_scale
+		FROM information_schema.columns
+		WHERE table_schema = 'public'
+		ORDER BY table_name, ordinal_position`).Scan(&columns).Error
+	if err != nil {
+		return models.SchemaData{}, fmt.Errorf("failed to list columns: %w", err)
+	}
+
+	primaryKeys, err := introspectConstraintColumns(db, "PRIMARY KEY")
+	if err != nil {
+		return models.SchemaData{}, err
+	}
+	uniqueColumns, err := introspectConstraintColumns(db, "UNIQUE")
+	if err != nil {
+		return models.SchemaData{}, err
+	}
+
+	var foreignKeys []introspectedForeignKey
+	err = db.Raw(`SELECT con.conname AS constraint_name,
+			src.relname AS source_table,
+			(SELECT string_agg(a.attname, ',' ORDER BY k.ord)
+				FROM unnest(con.conkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.conrelid AND a.attnum = k.attnum) AS source_columns,
+			tgt.relname AS target_table,
+			(SELECT string_agg(a.attname, ',' ORDER BY k.ord)
+				FROM unnest(con.confkey) WITH ORDINALITY AS k(attnum, ord)
+				JOIN pg_attribute a ON a.attrelid = con.confrelid AND a.attnum = k.attnum) AS target_columns,
+			con.confdeltype AS delete_action, con.confupdtype AS update_action
+		FROM pg_constraint con
+		JOIN pg_class src ON src.oid = con.conrelid
+		JOIN pg_class tgt ON tgt.oid = con.confrelid
+		JOIN pg_namespace ns ON ns.oid = src.relnamespace
+		WHERE con.contype = 'f' AND ns.nspname = 'public'
+		ORDER BY con.conname`).Scan(&foreignKeys).Error
+	if err != nil {
+		return models.SchemaData{}, fmt.Errorf("failed to list foreign keys: %w", err)
+	}
+
+	tables := make([]models.Table, 0, len(tableNames))
+	tableIndex := make(map[string]int)
+	for _, name := range tableNames {
+		tableIndex[name] = len(tables)
+		tables = append(tables, models.Table{ID: name, Name: name, Columns: []models.Column{}})
+	}
+
+	for _, row := range columns {
+		i, exists := tableIndex[row.TableName]
+		if !exists {
+			continue
+		}
+		column := introspectedToColumn(row)
+		key := row.TableName + "." + row.ColumnName
+		column.PrimaryKey = primaryKeys[key]
+		column.Unique = uniqueColumns[key]
+		tables[i].Columns = append(tables[i].Columns, column)
+	}
+
+	schemaData := models.SchemaData{
+		Tables:      tables,
+		ForeignKeys: make([]models.ForeignKey, 0, len(foreignKeys)),
+		ExportedAt:  time.Now().UTC(),
+	}
+	for _, fk := range foreignKeys {
+		foreignKey := models.ForeignKey{
+			ID:            fk.ConstraintName,
+			Name:          fk.ConstraintName,
+			SourceTableId: fk.SourceTable,
+			TargetTableId: fk.TargetTable,
+			OnDelete:      pgForeignKeyActions[fk.DeleteAction],
+			OnUpdate:      pgForeignKeyActions[fk.UpdateAction],
+		}
+		sourceIDs := qualifiedColumnIDs(fk.SourceTable, fk.SourceColumns)
+		targetIDs := qualifiedColumnIDs(fk.TargetTable, fk.TargetColumns)
+		if len(sourceIDs) == 1 && len(targetIDs) == 1 {
+			foreignKey.SourceColumnId, foreignKey.TargetColumnId = sourceIDs[0], targetIDs[0]
+		} else {
+			foreignKey.SourceColumnIds, foreignKey.TargetColumnIds = sourceIDs, targetIDs
+		}
+		schemaData.ForeignKeys = append(schemaData.ForeignKeys, foreignKey)
+	}
+
+	return schemaData, nil
+}
+
+// pgConstraintTypes maps pg_constraint type codes to constraint types
+var pgConstraintTypes = map[string]string{
+	"p": "PRIMARY KEY",
+	"u": "UNIQUE",
+	"f": "FOREIGN KEY",
+	"c": "CHECK",
+	"x": "EXCLUDE",
+}
+
+// ListLiveTables reads the tables of a generated database with their columns
+// and constraints as PostgreSQL reports them, without mapping them to the
+// column model
+func (d *databaseManagerService) ListLiveTables(databaseName string) ([]models.LiveTable, error) {
+	db, release, err := d.openDatabase(databaseName, logger.Silent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer release()
+
+	var tableNames []string
+	err = db.Raw(`SELECT table_name FROM information_schema.tables
+		WHERE table_schema = 'public' AND table_type = 'BASE TABLE'
+		ORDER BY table_name`).Scan(&tableNames).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	var columns []introspectedColumn
+	err = db.Raw(`SELECT table_name, column_name, data_type, udt_name, is_nullable, column_default,
+			character_maximum_length, numeric_precision, numeric_scale
+		FROM information_schema.columns
+		WHERE table_schema = 'public'
+		ORDER BY table_name, ordinal_position`).Scan(&columns).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list columns: %w", err)
+	}
+
+	var constraints []struct {
+		TableName      string
+		ConstraintName string
+		ConstraintType string
+		Definition     string
+	}
+	err = db.Raw(`SELECT rel.relname AS table_name, con.conname AS constraint_name,
+			con.contype AS constraint_type, pg_get_constraintdef(con.oid) AS definition
+		FROM pg_constraint con
+		JOIN pg_class rel ON rel.oid = con.conrelid
+		JOIN pg_namespace ns ON ns.oid = rel.relnamespace
+		WHERE ns.nspname = 'public'
+		ORDER BY rel.relname, con.conname`).Scan(&constraints).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list constraints: %w", err)
+	}
+
+	tables := make([]models.LiveTable, 0, len(tableNames))
+	tableIndex := make(map[string]int)
+	for _, name := range tableNames {
+		tableIndex[name] = len(tables)
+		tables = append(tables, models.LiveTable{Name: name, Columns: []models.LiveColumn{}, Constraints: []models.LiveConstraint{}})
+	}
+
+	for _, row := range columns {
+		i, exists := tableIndex[row.TableName]
+		if !exists {
+			continue
+		}
+		tables[i].Columns = append(tables[i].Columns, models.LiveColumn{
+			Name:     row.ColumnName,
+			DataType: liveColumnType(row),
+			Nullable: row.IsNullable == "YES",
+			Default:  row.ColumnDefault,
+		})
+	}
+
+	for _, row := range constraints {
+		i, exists := tableIndex[row.TableName]
+		if !exists {
+			continue
+		}
+		constraintType := pgConstraintTypes[row.ConstraintType]
+		if constraintType == "" {
+			constraintType = row.ConstraintType
+		}
+		tables[i].Constraints = append(tables[i].Constraints, models.LiveConstraint{
+			Name:       row.ConstraintName,
+			Type:       constraintType,
+			Definition: row.Definition,
+		})
+	}
+
+	return tables, nil
+}
+
+// liveColumnType formats the type of an information_schema column with its
+// length or precision, e.g. character varying(255). Array element types are
+// reported by udt_name, without their length.
+func liveColumnType(row introspectedColumn) string {
+	if row.DataType == "ARRAY" {
+		element := strings.TrimPrefix(row.UdtName, "_")
+		if dataType, exists := arrayElementTypes[element]; exists {
+			element = dataType
+		}
+		return element + "[]"
+	}
+
+	switch {
+	case row.CharacterMaximumLength != nil:
+		return fmt.Sprintf("%s(%d)", row.DataType, *row.CharacterMaximumLength)
+	case row.DataType == "numeric" && row.NumericPrecision != nil && row.NumericScale != nil:
+		return fmt.Sprintf("numeric(%d,%d)", *row.NumericPrecision, *row.NumericScale)
+	}
+	return row.DataType
+}
+
+// DetectDrift compares a stored schema definition with its live database
+func (d *databaseManagerService) DetectDrift(schemaData models.SchemaData, databaseName string) (*models.DriftReport, error) {
+	live, err := d.IntrospectDatabase(databaseName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Compare the names the generator actually created
+	expected := applyIdentifierCase(schemaData, identifierCase(schemaData.IdentifierCase, d.config))
+	diff := diffSchemas(expected, live, d.config)
+
+	return &models.DriftReport{
+		DatabaseName: databaseName,
+		HasDrift:     diff.HasChanges(),
+		Diff:         diff,
+		CheckedAt:    time.Now().UTC(),
+	}, nil
+}
+
+// introspectConstraintColumns returns the "table.column" keys of single-column
+// constraints of the given type
+func introspectConstraintColumns(db *gorm.DB, constraintType string) (map[string]bool, error) {
+	var rows []struct {
+		TableName   string
+		ColumnName  string
+		ColumnCount int
+	}
+	err := db.Raw(`SELECT kcu.table_name, kcu.column_name,
+			COUNT(*) OVER (PARTITION BY tc.constraint_name) AS column_count
+		FROM information_schema.table_constraints tc
+		JOIN information_schema.key_column_usage kcu
+			ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema
+		WHERE tc.table_schema = 'public' AND tc.constraint_type = ?`, constraintType).Scan(&rows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s constraints: %w", strings.ToLower(constraintType), err)
+	}
+
+	keys := make(map[string]bool)
+	for _, row := range rows {
+		// Every column of a composite primary key is a primary key column, but
+		// only single-column unique constraints make a column unique
+		if constraintType == "UNIQUE" && row.ColumnCount > 1 {
+			continue
+		}
+		keys[row.TableName+"."+row.ColumnName] = true
+	}
+	return keys, nil
+}
+
+// arrayElementTypes maps the udt_name of array elements to their
+// information_schema data_type
+var arrayElementTypes = map[string]string{
+	"int4":        "integer",
+	"int8":        "bigint",
+	"varchar":     "character varying",
+	"text":        "text",
+	"bool":        "boolean",
+	"timestamptz": "timestamp with time zone",
+	"timestamp":   "timestamp without time zone",
+	"date":        "date",
+	"time":        "time without time zone",
+	"timetz":      "time with time zone",
+	"numeric":     "numeric",
+	"float4":      "real",
+	"float8":      "double precision",
+	"json":        "json",
+	"jsonb":       "jsonb",
+	"uuid":        "uuid",
+}
+
+// introspectedToColumn maps an information_schema column to the column model.
+// information_schema doesn't report the length or precision of array
+// elements, so those are left unset.
+func introspectedToColumn(row introspectedColumn) models.Column {
+	if row.DataType == "ARRAY" {
+		element := strings.TrimPrefix(row.UdtName, "_")
+		row.DataType = element
+		if dataType, exists := arrayElementTypes[element]; exists {
+			row.DataType = dataType
+		}
+		column := introspectedToColumn(row)
+		column.IsArray = true
+		return column
+	}
+
+	nullable := row.IsNullable == "YES"
+	column := models.Column{
+		ID:       row.TableName + "." + row.ColumnName,
+		Name:     row.ColumnName,
+		Nullable: &nullable,
+	}
+
+	isSerial := row.ColumnDefault != nil && strings.HasPrefix(*row.ColumnDefault, "nextval(")
+
+	switch row.DataType {
+	case "integer":
+		column.DataType = "INT"
+		column.AutoIncrement = isSerial
+	case "bigint":
+		column.DataType = "BIGINT"
+		column.AutoIncrement = isSerial
+	case "character varying":
+		column.DataType = "VARCHAR"
+		column.Length = row.CharacterMaximumLength
+	case "text":
+		column.DataType = "TEXT"
+	case "boolean":
+		column.DataType = "BOOLEAN"
+	case "timestamp with time zone", "timestamp without time zone":
+		column.DataType = "TIMESTAMP"
+	case "date":
+		column.DataType = "DATE"
+	case "time without time zone", "time with time zone":
+		column.DataType = "TIME"
+	case "numeric":
+		column.DataType = "DECIMAL"
+		column.Precision = row.NumericPrecision
+		column.Scale = row.NumericScale
+	case "real":
+		column.DataType = "FLOAT"
+	case "double precision":
+		column.DataType = "DOUBLE"
+	case "json", "jsonb":
+		column.DataType = "JSON"
+	case "uuid":
+		column.DataType = "UUID"
+	default:
+		column.DataType = strings.ToUpper(row.DataType)
+	}
+
+	return column
+}
+
+// qualifiedColumnIDs turns comma-separated column names into "table.column" IDs
+func qualifiedColumnIDs(table, columns string) []string {
+	names := strings.Split(columns, ",")
+	ids := make([]string, len(names))
+	for i, name := range names {
+		ids[i] = table + "." + name
+	}
+	return ids
+}
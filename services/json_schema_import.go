@@ -0,0 +1,450 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// jsonSchemaStringLength is the VARCHAR length of strings without maxLength
+const jsonSchemaStringLength = 255
+
+// jsonSchema is the subset of a JSON Schema (or OpenAPI schema object) the
+// import understands. Definitions are read from definitions, $defs and
+// OpenAPI's components.schemas.
+type jsonSchema struct {
+	Ref         string             `json:"$ref"`
+	Type        jsonSchemaType     `json:"type"`
+	Format      string             `json:"format"`
+	Title       string             `json:"title"`
+	Description string             `json:"description"`
+	Properties  orderedJSONSchemas `json:"properties"`
+	Required    []string           `json:"required"`
+	Items       *jsonSchema        `json:"items"`
+	MaxLength   *int               `json:"maxLength"`
+	Nullable    bool               `json:"nullable"` // OpenAPI 3.0 spelling of a null type
+	Definitions orderedJSONSchemas `json:"definitions"`
+	Defs        orderedJSONSchemas `json:"$defs"`
+	Components  *struct {
+		Schemas orderedJSONSchemas `json:"schemas"`
+	} `json:"components"`
+}
+
+// isObject reports whether the schema describes an object, which is imported
+// as a table when it is a definition
+func (s *jsonSchema) isObject() bool {
+	return s.Type.primary() == "object" || len(s.Properties) > 0
+}
+
+// allowsNull reports whether the schema accepts null
+func (s *jsonSchema) allowsNull() bool {
+	return s.Nullable || s.Type.has("null")
+}
+
+// jsonSchemaType is a JSON Schema type, which may be a list such as
+// ["string", "null"]
+type jsonSchemaType []string
+
+// UnmarshalJSON accepts a single type or a list of types
+func (t *jsonSchemaType) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = jsonSchemaType{single}
+		return nil
+	}
+	var types []string
+	if err := json.Unmarshal(data, &types); err != nil {
+		return errors.New("type must be a string or an array of strings")
+	}
+	*t = types
+	return nil
+}
+
+// primary returns the type other than null
+func (t jsonSchemaType) primary() string {
+	for _, name := range t {
+		if name != "null" {
+			return name
+		}
+	}
+	return ""
+}
+
+// has reports whether name is one of the types
+func (t jsonSchemaType) has(name string) bool {
+	for _, candidate := range t {
+		if candidate == name {
+			return true
+		}
+	}
+	return false
+}
+
+// namedJSONSchema is a property or definition
+type namedJSONSchema struct {
+	name   string
+	schema *jsonSchema
+}
+
+// orderedJSONSchemas keeps properties and definitions in document order, so
+// tables and columns are imported in the order they were written
+type orderedJSONSchemas []namedJSONSchema
+
+// UnmarshalJSON decodes an object of schemas keeping its key order
+func (o *orderedJSONSchemas) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err != nil {
+		return err
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return errors.New("expected an object of schemas")
+	}
+
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		name := token.(string)
+
+		var schema jsonSchema
+		if err := decoder.Decode(&schema); err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		*o = append(*o, namedJSONSchema{name: name, schema: &schema})
+	}
+	return nil
+}
+
+// jsonSchemaDefinitions is a place definitions are read from, with the
+// prefix of the local references to them
+type jsonSchemaDefinitions struct {
+	prefix  string
+	schemas orderedJSONSchemas
+}
+
+// jsonSchemaTable is a table being imported from an object schema
+type jsonSchemaTable struct {
+	path       string
+	schema     *jsonSchema
+	table      models.Table
+	primaryKey models.Column
+	idProperty string // Property imported as the primary key, if any
+}
+
+// jsonSchemaImport holds the state of one JSON Schema import
+type jsonSchemaImport struct {
+	service     *schemaImportService
+	definitions map[string]*jsonSchema      // By local $ref
+	tables      map[string]*jsonSchemaTable // By local $ref
+	ordered     []*jsonSchemaTable
+	foreignKeys []models.ForeignKey
+	warnings    []models.ImportWarning
+
+	// One-to-many relationships, added once every table has its columns
+	backReferences []jsonSchemaBackReference
+}
+
+// jsonSchemaBackReference is an array property referencing another table
+type jsonSchemaBackReference struct {
+	path   string
+	parent *jsonSchemaTable
+	child  *jsonSchemaTable
+}
+
+// ImportJSONSchema builds a schema definition from a JSON Schema or OpenAPI
+// document. Every object definition becomes a table named after it, and so
+// does the root schema when it has properties. Properties referencing another
+// table become foreign key columns, and arrays of references become a foreign
+// key on the referenced table. Tables without an id property get an
+// auto-increment id primary key.
+func (s *schemaImportService) ImportJSONSchema(document []byte) (*models.SchemaImportResponse, error) {
+	if len(document) > models.MaxDefinitionBytes {
+		return nil, fmt.Errorf("%w: document is %d bytes, the maximum is %d", ErrTooLarge, len(document), models.MaxDefinitionBytes)
+	}
+
+	var root jsonSchema
+	if err := json.Unmarshal(document, &root); err != nil {
+		return nil, fmt.Errorf("%w: invalid JSON Schema document: %v", ErrValidation, err)
+	}
+
+	imp := &jsonSchemaImport{
+		service:     s,
+		definitions: make(map[string]*jsonSchema),
+		tables:      make(map[string]*jsonSchemaTable),
+	}
+	imp.collectTables(&root)
+	if len(imp.ordered) == 0 {
+		return nil, fmt.Errorf("%w: the document defines no object schemas to import as tables", ErrValidation)
+	}
+
+	for _, table := range imp.ordered {
+		imp.choosePrimaryKey(table)
+	}
+	for _, table := range imp.ordered {
+		imp.importProperties(table)
+	}
+	for _, reference := range imp.backReferences {
+		imp.addBackReference(reference)
+	}
+
+	tables := make([]models.Table, 0, len(imp.ordered))
+	for _, table := range imp.ordered {
+		tables = append(tables, table.table)
+	}
+	return importResponse(tables, imp.foreignKeys, imp.warnings), nil
+}
+
+// collectTables registers the definitions of the document and creates a table
+// for each object schema among them and for the root
+func (imp *jsonSchemaImport) collectTables(root *jsonSchema) {
+	if len(root.Properties) > 0 {
+		name := root.Title
+		if name == "" {
+			name = "root"
+		}
+		imp.addTable("#", name, root)
+	}
+
+	sources := []jsonSchemaDefinitions{
+		{prefix: "#/definitions/", schemas: root.Definitions},
+		{prefix: "#/$defs/", schemas: root.Defs},
+	}
+	if root.Components != nil {
+		sources = append(sources, jsonSchemaDefinitions{prefix: "#/components/schemas/", schemas: root.Components.Schemas})
+	}
+
+	escaper := strings.NewReplacer("~", "~0", "/", "~1")
+	for _, source := range sources {
+		for _, definition := range source.schemas {
+			ref := source.prefix + escaper.Replace(definition.name)
+			imp.definitions[ref] = definition.schema
+			if definition.schema.isObject() {
+				imp.addTable(ref, definition.name, definition.schema)
+			}
+		}
+	}
+}
+
+// addTable creates the table of an object schema
+func (imp *jsonSchemaImport) addTable(path, name string, schema *jsonSchema) {
+	table := &jsonSchemaTable{
+		path:   path,
+		schema: schema,
+		table: models.Table{
+			ID:      uuid.New().String(),
+			Name:    name,
+			Columns: []models.Column{},
+			Comment: schema.Description,
+		},
+	}
+	imp.tables[path] = table
+	imp.ordered = append(imp.ordered, table)
+}
+
+// choosePrimaryKey makes a scalar id property the primary key of a table, or
+// adds an auto-increment id column when there is none. It runs for every
+// table before properties are imported, so references know the type of the
+// key they point to.
+func (imp *jsonSchemaImport) choosePrimaryKey(table *jsonSchemaTable) {
+	for _, property := range table.schema.Properties {
+		if !strings.EqualFold(property.name, "id") || property.schema.Ref != "" {
+			continue
+		}
+		switch property.schema.Type.primary() {
+		case "string", "integer", "number":
+		default:
+			continue
+		}
+		column := models.Column{
+			ID:         uuid.New().String(),
+			Name:       property.name,
+			PrimaryKey: true,
+			Comment:    property.schema.Description,
+		}
+		imp.setColumnType(&column, table.path+"/properties/"+property.name, property.schema)
+		table.primaryKey = column
+		table.idProperty = property.name
+		return
+	}
+
+	table.primaryKey = models.Column{
+		ID:            uuid.New().String(),
+		Name:          "id",
+		DataType:      "INT",
+		PrimaryKey:    true,
+		AutoIncrement: true,
+	}
+	table.table.Columns = append(table.table.Columns, table.primaryKey)
+}
+
+// importProperties adds a column for each property of a table
+func (imp *jsonSchemaImport) importProperties(table *jsonSchemaTable) {
+	required := make(map[string]bool)
+	for _, name := range table.schema.Required {
+		required[name] = true
+	}
+
+	for _, property := range table.schema.Properties {
+		path := table.path + "/properties/" + property.name
+		schema := property.schema
+
+		if property.name == table.idProperty {
+			table.table.Columns = append(table.table.Columns, table.primaryKey)
+			continue
+		}
+
+		// A reference to another table is a many-to-one relationship
+		if target, exists := imp.tables[schema.Ref]; exists {
+			column := foreignKeyColumn(property.name+"_id", target.primaryKey)
+			column.Comment = schema.Description
+			if required[property.name] && !schema.allowsNull() {
+				column.Nullable = new(bool)
+			}
+			table.table.Columns = append(table.table.Columns, column)
+			imp.foreignKeys = append(imp.foreignKeys, imp.service.importForeignKey(table.table.ID, column.ID, target.table.ID, target.primaryKey.ID))
+			continue
+		}
+		schema = imp.resolve(path, schema)
+
+		// An array of references to another table is a one-to-many
+		// relationship, stored as a key on the referenced table
+		if schema.Type.primary() == "array" && schema.Items != nil {
+			if target, exists := imp.tables[schema.Items.Ref]; exists {
+				imp.backReferences = append(imp.backReferences, jsonSchemaBackReference{path: path, parent: table, child: target})
+				continue
+			}
+		}
+
+		column := models.Column{
+			ID:      uuid.New().String(),
+			Name:    property.name,
+			Comment: schema.Description,
+		}
+		imp.setColumnType(&column, path, schema)
+		if required[property.name] && !schema.allowsNull() {
+			column.Nullable = new(bool)
+		}
+		table.table.Columns = append(table.table.Columns, column)
+	}
+}
+
+// resolve returns the definition a schema references, or the schema itself
+// when it has no reference. References that can't be resolved are reported
+// and imported as untyped.
+func (imp *jsonSchemaImport) resolve(path string, schema *jsonSchema) *jsonSchema {
+	if schema.Ref == "" {
+		return schema
+	}
+	if definition, exists := imp.definitions[schema.Ref]; exists && definition.Ref == "" {
+		return definition
+	}
+	imp.warn(path, fmt.Sprintf("Reference '%s' can't be resolved, it is imported as JSON", schema.Ref))
+	return &jsonSchema{Type: jsonSchemaType{"object"}, Description: schema.Description}
+}
+
+// addBackReference adds a key to the parent on the child of a one-to-many
+// relationship, unless the child already references the parent
+func (imp *jsonSchemaImport) addBackReference(reference jsonSchemaBackReference) {
+	parent, child := reference.parent, reference.child
+	for _, fk := range imp.foreignKeys {
+		if fk.SourceTableId == child.table.ID && fk.TargetTableId == parent.table.ID {
+			return
+		}
+	}
+
+	name := parent.table.Name + "_id"
+	for _, column := range child.table.Columns {
+		if column.Name == name {
+			imp.warn(reference.path, fmt.Sprintf("Table '%s' already has a column '%s', add the foreign key to '%s' manually", child.table.Name, name, parent.table.Name))
+			return
+		}
+	}
+
+	column := foreignKeyColumn(name, parent.primaryKey)
+	child.table.Columns = append(child.table.Columns, column)
+	imp.foreignKeys = append(imp.foreignKeys, imp.service.importForeignKey(child.table.ID, column.ID, parent.table.ID, parent.primaryKey.ID))
+}
+
+// setColumnType maps the type of a schema to the type of a column. Arrays
+// of scalars become array columns; objects, nested arrays and schemas
+// without a type become JSON.
+func (imp *jsonSchemaImport) setColumnType(column *models.Column, path string, schema *jsonSchema) {
+	switch schema.Type.primary() {
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			column.DataType = "TIMESTAMP"
+		case "date":
+			column.DataType = "DATE"
+		case "time":
+			column.DataType = "TIME"
+		case "uuid":
+			column.DataType = "UUID"
+		default:
+			length := jsonSchemaStringLength
+			if schema.MaxLength != nil && *schema.MaxLength > 0 {
+				length = *schema.MaxLength
+			}
+			column.DataType = "VARCHAR"
+			column.Length = &length
+		}
+	case "integer":
+		column.DataType = "INT"
+		if schema.Format == "int64" {
+			column.DataType = "BIGINT"
+		}
+	case "number":
+		column.DataType = "DOUBLE"
+	case "boolean":
+		column.DataType = "BOOLEAN"
+	case "object":
+		column.DataType = "JSON"
+	case "array":
+		column.DataType = "JSON"
+		if schema.Items == nil {
+			return
+		}
+		items := imp.resolve(path+"/items", schema.Items)
+		if items.Type.primary() == "object" || items.Type.primary() == "array" || items.Type.primary() == "" {
+			return
+		}
+		imp.setColumnType(column, path+"/items", items)
+		column.IsArray = true
+	case "":
+		imp.warn(path, "Schema has no type, it is imported as JSON")
+		column.DataType = "JSON"
+	default:
+		imp.warn(path, fmt.Sprintf("Type '%s' is not supported, it is imported as JSON", schema.Type.primary()))
+		column.DataType = "JSON"
+	}
+}
+
+// warn records an import warning
+func (imp *jsonSchemaImport) warn(path, message string) {
+	imp.warnings = append(imp.warnings, models.ImportWarning{Path: path, Message: message})
+}
+
+// foreignKeyColumn returns a new column named name with the type of the
+// primary key it references
+func foreignKeyColumn(name string, primaryKey models.Column) models.Column {
+	return models.Column{
+		ID:        uuid.New().String(),
+		Name:      name,
+		DataType:  primaryKey.DataType,
+		Length:    primaryKey.Length,
+		Precision: primaryKey.Precision,
+		Scale:     primaryKey.Scale,
+	}
+}
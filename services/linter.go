@@ -0,0 +1,256 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"vdt-dashboard-backend/config"
+	"vdt-dashboard-backend/models"
+)
+
+// wideTableColumns is the column count above which a table is reported as
+// overly wide
+const wideTableColumns = 30
+
+// ambiguousColumnNames are column names that say nothing about their content
+var ambiguousColumnNames = map[string]bool{
+	"data":   true,
+	"value":  true,
+	"val":    true,
+	"info":   true,
+	"type":   true,
+	"misc":   true,
+	"temp":   true,
+	"tmp":    true,
+	"flag":   true,
+	"field":  true,
+	"column": true,
+	"object": true,
+	"item":   true,
+	"stuff":  true,
+}
+
+// SchemaLinter defines the interface for advisory best-practice checks.
+// Unlike validation, lint findings never block generation.
+type SchemaLinter interface {
+	Lint(request models.LintRequest) (*models.LintResult, error)
+}
+
+// NewSchemaLinter creates a new schema linter
+func NewSchemaLinter(cfg *config.Config) SchemaLinter {
+	return &schemaLinter{
+		config: cfg,
+	}
+}
+
+type schemaLinter struct {
+	config *config.Config
+}
+
+// lintRule is a best-practice check with its default severity
+type lintRule struct {
+	id          string
+	description string
+	severity    string
+	check       func(l *schemaLinter, request models.LintRequest) []models.LintFinding
+}
+
+// lintRules lists every lint rule in reporting order
+var lintRules = []lintRule{
+	{
+		id:          "surrogate-key",
+		description: "Tables should have a single-column surrogate primary key",
+		severity:    models.LintSeverityWarning,
+		check:       (*schemaLinter).lintSurrogateKeys,
+	},
+	{
+		id:          "ambiguous-column-name",
+		description: "Column names should describe their content",
+		severity:    models.LintSeverityInfo,
+		check:       (*schemaLinter).lintAmbiguousColumnNames,
+	},
+	{
+		id:          "varchar-without-length",
+		description: "VARCHAR columns should set an explicit length",
+		severity:    models.LintSeverityWarning,
+		check:       (*schemaLinter).lintVarcharLengths,
+	},
+	{
+		id:          "time-without-timezone",
+		description: "Times of day are stored without a time zone",
+		severity:    models.LintSeverityInfo,
+		check:       (*schemaLinter).lintTimeZones,
+	},
+	{
+		id:          "unindexed-foreign-key",
+		description: "Foreign key columns should be indexed",
+		severity:    models.LintSeverityWarning,
+		check:       (*schemaLinter).lintUnindexedForeignKeys,
+	},
+	{
+		id:          "wide-table",
+		description: fmt.Sprintf("Tables should have at most %d columns", wideTableColumns),
+		severity:    models.LintSeverityInfo,
+		check:       (*schemaLinter).lintWideTables,
+	},
+}
+
+// Lint runs the enabled lint rules against the schema definition. Unknown
+// rule IDs in the request are rejected.
+func (l *schemaLinter) Lint(request models.LintRequest) (*models.LintResult, error) {
+	known := make(map[string]bool, len(lintRules))
+	for _, rule := range lintRules {
+		known[rule.id] = true
+	}
+	for id := range request.Rules {
+		if !known[id] {
+			return nil, fmt.Errorf("%w: unknown lint rule '%s'", ErrValidation, id)
+		}
+	}
+
+	result := &models.LintResult{Rules: []models.LintRuleResult{}}
+	for _, rule := range lintRules {
+		override := request.Rules[rule.id]
+		if override.Enabled != nil && !*override.Enabled {
+			continue
+		}
+
+		findings := rule.check(l, request)
+		if len(findings) == 0 {
+			continue
+		}
+
+		severity := rule.severity
+		if override.Severity != "" {
+			severity = override.Severity
+		}
+
+		result.Rules = append(result.Rules, models.LintRuleResult{
+			RuleID:      rule.id,
+			Description: rule.description,
+			Severity:    severity,
+			Findings:    findings,
+		})
+		result.TotalFindings += len(findings)
+	}
+
+	return result, nil
+}
+
+// lintSurrogateKeys reports tables without a primary key or with a composite
+// one
+func (l *schemaLinter) lintSurrogateKeys(request models.LintRequest) []models.LintFinding {
+	var findings []models.LintFinding
+	for _, table := range request.Tables {
+		primaryKeys := 0
+		for _, column := range table.Columns {
+			if column.PrimaryKey {
+				primaryKeys++
+			}
+		}
+
+		switch {
+		case primaryKeys == 0:
+			findings = append(findings, models.LintFinding{
+				Table:   table.Name,
+				Message: "Table has no primary key",
+			})
+		case primaryKeys > 1:
+			findings = append(findings, models.LintFinding{
+				Table:   table.Name,
+				Message: fmt.Sprintf("Table has a composite primary key of %d columns; consider a surrogate key with a unique constraint", primaryKeys),
+			})
+		}
+	}
+	return findings
+}
+
+// lintAmbiguousColumnNames reports single-letter and generic column names
+func (l *schemaLinter) lintAmbiguousColumnNames(request models.LintRequest) []models.LintFinding {
+	var findings []models.LintFinding
+	for _, table := range request.Tables {
+		for _, column := range table.Columns {
+			name := strings.ToLower(column.Name)
+			if len(name) == 1 || ambiguousColumnNames[name] {
+				findings = append(findings, models.LintFinding{
+					Table:   table.Name,
+					Column:  column.Name,
+					Message: fmt.Sprintf("Column name '%s' is ambiguous", column.Name),
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// lintVarcharLengths reports VARCHAR columns relying on the default length
+func (l *schemaLinter) lintVarcharLengths(request models.LintRequest) []models.LintFinding {
+	var findings []models.LintFinding
+	for _, table := range request.Tables {
+		for _, column := range table.Columns {
+			if column.DataType == "VARCHAR" && (column.Length == nil || *column.Length <= 0) {
+				findings = append(findings, models.LintFinding{
+					Table:   table.Name,
+					Column:  column.Name,
+					Message: "VARCHAR column has no length and defaults to VARCHAR(255)",
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// lintTimeZones reports TIME columns, which are generated without a time
+// zone. TIMESTAMP columns are always generated WITH TIME ZONE.
+func (l *schemaLinter) lintTimeZones(request models.LintRequest) []models.LintFinding {
+	var findings []models.LintFinding
+	for _, table := range request.Tables {
+		for _, column := range table.Columns {
+			if column.DataType == "TIME" {
+				findings = append(findings, models.LintFinding{
+					Table:   table.Name,
+					Column:  column.Name,
+					Message: "TIME column has no time zone; use TIMESTAMP to store a point in time",
+				})
+			}
+		}
+	}
+	return findings
+}
+
+// lintUnindexedForeignKeys reports foreign key columns without an index,
+// unless foreign key indexes are generated automatically
+func (l *schemaLinter) lintUnindexedForeignKeys(request models.LintRequest) []models.LintFinding {
+	if autoIndexForeignKeys(request.AutoIndexForeignKeys, l.config) {
+		return nil
+	}
+
+	schemaData := models.SchemaData{
+		Tables:      request.Tables,
+		ForeignKeys: request.ForeignKeys,
+	}
+
+	var findings []models.LintFinding
+	for _, fkColumns := range unindexedForeignKeyColumns(schemaData) {
+		findings = append(findings, models.LintFinding{
+			Table:   fkColumns.table,
+			Column:  strings.Join(fkColumns.columns, ", "),
+			Message: "Foreign key column is not indexed; enable autoIndexForeignKeys or add an index",
+		})
+	}
+	return findings
+}
+
+// lintWideTables reports tables with more than wideTableColumns columns
+func (l *schemaLinter) lintWideTables(request models.LintRequest) []models.LintFinding {
+	var findings []models.LintFinding
+	for _, table := range request.Tables {
+		if len(table.Columns) > wideTableColumns {
+			findings = append(findings, models.LintFinding{
+				Table:   table.Name,
+				Message: fmt.Sprintf("Table has %d columns; consider splitting it", len(table.Columns)),
+			})
+		}
+	}
+	return findings
+}
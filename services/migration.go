@@ -0,0 +1,331 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"vdt-dashboard-backend/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// errMigrationUnsupported reports a change that can't be applied with ALTER
+// statements, so the database has to be regenerated
+var errMigrationUnsupported = errors.New("change cannot be migrated in place")
+
+// MigrateDatabase updates the database generated from old to match new with
+// ALTER statements, keeping the data of tables and columns that survive.
+// Changes that can't be expressed as ALTER statements, such as a different
+// primary key, fall back to RegenerateDatabase. The migration runs in one
+// transaction, so a statement that fails on the existing data, like adding a
+// NOT NULL column without a default to a table with rows, leaves the
+// database as it was.
+func (d *databaseManagerService) MigrateDatabase(ctx context.Context, old, new models.SchemaData, databaseName string) error {
+	sqlGen := &sqlGeneratorService{config: d.config}
+
+	statements, err := sqlGen.generateMigration(old, new)
+	if errors.Is(err, errMigrationUnsupported) {
+		log.Printf("Regenerating database %s: %v", databaseName, err)
+		return d.RegenerateDatabase(ctx, new, databaseName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to generate migration statements: %w", err)
+	}
+
+	db, release, err := d.openDatabase(databaseName, logger.Info)
+	if err != nil {
+		return fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer release()
+
+	// Foreign keys, indexes and unique and check constraints are rebuilt from
+	// the new definition, so they are dropped before the tables change
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := dropForeignKeyConstraints(tx); err != nil {
+			return err
+		}
+		if err := dropColumnConstraints(tx); err != nil {
+			return err
+		}
+		if err := dropIndexes(tx); err != nil {
+			return err
+		}
+
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return fmt.Errorf("failed to execute migration statement: %w\nStatement: %s", err, statement)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	log.Printf("Successfully migrated database %s with %d statements", databaseName, len(statements))
+	return nil
+}
+
+// dropColumnConstraints drops every unique and check constraint in the public
+// schema of the database tx is connected to
+func dropColumnConstraints(tx *gorm.DB) error {
+	var constraints []struct {
+		TableName      string
+		ConstraintName string
+	}
+	err := tx.Raw(`SELECT rel.relname AS table_name, con.conname AS constraint_name
+		FROM pg_constraint con
+		JOIN pg_class rel ON rel.oid = con.conrelid
+		JOIN pg_namespace nsp ON nsp.oid = rel.relnamespace
+		WHERE nsp.nspname = 'public' AND con.contype IN ('u', 'c')`).Scan(&constraints).Error
+	if err != nil {
+		return fmt.Errorf("failed to list constraints: %w", err)
+	}
+
+	for _, constraint := range constraints {
+		statement := fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;",
+			quoteIdentifier(constraint.TableName), quoteIdentifier(constraint.ConstraintName))
+		if err := tx.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to drop constraint: %w\nStatement: %s", err, statement)
+		}
+	}
+	return nil
+}
+
+// dropIndexes drops every index in the public schema of the database tx is
+// connected to that doesn't back a primary key or constraint
+func dropIndexes(tx *gorm.DB) error {
+	var indexes []string
+	err := tx.Raw(`SELECT idx.relname
+		FROM pg_index i
+		JOIN pg_class idx ON idx.oid = i.indexrelid
+		JOIN pg_namespace nsp ON nsp.oid = idx.relnamespace
+		WHERE nsp.nspname = 'public'
+		AND NOT EXISTS (SELECT 1 FROM pg_constraint con WHERE con.conindid = i.indexrelid AND con.contype IN ('p', 'u', 'x'))`).Scan(&indexes).Error
+	if err != nil {
+		return fmt.Errorf("failed to list indexes: %w", err)
+	}
+
+	for _, index := range indexes {
+		statement := fmt.Sprintf("DROP INDEX IF EXISTS %s;", quoteIdentifier(index))
+		if err := tx.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to drop index: %w\nStatement: %s", err, statement)
+		}
+	}
+	return nil
+}
+
+// generateMigration creates the statements turning the tables generated from
+// old into those of new, to run once every foreign key, index and unique and
+// check constraint is dropped. Tables and columns are matched by ID, falling
+// back to their name, so renames keep their data. It returns
+// errMigrationUnsupported when the change needs a full regeneration.
+func (g *sqlGeneratorService) generateMigration(old, new models.SchemaData) ([]string, error) {
+	cased := applyIdentifierCase(new, identifierCase(new.IdentifierCase, g.config))
+	old = applyIdentifierCase(old, identifierCase(old.IdentifierCase, g.config))
+
+	tableKey := func(table models.Table) (string, string) { return table.ID, table.Name }
+	pairs, removed, added := pairDefinitionObjects(old.Tables, cased.Tables, tableKey)
+
+	if name, clash := renameClash(pairs, func(table models.Table) string { return table.Name }); clash {
+		return nil, fmt.Errorf("%w: table '%s' is renamed to the name of another table", errMigrationUnsupported, name)
+	}
+
+	var statements []string
+	for _, table := range removed {
+		statements = append(statements, fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE;", sqlName(table.Name)))
+	}
+	for _, pair := range pairs {
+		if !sameIdentifier(pair[0].Name, pair[1].Name) {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", sqlName(pair[0].Name), sqlName(pair[1].Name)))
+		}
+	}
+	for _, pair := range pairs {
+		tableStatements, err := g.generateAlterTable(pair[0], pair[1])
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, tableStatements...)
+	}
+
+	for _, table := range added {
+		statement, err := g.GenerateCreateTable(table, cased)
+		if err != nil {
+			return nil, err
+		}
+		comments, err := g.GenerateTableComments(table, cased)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, statement)
+		statements = append(statements, comments...)
+	}
+
+	// Unique and check constraints of new tables are part of CREATE TABLE
+	for _, pair := range pairs {
+		statements = append(statements, columnConstraintStatements(pair[1])...)
+	}
+
+	for _, generate := range []func(models.SchemaData) ([]string, error){
+		g.GenerateIndexes,
+		g.GenerateForeignKeys,
+		g.GenerateForeignKeyIndexes,
+	} {
+		generated, err := generate(new)
+		if err != nil {
+			return nil, err
+		}
+		statements = append(statements, generated...)
+	}
+
+	return statements, nil
+}
+
+// generateAlterTable creates the statements changing the columns and
+// comments of a table from old to new, which is already renamed
+func (g *sqlGeneratorService) generateAlterTable(old, new models.Table) ([]string, error) {
+	columnKey := func(column models.Column) (string, string) { return column.ID, column.Name }
+	pairs, removed, added := pairDefinitionObjects(old.Columns, new.Columns, columnKey)
+
+	// A primary key can't be changed without recreating the constraint every
+	// foreign key to the table depends on
+	for _, column := range removed {
+		if column.PrimaryKey {
+			return nil, fmt.Errorf("%w: primary key column '%s.%s' is removed", errMigrationUnsupported, old.Name, column.Name)
+		}
+	}
+	for _, column := range added {
+		if column.PrimaryKey {
+			return nil, fmt.Errorf("%w: primary key column '%s.%s' is added", errMigrationUnsupported, new.Name, column.Name)
+		}
+	}
+	for _, pair := range pairs {
+		oldColumn, newColumn := pair[0], pair[1]
+		if oldColumn.PrimaryKey != newColumn.PrimaryKey {
+			return nil, fmt.Errorf("%w: primary key of table '%s' changes", errMigrationUnsupported, new.Name)
+		}
+		if oldColumn.AutoIncrement != newColumn.AutoIncrement {
+			return nil, fmt.Errorf("%w: autoIncrement of column '%s.%s' changes", errMigrationUnsupported, new.Name, newColumn.Name)
+		}
+	}
+
+	if name, clash := renameClash(pairs, func(column models.Column) string { return column.Name }); clash {
+		return nil, fmt.Errorf("%w: column '%s.%s' is renamed to the name of another column", errMigrationUnsupported, new.Name, name)
+	}
+
+	var statements []string
+	for _, column := range removed {
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", sqlName(new.Name), sqlName(column.Name)))
+	}
+	for _, pair := range pairs {
+		if !sameIdentifier(pair[0].Name, pair[1].Name) {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s;", sqlName(new.Name), sqlName(pair[0].Name), sqlName(pair[1].Name)))
+		}
+	}
+	for _, column := range added {
+		// Check constraints are added back with those of existing columns
+		column.Check = nil
+		statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", sqlName(new.Name), g.generateColumnDefinition(column)))
+	}
+
+	for _, pair := range pairs {
+		oldColumn, newColumn := pair[0], pair[1]
+		alter := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", sqlName(new.Name), sqlName(newColumn.Name))
+
+		oldType, newType := postgresColumnType(oldColumn), postgresColumnType(newColumn)
+		oldDefault, newDefault := columnDefault(oldColumn), columnDefault(newColumn)
+		if oldType != newType {
+			if newColumn.AutoIncrement {
+				return nil, fmt.Errorf("%w: type of auto-increment column '%s.%s' changes", errMigrationUnsupported, new.Name, newColumn.Name)
+			}
+			// The old default may not cast to the new type
+			if oldDefault != "" {
+				statements = append(statements, alter+" DROP DEFAULT;")
+				oldDefault = ""
+			}
+			statements = append(statements, fmt.Sprintf("%s TYPE %s USING %s::%s;", alter, newType, sqlName(newColumn.Name), newType))
+		}
+		if oldDefault != newDefault && !newColumn.AutoIncrement {
+			if newDefault == "" {
+				statements = append(statements, alter+" DROP DEFAULT;")
+			} else {
+				statements = append(statements, fmt.Sprintf("%s SET DEFAULT %s;", alter, newDefault))
+			}
+		}
+		if oldColumn.IsNullable() != newColumn.IsNullable() {
+			if newColumn.IsNullable() {
+				statements = append(statements, alter+" DROP NOT NULL;")
+			} else {
+				statements = append(statements, alter+" SET NOT NULL;")
+			}
+		}
+	}
+
+	if old.Comment != new.Comment {
+		statements = append(statements, fmt.Sprintf("COMMENT ON TABLE %s IS %s;", sqlName(new.Name), commentLiteral(new.Comment)))
+	}
+	for _, pair := range pairs {
+		if pair[0].Comment != pair[1].Comment {
+			statements = append(statements, fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s;", sqlName(new.Name), sqlName(pair[1].Name), commentLiteral(pair[1].Comment)))
+		}
+	}
+	for _, column := range added {
+		if column.Comment != "" {
+			statements = append(statements, fmt.Sprintf("COMMENT ON COLUMN %s.%s IS %s;", sqlName(new.Name), sqlName(column.Name), commentLiteral(column.Comment)))
+		}
+	}
+
+	return statements, nil
+}
+
+// columnConstraintStatements creates the unique and check constraints
+// CREATE TABLE declares for the columns of an existing table
+func columnConstraintStatements(table models.Table) []string {
+	var statements []string
+	for _, column := range orderedColumns(table.Columns) {
+		if column.Unique && !column.PrimaryKey {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD UNIQUE (%s);", sqlName(table.Name), sqlName(column.Name)))
+		}
+		if column.Check != nil {
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD CHECK (%s);", sqlName(table.Name), strings.TrimSpace(*column.Check)))
+		}
+	}
+	return statements
+}
+
+// renameClash reports a renamed object whose new name is still held by
+// another object when renames run one at a time, returning its old name
+func renameClash[T any](pairs [][2]T, name func(T) string) (string, bool) {
+	existing := make(map[string]int)
+	for i, pair := range pairs {
+		existing[strings.ToLower(name(pair[0]))] = i
+	}
+	for i, pair := range pairs {
+		oldName, newName := name(pair[0]), name(pair[1])
+		if sameIdentifier(oldName, newName) {
+			continue
+		}
+		if j, exists := existing[strings.ToLower(newName)]; exists && j != i {
+			return oldName, true
+		}
+	}
+	return "", false
+}
+
+// commentLiteral renders a comment for COMMENT ON, where NULL removes it
+func commentLiteral(comment string) string {
+	if comment == "" {
+		return "NULL"
+	}
+	return quoteLiteral(comment)
+}
+
+// sameIdentifier reports whether two unquoted identifiers name the same
+// object, which PostgreSQL folds to lower case
+func sameIdentifier(a, b string) bool {
+	return strings.ToLower(a) == strings.ToLower(b)
+}
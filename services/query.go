@@ -0,0 +1,279 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// QueryOptions bound an ad-hoc query: at most RowLimit rows and MaxBytes of
+// encoded rows are returned, and the query is cancelled after Timeout. It
+// runs as the database role Role.
+type QueryOptions struct {
+	RowLimit int
+	MaxBytes int
+	Timeout  time.Duration
+	Role     string
+}
+
+// queryForbiddenWords are keywords and functions rejected anywhere in an
+// ad-hoc query. Queries also run in a read-only transaction; this list
+// catches writes early and blocks server functions a read-only transaction
+// still allows.
+var queryForbiddenWords = map[string]bool{
+	"insert":               true,
+	"update":               true,
+	"delete":               true,
+	"merge":                true,
+	"create":               true,
+	"alter":                true,
+	"drop":                 true,
+	"truncate":             true,
+	"grant":                true,
+	"revoke":               true,
+	"copy":                 true,
+	"into":                 true,
+	"dblink":               true,
+	"set_config":           true,
+	"pg_read_file":         true,
+	"pg_read_binary_file":  true,
+	"pg_ls_dir":            true,
+	"pg_stat_file":         true,
+	"pg_terminate_backend": true,
+	"pg_cancel_backend":    true,
+	"pg_reload_conf":       true,
+	"lo_import":            true,
+	"lo_export":            true,
+	// These run a query given as a string, which the word list can't see into
+	"query_to_xml":               true,
+	"query_to_xmlschema":         true,
+	"query_to_xml_and_xmlschema": true,
+}
+
+// ExecuteQuery runs a read-only query against the database of a schema the
+// user owns and returns at most config.QueryRowLimit rows. The query is
+// cancelled when ctx is.
+func (s *schemaService) ExecuteQuery(ctx context.Context, id, userID uuid.UUID, request models.QueryRequest) (*models.QueryResponse, error) {
+	schema, err := s.ownedSchema(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	query, err := readOnlyQuery(request.SQL)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.databaseManager.RunQuery(ctx, schema.DatabaseName, query, QueryOptions{
+		RowLimit: s.config.QueryRowLimit,
+		MaxBytes: s.config.QueryMaxBytes,
+		Timeout:  s.config.QueryTimeout,
+		Role:     s.config.QueryRole,
+	})
+}
+
+// readOnlyQuery checks that query is a single SELECT statement, returning it
+// without its trailing semicolon. Strings and comments are skipped, so a
+// keyword inside a literal doesn't count. Quoted identifiers are checked like
+// plain words, as "dblink" still names the function.
+func readOnlyQuery(query string) (string, error) {
+	tokens, err := tokenizeSQL(query)
+	if err != nil {
+		return "", fmt.Errorf("%w: %v", ErrValidation, err)
+	}
+	if len(tokens) > 0 && tokens[len(tokens)-1].is(";") {
+		query = query[:tokens[len(tokens)-1].start]
+		tokens = tokens[:len(tokens)-1]
+	}
+	if len(tokens) == 0 {
+		return "", fmt.Errorf("%w: query is empty", ErrValidation)
+	}
+	if !tokens[0].is("SELECT") {
+		return "", fmt.Errorf("%w: only SELECT queries are allowed", ErrValidation)
+	}
+
+	for i, token := range tokens {
+		if token.is(";") {
+			return "", fmt.Errorf("%w: only a single statement is allowed", ErrValidation)
+		}
+		// U&"d\0062link" spells an identifier the word list can't match
+		if token.is("U") && i+1 < len(tokens) && tokens[i+1].is("&") && tokens[i+1].start == token.end {
+			return "", fmt.Errorf("%w: Unicode escapes are not allowed in a query (line %d)", ErrValidation, token.line)
+		}
+		if (token.kind == sqlWord || token.kind == sqlQuoted) && queryForbiddenWords[strings.ToLower(token.text)] {
+			return "", fmt.Errorf("%w: '%s' is not allowed in a query (line %d)", ErrValidation, token.text, token.line)
+		}
+	}
+
+	return query, nil
+}
+
+// RunQuery runs query as options.Role in a read-only transaction on a
+// generated database, cancelling it after options.Timeout or when ctx is
+// cancelled. The role is granted SELECT on the database's tables first, so it
+// can read them but nothing else. Rows are encoded as they are read and stop
+// at options.RowLimit rows or options.MaxBytes bytes; Truncated reports
+// whether there were more. Errors raised by the query itself are returned as
+// ErrValidation, timeouts as ErrQueryTimeout and cancellations as
+// ErrQueryCanceled.
+func (d *databaseManagerService) RunQuery(ctx context.Context, databaseName, query string, options QueryOptions) (*models.QueryResponse, error) {
+	db, release, err := d.openDatabase(databaseName, logger.Silent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer release()
+	db = db.WithContext(ctx)
+
+	// Granting on every query also covers tables created by later generations
+	role := quoteIdentifier(options.Role)
+	if err := db.Exec("GRANT SELECT ON ALL TABLES IN SCHEMA public TO " + role).Error; err != nil {
+		return nil, canceledQueryError(ctx, fmt.Errorf("failed to grant query role %s: %w", options.Role, err))
+	}
+
+	var response *models.QueryResponse
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SET TRANSACTION READ ONLY").Error; err != nil {
+			return fmt.Errorf("failed to start read-only transaction: %w", err)
+		}
+		if err := tx.Exec("SET LOCAL ROLE " + role).Error; err != nil {
+			return fmt.Errorf("failed to switch to query role %s: %w", options.Role, err)
+		}
+		if err := tx.Exec(fmt.Sprintf("SET LOCAL statement_timeout = %d", options.Timeout.Milliseconds())).Error; err != nil {
+			return fmt.Errorf("failed to set statement timeout: %w", err)
+		}
+
+		rows, err := tx.Raw(query).Rows()
+		if err != nil {
+			return queryError(ctx, err)
+		}
+		defer rows.Close()
+
+		response, err = encodeQueryRows(rows, options.RowLimit, options.MaxBytes)
+		if err != nil {
+			return queryError(ctx, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, canceledQueryError(ctx, err)
+	}
+
+	return response, nil
+}
+
+// encodeQueryRows reads rows into a response, encoding each row as JSON as
+// soon as it is scanned so only the encoded result is held in memory. It
+// stops after limit rows, or before the encoded rows would exceed maxBytes.
+func encodeQueryRows(rows *sql.Rows, limit, maxBytes int) (*models.QueryResponse, error) {
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result columns: %w", err)
+	}
+	response := &models.QueryResponse{Columns: make([]string, len(columnTypes))}
+	for i, columnType := range columnTypes {
+		response.Columns[i] = columnType.Name()
+	}
+
+	var encoded bytes.Buffer
+	encoded.WriteByte('[')
+	values := make([]any, len(columnTypes))
+	pointers := make([]any, len(columnTypes))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+	for rows.Next() {
+		if response.RowCount == limit {
+			response.Truncated = true
+			break
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			return nil, fmt.Errorf("%w: failed to read row: %v", ErrValidation, err)
+		}
+		for i, value := range values {
+			values[i] = queryValue(value, columnTypes[i].DatabaseTypeName())
+		}
+		row, err := json.Marshal(values)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode row: %w", err)
+		}
+		// One byte each for the separator and the closing bracket
+		if encoded.Len()+len(row)+2 > maxBytes {
+			response.Truncated = true
+			break
+		}
+		if response.RowCount > 0 {
+			encoded.WriteByte(',')
+		}
+		encoded.Write(row)
+		response.RowCount++
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	encoded.WriteByte(']')
+	response.Rows = encoded.Bytes()
+	return response, nil
+}
+
+// queryError classifies an error raised while running an ad-hoc query:
+// cancellation and timeouts as by canceledQueryError, anything else as
+// ErrValidation, since the query itself failed
+func queryError(ctx context.Context, err error) error {
+	if err := canceledQueryError(ctx, err); errors.Is(err, ErrQueryCanceled) || errors.Is(err, ErrQueryTimeout) || errors.Is(err, ErrValidation) {
+		return err
+	}
+	return fmt.Errorf("%w: query failed: %v", ErrValidation, err)
+}
+
+// canceledQueryError returns ErrQueryCanceled when ctx was cancelled, e.g.
+// by the client disconnecting, and ErrQueryTimeout when a deadline or the
+// statement_timeout was exceeded. Other errors are returned as is.
+func canceledQueryError(ctx context.Context, err error) error {
+	if errors.Is(err, ErrQueryCanceled) || errors.Is(err, ErrQueryTimeout) {
+		return err
+	}
+
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, context.Canceled) || errors.Is(ctx.Err(), context.Canceled):
+		return fmt.Errorf("%w: %v", ErrQueryCanceled, err)
+	case errors.Is(err, context.DeadlineExceeded) || errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("%w: %v", ErrQueryTimeout, err)
+	case errors.As(err, &pgErr) && pgErr.Code == "57014":
+		// query_canceled, raised by statement_timeout
+		return fmt.Errorf("%w: %s", ErrQueryTimeout, pgErr.Message)
+	}
+	return err
+}
+
+// queryValue converts a scanned column value to a value that encodes
+// readably as JSON. JSON columns are embedded as is and other byte values
+// become strings.
+func queryValue(value any, typeName string) any {
+	bytes, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+	switch typeName {
+	case "JSON", "JSONB":
+		if json.Valid(bytes) {
+			return json.RawMessage(bytes)
+		}
+	case "BYTEA":
+		return `\x` + fmt.Sprintf("%x", bytes)
+	}
+	return string(bytes)
+}
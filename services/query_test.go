@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+func TestReadOnlyQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string // Accepted query, empty when it must be rejected
+	}{
+		{name: "select", query: "SELECT id, email FROM users", want: "SELECT id, email FROM users"},
+		{name: "trailing semicolon", query: "SELECT 1;", want: "SELECT 1"},
+		{name: "keyword in a string", query: "SELECT 'drop table users' AS note", want: "SELECT 'drop table users' AS note"},
+		{name: "keyword in a comment", query: "SELECT 1 -- then drop it", want: "SELECT 1 -- then drop it"},
+		{name: "escape string", query: `SELECT E'it\'s; drop'`, want: `SELECT E'it\'s; drop'`},
+		{name: "drop", query: "DROP TABLE users"},
+		{name: "insert", query: "INSERT INTO users (id) VALUES (1)"},
+		{name: "select into", query: "SELECT * INTO copied FROM users"},
+		{name: "data-modifying CTE", query: "WITH gone AS (DELETE FROM users RETURNING *) SELECT * FROM gone"},
+		{name: "multiple statements", query: "SELECT 1; DROP TABLE users"},
+		{name: "multiple selects", query: "SELECT 1; SELECT 2"},
+		{name: "statement after a comment", query: "SELECT 1; -- harmless\nDELETE FROM users"},
+		{name: "quoted function", query: `SELECT "pg_read_file"('/etc/passwd')`},
+		{name: "query in a string", query: "SELECT query_to_xml('SELECT pg_read_file(''/etc/passwd'')', true, false, '')"},
+		{name: "quoted dblink", query: `SELECT * FROM "dblink"('host=db', 'SELECT 1') AS t(x int)`},
+		{name: "unicode escaped identifier", query: `SELECT U&"pg\005fread\005ffile"('/etc/passwd')`},
+		{name: "statement after an escape string", query: `SELECT E'\''; DROP TABLE users; SELECT 'x`},
+		{name: "empty", query: " ; "},
+		{name: "unterminated string", query: "SELECT 'open"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := readOnlyQuery(test.query)
+			if test.want == "" {
+				if !errors.Is(err, ErrValidation) {
+					t.Fatalf("readOnlyQuery(%q) = %q, %v, want ErrValidation", test.query, got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("readOnlyQuery(%q) = %v", test.query, err)
+			}
+			if got != test.want {
+				t.Fatalf("readOnlyQuery(%q) = %q, want %q", test.query, got, test.want)
+			}
+		})
+	}
+}
+
+func TestEncodeQueryRows(t *testing.T) {
+	db := openTestDatabase(t)
+	query := `SELECT 1 AS id, 'first' AS name UNION ALL SELECT 2, 'second' UNION ALL SELECT 3, 'third'`
+
+	tests := []struct {
+		name          string
+		limit         int
+		maxBytes      int
+		wantRows      string
+		wantTruncated bool
+	}{
+		{name: "all rows", limit: 10, maxBytes: 1024, wantRows: `[[1,"first"],[2,"second"],[3,"third"]]`},
+		{name: "row limit", limit: 2, maxBytes: 1024, wantRows: `[[1,"first"],[2,"second"]]`, wantTruncated: true},
+		{name: "byte limit", limit: 10, maxBytes: 27, wantRows: `[[1,"first"],[2,"second"]]`, wantTruncated: true},
+		{name: "first row too large", limit: 10, maxBytes: 5, wantRows: `[]`, wantTruncated: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			rows, err := db.Raw(query).Rows()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rows.Close()
+
+			response, err := encodeQueryRows(rows, test.limit, test.maxBytes)
+			if err != nil {
+				t.Fatalf("encodeQueryRows() = %v", err)
+			}
+			if fmt.Sprint(response.Columns) != "[id name]" {
+				t.Fatalf("Columns = %v, want [id name]", response.Columns)
+			}
+			if string(response.Rows) != test.wantRows {
+				t.Fatalf("Rows = %s, want %s", response.Rows, test.wantRows)
+			}
+			if len(response.Rows) > test.maxBytes {
+				t.Fatalf("Rows are %d bytes, want at most %d", len(response.Rows), test.maxBytes)
+			}
+			if response.Truncated != test.wantTruncated {
+				t.Fatalf("Truncated = %v, want %v", response.Truncated, test.wantTruncated)
+			}
+		})
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		err  error
+		want error
+	}{
+		{name: "client disconnected", ctx: canceled, err: errors.New("conn closed"), want: ErrQueryCanceled},
+		{name: "canceled error", ctx: context.Background(), err: fmt.Errorf("scan: %w", context.Canceled), want: ErrQueryCanceled},
+		{name: "deadline", ctx: context.Background(), err: context.DeadlineExceeded, want: ErrQueryTimeout},
+		{name: "statement timeout", ctx: context.Background(), err: &pgconn.PgError{Code: "57014", Message: "canceling statement due to statement timeout"}, want: ErrQueryTimeout},
+		{name: "already classified", ctx: canceled, err: fmt.Errorf("%w: slow", ErrQueryTimeout), want: ErrQueryTimeout},
+		{name: "query error", ctx: context.Background(), err: &pgconn.PgError{Code: "42P01", Message: `relation "missing" does not exist`}, want: ErrValidation},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			if got := queryError(test.ctx, test.err); !errors.Is(got, test.want) {
+				t.Fatalf("queryError() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package services
+
+import "strings"
+
+// reservedWords are the PostgreSQL key words that can't be used as table or
+// column names unquoted, including those only allowed as function or type
+// names
+var reservedWords = map[string]bool{
+	"all": true, "analyse": true, "analyze": true, "and": true, "any": true,
+	"array": true, "as": true, "asc": true, "asymmetric": true,
+	"authorization": true, "binary": true, "both": true, "case": true,
+	"cast": true, "check": true, "collate": true, "collation": true,
+	"column": true, "concurrently": true, "constraint": true, "create": true,
+	"cross": true, "current_catalog": true, "current_date": true,
+	"current_role": true, "current_schema": true, "current_time": true,
+	"current_timestamp": true, "current_user": true, "default": true,
+	"deferrable": true, "desc": true, "distinct": true, "do": true,
+	"else": true, "end": true, "except": true, "false": true, "fetch": true,
+	"for": true, "foreign": true, "freeze": true, "from": true, "full": true,
+	"grant": true, "group": true, "having": true, "ilike": true, "in": true,
+	"initially": true, "inner": true, "intersect": true, "into": true,
+	"is": true, "isnull": true, "join": true, "lateral": true,
+	"leading": true, "left": true, "like": true, "limit": true,
+	"localtime": true, "localtimestamp": true, "natural": true, "not": true,
+	"notnull": true, "null": true, "offset": true, "on": true, "only": true,
+	"or": true, "order": true, "outer": true, "overlaps": true,
+	"placing": true, "primary": true, "references": true, "returning": true,
+	"right": true, "select": true, "session_user": true, "similar": true,
+	"some": true, "symmetric": true, "system_user": true, "table": true,
+	"tablesample": true, "then": true, "to": true, "trailing": true,
+	"true": true, "union": true, "unique": true, "user": true, "using": true,
+	"variadic": true, "verbose": true, "when": true, "where": true,
+	"window": true, "with": true,
+}
+
+// isReservedWord reports whether name is a reserved PostgreSQL key word
+func isReservedWord(name string) bool {
+	return reservedWords[strings.ToLower(name)]
+}
+
+// sqlName renders a generated name for SQL. Names are emitted unquoted, so
+// PostgreSQL folds them to lower case, except reserved words, which are
+// quoted in lower case to name the same object.
+func sqlName(name string) string {
+	if isReservedWord(name) {
+		return quoteIdentifier(strings.ToLower(name))
+	}
+	return name
+}
+
+// sqlNames renders a list of generated names for SQL, separated by commas
+func sqlNames(names []string) string {
+	rendered := make([]string, len(names))
+	for i, name := range names {
+		rendered[i] = sqlName(name)
+	}
+	return strings.Join(rendered, ", ")
+}
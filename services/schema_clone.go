@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// CloneSchema creates a new schema named newName from a copy of an existing
+// schema's definition and provisions its own database. Tables, columns and
+// foreign keys get new IDs so the copy shares nothing with the original.
+func (s *schemaService) CloneSchema(ctx context.Context, id, userID uuid.UUID, newName string) (*models.Schema, error) {
+	source, err := s.repo.GetByIDAndUserID(id, userID)
+	if err != nil {
+		return nil, schemaLookupError(id, err)
+	}
+
+	definition, err := cloneSchemaData(source.SchemaDefinition)
+	if err != nil {
+		return nil, err
+	}
+
+	// Creating goes through the same duplicate-name check, validation and
+	// provisioning as a new schema
+	return s.CreateSchema(ctx, models.CreateSchemaRequest{
+		Name:                 newName,
+		Description:          source.Description,
+		Tables:               definition.Tables,
+		ForeignKeys:          definition.ForeignKeys,
+		AutoIndexForeignKeys: definition.AutoIndexForeignKeys,
+		IdentifierCase:       definition.IdentifierCase,
+	}, userID)
+}
+
+// cloneSchemaData returns a deep copy of schemaData with new table, column
+// and foreign key IDs. References to the old IDs from foreign keys and index
+// columns are updated; index columns given by name are kept.
+func cloneSchemaData(schemaData models.SchemaData) (models.SchemaData, error) {
+	// A JSON round trip copies every nested slice, pointer and default value
+	data, err := json.Marshal(schemaData)
+	if err != nil {
+		return models.SchemaData{}, fmt.Errorf("failed to copy schema definition: %w", err)
+	}
+	var clone models.SchemaData
+	if err := json.Unmarshal(data, &clone); err != nil {
+		return models.SchemaData{}, fmt.Errorf("failed to copy schema definition: %w", err)
+	}
+
+	tableIDs := make(map[string]string)
+	columnIDs := make(map[string]string)
+	for i := range clone.Tables {
+		table := &clone.Tables[i]
+		tableIDs[table.ID] = uuid.New().String()
+		table.ID = tableIDs[table.ID]
+
+		for j := range table.Columns {
+			column := &table.Columns[j]
+			columnIDs[column.ID] = uuid.New().String()
+			column.ID = columnIDs[column.ID]
+		}
+		for j := range table.Indexes {
+			for k, column := range table.Indexes[j].Columns {
+				if newID, exists := columnIDs[column]; exists {
+					table.Indexes[j].Columns[k] = newID
+				}
+			}
+		}
+	}
+
+	for i := range clone.ForeignKeys {
+		fk := &clone.ForeignKeys[i]
+		fk.ID = uuid.New().String()
+		fk.SourceTableId = remapID(fk.SourceTableId, tableIDs)
+		fk.TargetTableId = remapID(fk.TargetTableId, tableIDs)
+		fk.SourceColumnId = remapID(fk.SourceColumnId, columnIDs)
+		fk.TargetColumnId = remapID(fk.TargetColumnId, columnIDs)
+		for j, columnID := range fk.SourceColumnIds {
+			fk.SourceColumnIds[j] = remapID(columnID, columnIDs)
+		}
+		for j, columnID := range fk.TargetColumnIds {
+			fk.TargetColumnIds[j] = remapID(columnID, columnIDs)
+		}
+	}
+
+	return clone, nil
+}
+
+// remapID returns the new ID of id, or id itself when it wasn't reassigned
+func remapID(id string, ids map[string]string) string {
+	if newID, exists := ids[id]; exists {
+		return newID
+	}
+	return id
+}
@@ -0,0 +1,239 @@
+package services
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"vdt-dashboard-backend/config"
+	"vdt-dashboard-backend/models"
+)
+
+// diffSchemas compares two schema definitions by table and column name.
+// Objects only in target are reported as added, objects only in base as removed.
+// Missing foreign key actions are compared as the configured defaults.
+func diffSchemas(base, target models.SchemaData, cfg *config.Config) models.SchemaDiff {
+	diff := models.SchemaDiff{
+		Added:   []models.SchemaObject{},
+		Removed: []models.SchemaObject{},
+		Changed: []models.SchemaObject{},
+	}
+
+	baseTables := tablesByName(base)
+	targetTables := tablesByName(target)
+
+	for _, name := range sortedKeys(baseTables) {
+		baseTable := baseTables[name]
+		targetTable, exists := targetTables[name]
+		if !exists {
+			diff.Removed = append(diff.Removed, models.SchemaObject{Kind: "table", Name: name})
+			continue
+		}
+		diffColumns(&diff, baseTable, targetTable)
+	}
+
+	for _, name := range sortedKeys(targetTables) {
+		if _, exists := baseTables[name]; !exists {
+			diff.Added = append(diff.Added, models.SchemaObject{Kind: "table", Name: name})
+		}
+	}
+
+	diffForeignKeys(&diff, base, target, cfg)
+
+	return diff
+}
+
+// diffColumns adds the column differences between two versions of a table,
+// in generated DDL order. Reordering columns is not reported as a change.
+func diffColumns(diff *models.SchemaDiff, base, target models.Table) {
+	baseColumns := make(map[string]models.Column)
+	for _, column := range base.Columns {
+		baseColumns[column.Name] = column
+	}
+	targetColumns := make(map[string]models.Column)
+	for _, column := range target.Columns {
+		targetColumns[column.Name] = column
+	}
+
+	for _, column := range orderedColumns(base.Columns) {
+		name := base.Name + "." + column.Name
+		targetColumn, exists := targetColumns[column.Name]
+		if !exists {
+			diff.Removed = append(diff.Removed, models.SchemaObject{Kind: "column", Name: name})
+			continue
+		}
+		if changes := columnChanges(column, targetColumn); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, models.SchemaObject{
+				Kind:    "column",
+				Name:    name,
+				Details: strings.Join(changes, "; "),
+			})
+		}
+	}
+
+	for _, column := range orderedColumns(target.Columns) {
+		if _, exists := baseColumns[column.Name]; !exists {
+			diff.Added = append(diff.Added, models.SchemaObject{Kind: "column", Name: target.Name + "." + column.Name})
+		}
+	}
+}
+
+// columnChanges describes how a column differs between two definitions
+func columnChanges(base, target models.Column) []string {
+	var changes []string
+	if baseType, targetType := describeColumnType(base), describeColumnType(target); baseType != targetType {
+		changes = append(changes, fmt.Sprintf("type %s -> %s", baseType, targetType))
+	}
+	return append(changes, columnConstraintChanges(base, target)...)
+}
+
+// columnConstraintChanges describes how the nullability, primary key and
+// unique constraint of a column differ between two definitions
+func columnConstraintChanges(base, target models.Column) []string {
+	var changes []string
+
+	// Primary key columns are always NOT NULL in the database
+	baseNullable := base.IsNullable()
+	targetNullable := target.IsNullable()
+	if baseNullable != targetNullable {
+		changes = append(changes, fmt.Sprintf("nullable %t -> %t", baseNullable, targetNullable))
+	}
+	if base.PrimaryKey != target.PrimaryKey {
+		changes = append(changes, fmt.Sprintf("primaryKey %t -> %t", base.PrimaryKey, target.PrimaryKey))
+	}
+	if base.Unique != target.Unique && !base.PrimaryKey && !target.PrimaryKey {
+		changes = append(changes, fmt.Sprintf("unique %t -> %t", base.Unique, target.Unique))
+	}
+
+	return changes
+}
+
+// describeColumnType renders a column type with its size parameters
+func describeColumnType(column models.Column) string {
+	if column.IsArray {
+		element := column
+		element.IsArray = false
+		return describeColumnType(element) + "[]"
+	}
+
+	switch column.DataType {
+	case "VARCHAR":
+		length := 255
+		if column.Length != nil && *column.Length > 0 {
+			length = *column.Length
+		}
+		return fmt.Sprintf("VARCHAR(%d)", length)
+	case "DECIMAL":
+		precision, scale := 10, 2
+		if column.Precision != nil {
+			precision = *column.Precision
+		}
+		if column.Scale != nil {
+			scale = *column.Scale
+		}
+		return fmt.Sprintf("DECIMAL(%d,%d)", precision, scale)
+	default:
+		return column.DataType
+	}
+}
+
+// diffForeignKeys adds the foreign key differences. Foreign keys are matched
+// by the columns they connect since constraint names may be generated.
+func diffForeignKeys(diff *models.SchemaDiff, base, target models.SchemaData, cfg *config.Config) {
+	baseKeys := foreignKeysByColumns(base)
+	targetKeys := foreignKeysByColumns(target)
+
+	for _, name := range sortedKeys(baseKeys) {
+		baseKey := baseKeys[name]
+		targetKey, exists := targetKeys[name]
+		if !exists {
+			diff.Removed = append(diff.Removed, models.SchemaObject{Kind: "foreignKey", Name: name})
+			continue
+		}
+
+		var changes []string
+		baseOnDelete := effectiveForeignKeyAction(baseKey.OnDelete, cfg.DefaultFKOnDelete)
+		targetOnDelete := effectiveForeignKeyAction(targetKey.OnDelete, cfg.DefaultFKOnDelete)
+		if baseOnDelete != targetOnDelete {
+			changes = append(changes, fmt.Sprintf("onDelete %s -> %s", baseOnDelete, targetOnDelete))
+		}
+		baseOnUpdate := effectiveForeignKeyAction(baseKey.OnUpdate, cfg.DefaultFKOnUpdate)
+		targetOnUpdate := effectiveForeignKeyAction(targetKey.OnUpdate, cfg.DefaultFKOnUpdate)
+		if baseOnUpdate != targetOnUpdate {
+			changes = append(changes, fmt.Sprintf("onUpdate %s -> %s", baseOnUpdate, targetOnUpdate))
+		}
+		if len(changes) > 0 {
+			diff.Changed = append(diff.Changed, models.SchemaObject{
+				Kind:    "foreignKey",
+				Name:    name,
+				Details: strings.Join(changes, "; "),
+			})
+		}
+	}
+
+	for _, name := range sortedKeys(targetKeys) {
+		if _, exists := baseKeys[name]; !exists {
+			diff.Added = append(diff.Added, models.SchemaObject{Kind: "foreignKey", Name: name})
+		}
+	}
+}
+
+// foreignKeysByColumns indexes foreign keys by "source.column -> target.column",
+// with composite keys listed as "source.(a, b) -> target.(x, y)"
+func foreignKeysByColumns(schemaData models.SchemaData) map[string]models.ForeignKey {
+	tableNames := make(map[string]string)
+	columnNames := make(map[string]string)
+	for _, table := range schemaData.Tables {
+		tableNames[table.ID] = table.Name
+		for _, column := range table.Columns {
+			columnNames[column.ID] = column.Name
+		}
+	}
+
+	keys := make(map[string]models.ForeignKey)
+	for _, fk := range schemaData.ForeignKeys {
+		key := fmt.Sprintf("%s -> %s",
+			columnListLabel(tableNames[fk.SourceTableId], fk.SourceColumns(), columnNames),
+			columnListLabel(tableNames[fk.TargetTableId], fk.TargetColumns(), columnNames))
+		keys[key] = fk
+	}
+	return keys
+}
+
+// columnListLabel formats columns as "table.column" or "table.(a, b)"
+func columnListLabel(table string, columnIDs []string, columnNames map[string]string) string {
+	names := make([]string, len(columnIDs))
+	for i, id := range columnIDs {
+		names[i] = columnNames[id]
+	}
+	if len(names) == 1 {
+		return table + "." + names[0]
+	}
+	return fmt.Sprintf("%s.(%s)", table, strings.Join(names, ", "))
+}
+
+// effectiveForeignKeyAction returns the action the generator applies: the
+// given action when valid, otherwise the configured fallback
+func effectiveForeignKeyAction(action, fallback string) string {
+	if action != "" && models.ValidForeignKeyActions[action] {
+		return action
+	}
+	return fallback
+}
+
+func tablesByName(schemaData models.SchemaData) map[string]models.Table {
+	tables := make(map[string]models.Table)
+	for _, table := range schemaData.Tables {
+		tables[table.Name] = table
+	}
+	return tables
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,256 @@
+package services
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"vdt-dashboard-backend/config"
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// SchemaDiffService defines the interface for comparing schema definitions
+type SchemaDiffService interface {
+	Diff(old, new models.SchemaData) models.SchemaDiff
+}
+
+// NewSchemaDiffService creates a new schema diff service
+func NewSchemaDiffService(cfg *config.Config) SchemaDiffService {
+	return &schemaDiffService{
+		config: cfg,
+	}
+}
+
+type schemaDiffService struct {
+	config *config.Config
+}
+
+// Diff compares two versions of a schema definition. Tables, columns and
+// foreign keys are matched by ID, falling back to their name, so a renamed
+// object is reported as renamed rather than removed and added again. Names
+// follow each definition's identifier case policy and column types are
+// compared as the PostgreSQL types they are generated as, so only changes
+// that alter the generated database are reported.
+func (d *schemaDiffService) Diff(old, new models.SchemaData) models.SchemaDiff {
+	old = applyIdentifierCase(old, identifierCase(old.IdentifierCase, d.config))
+	new = applyIdentifierCase(new, identifierCase(new.IdentifierCase, d.config))
+
+	diff := models.SchemaDiff{
+		Added:   []models.SchemaObject{},
+		Removed: []models.SchemaObject{},
+		Changed: []models.SchemaObject{},
+		Renamed: []models.SchemaObject{},
+	}
+
+	tableKey := func(table models.Table) (string, string) { return table.ID, table.Name }
+	pairs, removed, added := pairDefinitionObjects(old.Tables, new.Tables, tableKey)
+	for _, table := range removed {
+		diff.Removed = append(diff.Removed, models.SchemaObject{Kind: "table", Name: table.Name})
+	}
+	for _, pair := range pairs {
+		oldTable, newTable := pair[0], pair[1]
+		if oldTable.Name != newTable.Name {
+			diff.Renamed = append(diff.Renamed, models.SchemaObject{Kind: "table", Name: newTable.Name, PreviousName: oldTable.Name})
+		}
+		diffDefinitionColumns(&diff, oldTable, newTable)
+	}
+	for _, table := range added {
+		diff.Added = append(diff.Added, models.SchemaObject{Kind: "table", Name: table.Name})
+	}
+
+	d.diffDefinitionForeignKeys(&diff, old, new)
+
+	return diff
+}
+
+// diffDefinitionColumns adds the column differences between two versions of
+// a table. Columns are named after the new version of the table, except those
+// that were removed.
+func diffDefinitionColumns(diff *models.SchemaDiff, old, new models.Table) {
+	columnKey := func(column models.Column) (string, string) { return column.ID, column.Name }
+	pairs, removed, added := pairDefinitionObjects(old.Columns, new.Columns, columnKey)
+
+	for _, column := range removed {
+		diff.Removed = append(diff.Removed, models.SchemaObject{Kind: "column", Name: old.Name + "." + column.Name})
+	}
+	for _, pair := range pairs {
+		oldColumn, newColumn := pair[0], pair[1]
+		name := new.Name + "." + newColumn.Name
+		if oldColumn.Name != newColumn.Name {
+			diff.Renamed = append(diff.Renamed, models.SchemaObject{Kind: "column", Name: name, PreviousName: old.Name + "." + oldColumn.Name})
+		}
+		if changes := definitionColumnChanges(oldColumn, newColumn); len(changes) > 0 {
+			diff.Changed = append(diff.Changed, models.SchemaObject{
+				Kind:    "column",
+				Name:    name,
+				Details: strings.Join(changes, "; "),
+			})
+		}
+	}
+	for _, column := range added {
+		diff.Added = append(diff.Added, models.SchemaObject{Kind: "column", Name: new.Name + "." + column.Name})
+	}
+}
+
+// definitionColumnChanges describes how a column differs between two
+// definitions. Types are compared as generated, so a VARCHAR without a length
+// equals VARCHAR(255), unknown types equal TEXT, and toggling autoIncrement
+// changes an integer column to or from SERIAL.
+func definitionColumnChanges(old, new models.Column) []string {
+	var changes []string
+	if oldType, newType := postgresColumnType(old), postgresColumnType(new); oldType != newType {
+		changes = append(changes, fmt.Sprintf("type %s -> %s", oldType, newType))
+	}
+	return append(changes, columnConstraintChanges(old, new)...)
+}
+
+// diffDefinitionForeignKeys adds the foreign key differences. A foreign key
+// whose tables or columns were only renamed is unchanged.
+func (d *schemaDiffService) diffDefinitionForeignKeys(diff *models.SchemaDiff, old, new models.SchemaData) {
+	oldLabel := foreignKeyLabeler(old)
+	newLabel := foreignKeyLabeler(new)
+
+	pairs, removed, added := pairDefinitionObjectsBy(old.ForeignKeys, new.ForeignKeys,
+		func(fk models.ForeignKey) (string, string) { return fk.ID, oldLabel(fk) },
+		func(fk models.ForeignKey) (string, string) { return fk.ID, newLabel(fk) })
+
+	for _, fk := range removed {
+		diff.Removed = append(diff.Removed, models.SchemaObject{Kind: "foreignKey", Name: oldLabel(fk)})
+	}
+	for _, pair := range pairs {
+		oldKey, newKey := pair[0], pair[1]
+		name := newLabel(newKey)
+
+		var changes []string
+		if previous := oldLabel(oldKey); previous != name && !sameForeignKeyColumns(oldKey, newKey) {
+			changes = append(changes, fmt.Sprintf("columns %s -> %s", previous, name))
+		}
+		oldOnDelete := effectiveForeignKeyAction(oldKey.OnDelete, d.config.DefaultFKOnDelete)
+		newOnDelete := effectiveForeignKeyAction(newKey.OnDelete, d.config.DefaultFKOnDelete)
+		if oldOnDelete != newOnDelete {
+			changes = append(changes, fmt.Sprintf("onDelete %s -> %s", oldOnDelete, newOnDelete))
+		}
+		oldOnUpdate := effectiveForeignKeyAction(oldKey.OnUpdate, d.config.DefaultFKOnUpdate)
+		newOnUpdate := effectiveForeignKeyAction(newKey.OnUpdate, d.config.DefaultFKOnUpdate)
+		if oldOnUpdate != newOnUpdate {
+			changes = append(changes, fmt.Sprintf("onUpdate %s -> %s", oldOnUpdate, newOnUpdate))
+		}
+		if len(changes) > 0 {
+			diff.Changed = append(diff.Changed, models.SchemaObject{
+				Kind:    "foreignKey",
+				Name:    name,
+				Details: strings.Join(changes, "; "),
+			})
+		}
+	}
+	for _, fk := range added {
+		diff.Added = append(diff.Added, models.SchemaObject{Kind: "foreignKey", Name: newLabel(fk)})
+	}
+}
+
+// foreignKeyLabeler returns a function labelling the foreign keys of a
+// definition as "source.column -> target.column"
+func foreignKeyLabeler(schemaData models.SchemaData) func(models.ForeignKey) string {
+	tableNames := make(map[string]string)
+	columnNames := make(map[string]string)
+	for _, table := range schemaData.Tables {
+		tableNames[table.ID] = table.Name
+		for _, column := range table.Columns {
+			columnNames[column.ID] = column.Name
+		}
+	}
+
+	return func(fk models.ForeignKey) string {
+		return fmt.Sprintf("%s -> %s",
+			columnListLabel(tableNames[fk.SourceTableId], fk.SourceColumns(), columnNames),
+			columnListLabel(tableNames[fk.TargetTableId], fk.TargetColumns(), columnNames))
+	}
+}
+
+// sameForeignKeyColumns reports whether two foreign keys connect the same
+// tables and columns by ID
+func sameForeignKeyColumns(old, new models.ForeignKey) bool {
+	return old.SourceTableId == new.SourceTableId &&
+		old.TargetTableId == new.TargetTableId &&
+		slices.Equal(old.SourceColumns(), new.SourceColumns()) &&
+		slices.Equal(old.TargetColumns(), new.TargetColumns())
+}
+
+// pairDefinitionObjects matches the objects of two definitions by ID, then
+// the rest by name. Pairs follow the old order, removed objects are those
+// only in old and added objects those only in new, in their own order.
+func pairDefinitionObjects[T any](old, new []T, key func(T) (id, name string)) (pairs [][2]T, removed, added []T) {
+	return pairDefinitionObjectsBy(old, new, key, key)
+}
+
+// pairDefinitionObjectsBy is pairDefinitionObjects with separate key functions
+// for the old and new objects
+func pairDefinitionObjectsBy[T any](old, new []T, oldKey, newKey func(T) (id, name string)) (pairs [][2]T, removed, added []T) {
+	matches := make([]int, len(old))
+	matched := make([]bool, len(new))
+	for i := range matches {
+		matches[i] = -1
+	}
+
+	match := func(byID bool) {
+		for i, object := range old {
+			if matches[i] >= 0 {
+				continue
+			}
+			id, name := oldKey(object)
+			for j, candidate := range new {
+				if matched[j] {
+					continue
+				}
+				candidateID, candidateName := newKey(candidate)
+				if (byID && id != "" && id == candidateID) || (!byID && name == candidateName) {
+					matches[i] = j
+					matched[j] = true
+					break
+				}
+			}
+		}
+	}
+	match(true)
+	match(false)
+
+	for i, object := range old {
+		if matches[i] < 0 {
+			removed = append(removed, object)
+			continue
+		}
+		pairs = append(pairs, [2]T{object, new[matches[i]]})
+	}
+	for j, object := range new {
+		if !matched[j] {
+			added = append(added, object)
+		}
+	}
+	return pairs, removed, added
+}
+
+// DiffSchema compares the stored definition of a schema with the one an
+// update request would store, without applying it. The proposed definition
+// is validated like an update.
+func (s *schemaService) DiffSchema(id, userID uuid.UUID, request models.UpdateSchemaRequest) (*models.SchemaDiffResponse, error) {
+	schema, err := s.repo.GetByIDAndUserID(id, userID)
+	if err != nil {
+		return nil, schemaLookupError(id, err)
+	}
+
+	proposed := *schema
+	proposed.Name = request.Name
+	proposed.SchemaDefinition = updatedDefinition(request, schema.Version+1)
+	if _, err := s.validateDefinition(&proposed); err != nil {
+		return nil, err
+	}
+
+	diff := NewSchemaDiffService(s.config).Diff(schema.SchemaDefinition, proposed.SchemaDefinition)
+	return &models.SchemaDiffResponse{
+		SchemaID:   schema.ID,
+		HasChanges: diff.HasChanges(),
+		Diff:       diff,
+	}, nil
+}
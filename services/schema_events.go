@@ -0,0 +1,124 @@
+package services
+
+import (
+	"log"
+	"sync"
+	"time"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// schemaEventBuffer is how many events a subscriber may fall behind before
+// further events are dropped for it
+const schemaEventBuffer = 16
+
+// schemaEvents publishes the status and generation job changes of schemas to
+// the subscribers watching them. Like job state, events only reach
+// subscribers of the service instance making the change.
+type schemaEvents struct {
+	mu          sync.Mutex
+	subscribers map[uuid.UUID]map[chan models.SchemaEvent]struct{}
+	closed      bool
+}
+
+// newSchemaEvents creates an event hub without subscribers
+func newSchemaEvents() *schemaEvents {
+	return &schemaEvents{
+		subscribers: make(map[uuid.UUID]map[chan models.SchemaEvent]struct{}),
+	}
+}
+
+// subscribe returns a channel receiving the events of a schema until cancel
+// is called or the hub is closed, which closes the channel
+func (e *schemaEvents) subscribe(schemaID uuid.UUID) (<-chan models.SchemaEvent, func()) {
+	events := make(chan models.SchemaEvent, schemaEventBuffer)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.closed {
+		close(events)
+		return events, func() {}
+	}
+	if e.subscribers[schemaID] == nil {
+		e.subscribers[schemaID] = make(map[chan models.SchemaEvent]struct{})
+	}
+	e.subscribers[schemaID][events] = struct{}{}
+
+	cancel := func() {
+		e.mu.Lock()
+		defer e.mu.Unlock()
+
+		if _, subscribed := e.subscribers[schemaID][events]; !subscribed {
+			return
+		}
+		delete(e.subscribers[schemaID], events)
+		if len(e.subscribers[schemaID]) == 0 {
+			delete(e.subscribers, schemaID)
+		}
+		close(events)
+	}
+	return events, cancel
+}
+
+// publish sends an event to the subscribers of its schema without blocking.
+// A subscriber too far behind misses the event rather than holding up the
+// change that caused it.
+func (e *schemaEvents) publish(event models.SchemaEvent) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for events := range e.subscribers[event.SchemaID] {
+		select {
+		case events <- event:
+		default:
+			log.Printf("Warning: dropped %s event of schema %s for a slow subscriber", event.Type, event.SchemaID)
+		}
+	}
+}
+
+// close ends every subscription and refuses new ones. It is called on
+// shutdown so open streams don't hold it up.
+func (e *schemaEvents) close() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.closed = true
+	for schemaID, subscribers := range e.subscribers {
+		for events := range subscribers {
+			close(events)
+		}
+		delete(e.subscribers, schemaID)
+	}
+}
+
+// publishStatus tells the subscribers of a schema its status was saved
+func (s *schemaService) publishStatus(schema *models.Schema) {
+	s.events.publish(models.SchemaEvent{
+		Type:     models.SchemaEventStatus,
+		SchemaID: schema.ID,
+		Status:   schema.Status,
+		Time:     time.Now().UTC(),
+	})
+}
+
+// SubscribeEvents returns a schema with the events changing it from then on.
+// Any collaborator may watch a schema. The subscription is taken before the
+// schema is read, so no change is missed in between; cancel ends it.
+func (s *schemaService) SubscribeEvents(id, userID uuid.UUID) (*models.Schema, <-chan models.SchemaEvent, func(), error) {
+	events, cancel := s.events.subscribe(id)
+
+	schema, err := s.GetSchema(id, userID)
+	if err != nil {
+		cancel()
+		return nil, nil, nil, err
+	}
+	return schema, events, cancel, nil
+}
+
+// CloseEvents ends every event subscription
+func (s *schemaService) CloseEvents() {
+	s.events.close()
+}
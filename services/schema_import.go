@@ -0,0 +1,77 @@
+package services
+
+import (
+	"time"
+
+	"vdt-dashboard-backend/config"
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// importColumnsPerRow is how many tables an import lays out side by side
+	importColumnsPerRow = 4
+	// importTableSpacingX and importTableSpacingY separate imported tables
+	importTableSpacingX = 300
+	importTableSpacingY = 250
+)
+
+// SchemaImportService defines the interface for building schema definitions
+// from documents in other formats. Imports are never stored; the result is
+// reviewed and then created like any other definition.
+type SchemaImportService interface {
+	ImportJSONSchema(document []byte) (*models.SchemaImportResponse, error)
+	ImportSQL(document []byte) (*models.SchemaImportResponse, error)
+}
+
+// NewSchemaImportService creates a new schema import service
+func NewSchemaImportService(cfg *config.Config) SchemaImportService {
+	return &schemaImportService{
+		config: cfg,
+	}
+}
+
+type schemaImportService struct {
+	config *config.Config
+}
+
+// importForeignKey returns a foreign key between single columns using the
+// configured default actions
+func (s *schemaImportService) importForeignKey(sourceTable, sourceColumn, targetTable, targetColumn string) models.ForeignKey {
+	return models.ForeignKey{
+		ID:             uuid.New().String(),
+		SourceTableId:  sourceTable,
+		SourceColumnId: sourceColumn,
+		TargetTableId:  targetTable,
+		TargetColumnId: targetColumn,
+		OnDelete:       s.config.DefaultFKOnDelete,
+		OnUpdate:       s.config.DefaultFKOnUpdate,
+	}
+}
+
+// importResponse lays the imported tables out in a grid and wraps them in a
+// response
+func importResponse(tables []models.Table, foreignKeys []models.ForeignKey, warnings []models.ImportWarning) *models.SchemaImportResponse {
+	for i := range tables {
+		tables[i].Position = models.Position{
+			X: float64(i%importColumnsPerRow) * importTableSpacingX,
+			Y: float64(i/importColumnsPerRow) * importTableSpacingY,
+		}
+	}
+	if foreignKeys == nil {
+		foreignKeys = []models.ForeignKey{}
+	}
+	if warnings == nil {
+		warnings = []models.ImportWarning{}
+	}
+
+	return &models.SchemaImportResponse{
+		SchemaDefinition: models.SchemaData{
+			Tables:      tables,
+			ForeignKeys: foreignKeys,
+			ExportedAt:  time.Now().UTC(),
+		},
+		Warnings: warnings,
+	}
+}
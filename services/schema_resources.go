@@ -0,0 +1,114 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// ListTables returns a page of the tables of a schema definition, optionally
+// filtered by a case-insensitive search on the table name
+func (s *schemaService) ListTables(id, userID uuid.UUID, pagination models.PaginationRequest) ([]models.Table, *models.PaginationResponse, error) {
+	schema, err := s.loadDefinition(id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	search := strings.ToLower(pagination.Search)
+	var tables []models.Table
+	for _, table := range schema.SchemaDefinition.Tables {
+		if search == "" || strings.Contains(strings.ToLower(table.Name), search) {
+			tables = append(tables, table)
+		}
+	}
+
+	page, paginationResp := paginateSlice(tables, pagination)
+	return page, paginationResp, nil
+}
+
+// ListRelationships returns a page of the foreign keys of a schema
+// definition, optionally filtered by a case-insensitive search on the name or
+// the source and target table names
+func (s *schemaService) ListRelationships(id, userID uuid.UUID, pagination models.PaginationRequest) ([]models.ForeignKey, *models.PaginationResponse, error) {
+	schema, err := s.loadDefinition(id, userID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tableNames := make(map[string]string)
+	for _, table := range schema.SchemaDefinition.Tables {
+		tableNames[table.ID] = strings.ToLower(table.Name)
+	}
+
+	search := strings.ToLower(pagination.Search)
+	var foreignKeys []models.ForeignKey
+	for _, fk := range schema.SchemaDefinition.ForeignKeys {
+		if search == "" ||
+			strings.Contains(strings.ToLower(fk.Name), search) ||
+			strings.Contains(tableNames[fk.SourceTableId], search) ||
+			strings.Contains(tableNames[fk.TargetTableId], search) {
+			foreignKeys = append(foreignKeys, fk)
+		}
+	}
+
+	page, paginationResp := paginateSlice(foreignKeys, pagination)
+	return page, paginationResp, nil
+}
+
+// ListLiveTables returns the tables of a schema's generated database as they
+// exist in PostgreSQL. Only the owner may inspect the database.
+func (s *schemaService) ListLiveTables(id, userID uuid.UUID) (*models.LiveTablesResponse, error) {
+	schema, err := s.ownedSchema(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tables, err := s.databaseManager.ListLiveTables(schema.DatabaseName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect database: %w", err)
+	}
+
+	return &models.LiveTablesResponse{
+		SchemaID:     schema.ID,
+		DatabaseName: schema.DatabaseName,
+		Tables:       tables,
+		CheckedAt:    time.Now().UTC(),
+	}, nil
+}
+
+// loadDefinition loads a schema after checking that its stored definition is
+// small enough to hold in memory
+func (s *schemaService) loadDefinition(id, userID uuid.UUID) (*models.Schema, error) {
+	size, err := s.repo.GetDefinitionSize(id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check schema definition size: %w", err)
+	}
+	if size > models.MaxDefinitionBytes {
+		return nil, fmt.Errorf("%w: schema definition is %d bytes, the maximum is %d", ErrTooLarge, size, models.MaxDefinitionBytes)
+	}
+
+	schema, err := s.repo.GetByIDAndUserID(id, userID)
+	if err != nil {
+		return nil, schemaLookupError(id, err)
+	}
+	return schema, nil
+}
+
+// paginateSlice returns the requested page of items with its pagination
+// metadata
+func paginateSlice[T any](items []T, pagination models.PaginationRequest) ([]T, *models.PaginationResponse) {
+	pagination.Normalize()
+
+	total := len(items)
+	start := min((pagination.Page-1)*pagination.Limit, total)
+	end := min(start+pagination.Limit, total)
+
+	page := make([]T, 0, end-start)
+	page = append(page, items[start:end]...)
+
+	return page, models.PagePagination(pagination.Page, pagination.Limit, total)
+}
@@ -0,0 +1,19 @@
+package services
+
+import (
+	"testing"
+
+	"vdt-dashboard-backend/models"
+)
+
+func TestPaginateSliceClampsLimit(t *testing.T) {
+	items := make([]int, models.MaxPageLimit+50)
+
+	page, pagination := paginateSlice(items, models.PaginationRequest{Page: 1, Limit: 100000})
+	if len(page) != models.MaxPageLimit {
+		t.Fatalf("got %d items, want %d", len(page), models.MaxPageLimit)
+	}
+	if pagination.Limit != models.MaxPageLimit {
+		t.Fatalf("pagination limit = %d, want %d", pagination.Limit, models.MaxPageLimit)
+	}
+}
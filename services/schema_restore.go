@@ -0,0 +1,55 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// RestoreSchema undoes the soft delete of a schema. A database kept on delete
+// is left as it is, data included. A database dropped with the schema is
+// rebuilt empty from the stored definition in a background job.
+func (s *schemaService) RestoreSchema(ctx context.Context, id, userID uuid.UUID) (*models.Schema, error) {
+	schema, err := s.repo.GetDeletedByIDAndUserID(id, userID)
+	if err != nil {
+		return nil, schemaLookupError(id, err)
+	}
+
+	// Names are unique among a user's active schemas
+	if _, err := s.repo.GetByNameAndUserID(schema.Name, userID); err == nil {
+		return nil, fmt.Errorf("schema named '%s' %w, rename it before restoring", schema.Name, ErrDuplicate)
+	}
+
+	exists, err := s.databaseManager.DatabaseExists(schema.DatabaseName)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDatabaseProvision, err)
+	}
+
+	if err := s.repo.RestoreByIDAndUserID(id, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: deleted schema %s", ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("failed to restore schema: %w", err)
+	}
+
+	schema.DeletedAt = gorm.DeletedAt{}
+	if exists {
+		return schema, nil
+	}
+
+	schema.Status = models.SchemaStatusRegenerating
+	if err := s.repo.Update(schema); err != nil {
+		return nil, fmt.Errorf("failed to update schema: %w", err)
+	}
+	s.publishStatus(schema)
+
+	job := s.submitGeneration(ctx, schema, models.GenerationOperationRestore, models.SchemaStatusRegenerated)
+	schema.GenerationJob = &job
+
+	return schema, nil
+}
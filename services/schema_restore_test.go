@@ -0,0 +1,85 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+func TestRestoreSchema(t *testing.T) {
+	tests := []struct {
+		name           string
+		databaseExists bool
+		wantStatus     models.SchemaStatus
+		wantGenerated  bool
+	}{
+		{name: "database kept on delete", databaseExists: true, wantStatus: models.SchemaStatusUpdated},
+		{name: "database dropped on delete", databaseExists: false, wantStatus: models.SchemaStatusRegenerating, wantGenerated: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			userID := uuid.New()
+			schema := models.Schema{
+				ID:               uuid.New(),
+				Name:             "shop",
+				DatabaseName:     "schema_shop",
+				UserID:           userID,
+				Status:           models.SchemaStatusUpdated,
+				SchemaDefinition: models.SchemaData{Tables: testTables()},
+				DeletedAt:        gorm.DeletedAt{Time: time.Now(), Valid: true},
+			}
+			manager := newFakeDatabaseManager()
+			if test.databaseExists {
+				manager.databases[schema.DatabaseName] = true
+			}
+			service := newTestSchemaService(t, newFakeSchemaRepository(schema), manager)
+
+			restored, err := service.RestoreSchema(context.Background(), schema.ID, userID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if restored.Status != test.wantStatus {
+				t.Fatalf("status = %s, want %s", restored.Status, test.wantStatus)
+			}
+			if got := restored.GenerationJob != nil; got != test.wantGenerated {
+				t.Fatalf("generation job queued = %v, want %v", got, test.wantGenerated)
+			}
+
+			service.WaitForJobs(context.Background())
+			manager.mu.Lock()
+			defer manager.mu.Unlock()
+			if got := len(manager.regenerated) > 0; got != test.wantGenerated {
+				t.Fatalf("regenerated = %v, want generated %v", manager.regenerated, test.wantGenerated)
+			}
+		})
+	}
+}
+
+func TestRestoreSchemaNameTaken(t *testing.T) {
+	userID := uuid.New()
+	deleted := models.Schema{
+		ID:           uuid.New(),
+		Name:         "shop",
+		DatabaseName: "schema_shop",
+		UserID:       userID,
+		Status:       models.SchemaStatusUpdated,
+		DeletedAt:    gorm.DeletedAt{Time: time.Now(), Valid: true},
+	}
+	active := models.Schema{ID: uuid.New(), Name: "shop", DatabaseName: "schema_shop_2", UserID: userID, Status: models.SchemaStatusCreated}
+	service := newTestSchemaService(t, newFakeSchemaRepository(deleted, active), newFakeDatabaseManager())
+
+	_, err := service.RestoreSchema(context.Background(), deleted.ID, userID)
+	if !errors.Is(err, ErrDuplicate) {
+		t.Fatalf("RestoreSchema() = %v, want ErrDuplicate", err)
+	}
+	if want := "schema named 'shop' already exists, rename it before restoring"; err.Error() != want {
+		t.Fatalf("error = %q, want %q", err, want)
+	}
+}
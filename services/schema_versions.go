@@ -0,0 +1,118 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// ListVersions returns a page of the stored versions of a schema, newest
+// first
+func (s *schemaService) ListVersions(id, userID uuid.UUID, pagination models.PaginationRequest) ([]models.SchemaVersion, *models.PaginationResponse, error) {
+	schema, err := s.repo.GetByIDAndUserID(id, userID)
+	if err != nil {
+		return nil, nil, schemaLookupError(id, err)
+	}
+
+	pagination.Normalize()
+	versions, total, err := s.versions.ListBySchemaID(schema.ID, pagination)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list schema versions: %w", err)
+	}
+
+	return versions, models.PagePagination(pagination.Page, pagination.Limit, total), nil
+}
+
+// RollbackSchema stores the definition of a past version as a new version of
+// the schema and queues a rebuild of its database from it. The name and
+// description are kept, and the database is rebuilt empty. The schema is in
+// regenerating status until the job finishes.
+func (s *schemaService) RollbackSchema(ctx context.Context, id, userID uuid.UUID, version int) (*models.Schema, error) {
+	schema, err := s.GetEditableSchema(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	target, err := s.versions.Get(schema.ID, version)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: version %d of schema %s", ErrNotFound, version, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get schema version: %w", err)
+	}
+
+	schema, err = s.storeRollback(id, userID, target)
+	if err != nil {
+		return nil, err
+	}
+
+	job := s.submitGeneration(ctx, schema, models.GenerationOperationRollback, models.SchemaStatusRegenerated)
+	schema.GenerationJob = &job
+
+	return schema, nil
+}
+
+// storeRollback stores the definition of target as the next version of the
+// schema. It holds the generation lock so it can't interleave with an update;
+// the lock is released before the rebuild is queued, which takes it again.
+func (s *schemaService) storeRollback(id, userID uuid.UUID, target *models.SchemaVersion) (*models.Schema, error) {
+	unlock, err := s.databaseManager.LockGeneration(id)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	// Read the schema again under the lock, so a version stored by the request
+	// that held it before isn't overwritten
+	schema, err := s.GetEditableSchema(id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if target.Version == schema.Version {
+		return nil, fmt.Errorf("%w: schema %s is already at version %d", ErrValidation, id, target.Version)
+	}
+
+	schema.Version++
+	schema.SchemaDefinition = target.SchemaDefinition
+	schema.SchemaDefinition.Version = strconv.Itoa(schema.Version)
+	schema.SchemaDefinition.ExportedAt = time.Now().UTC()
+
+	// Validation rules may have changed since the version was stored
+	if _, err := s.validateDefinition(schema); err != nil {
+		return nil, err
+	}
+
+	schema.Status = models.SchemaStatusRegenerating
+	if err := s.repo.Update(schema); err != nil {
+		return nil, fmt.Errorf("failed to update schema: %w", err)
+	}
+	s.recordVersion(schema, userID)
+	s.publishStatus(schema)
+	return schema, nil
+}
+
+// recordVersion snapshots the stored definition of a schema as its current
+// version. A failure only loses the history entry, so it is logged rather
+// than failing the request whose changes are already saved.
+func (s *schemaService) recordVersion(schema *models.Schema, userID uuid.UUID) {
+	version := &models.SchemaVersion{
+		SchemaID:         schema.ID,
+		Version:          schema.Version,
+		Name:             schema.Name,
+		Description:      schema.Description,
+		SchemaDefinition: schema.SchemaDefinition,
+		CreatedBy:        userID,
+		CreatedAt:        time.Now().UTC(),
+	}
+	if err := s.versions.Create(version); err != nil {
+		log.Printf("Warning: failed to record version %d of schema %s: %v", schema.Version, schema.ID, err)
+	}
+}
@@ -0,0 +1,43 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+)
+
+func TestRollbackSchemaReadsSchemaUnderLock(t *testing.T) {
+	userID := uuid.New()
+	schema := models.Schema{ID: uuid.New(), Name: "shop", DatabaseName: "schema_shop", UserID: userID, Status: models.SchemaStatusCreated, Version: 2}
+	repo := newFakeSchemaRepository(schema)
+	manager := newFakeDatabaseManager(schema.DatabaseName)
+	// An update stores version 3 while the rollback waits for the lock
+	manager.onLock = func(schemaID uuid.UUID) {
+		manager.onLock = nil
+		concurrent := schema
+		concurrent.Version = 3
+		repo.Update(&concurrent)
+	}
+	service := newTestSchemaService(t, repo, manager)
+	versions := service.versions.(*fakeSchemaVersionRepository)
+	versions.Create(&models.SchemaVersion{
+		SchemaID:         schema.ID,
+		Version:          1,
+		Name:             schema.Name,
+		SchemaDefinition: models.SchemaData{Tables: testTables()},
+	})
+
+	rolledBack, err := service.RollbackSchema(context.Background(), schema.ID, userID, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rolledBack.Version != 4 {
+		t.Fatalf("version = %d, want 4", rolledBack.Version)
+	}
+	if _, err := versions.Get(schema.ID, 4); err != nil {
+		t.Fatalf("version 4 was not recorded: %v", err)
+	}
+}
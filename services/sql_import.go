@@ -0,0 +1,1032 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+)
+
+// sqlTokenKind classifies the tokens of a DDL script
+type sqlTokenKind int
+
+const (
+	sqlWord   sqlTokenKind = iota // Unquoted identifier or keyword
+	sqlQuoted                     // Double-quoted identifier
+	sqlString                     // String literal, including dollar-quoted ones
+	sqlNumber
+	sqlSymbol
+)
+
+// sqlToken is a token of a DDL script. start and end are byte offsets into
+// the script, so expressions can be imported as they were written.
+type sqlToken struct {
+	kind  sqlTokenKind
+	text  string
+	start int
+	end   int
+	line  int
+}
+
+// is reports whether the token is the keyword or symbol s, ignoring case
+func (t sqlToken) is(s string) bool {
+	return (t.kind == sqlWord || t.kind == sqlSymbol) && strings.EqualFold(t.text, s)
+}
+
+// sqlColumnConstraintKeywords end a column's type and default expression
+var sqlColumnConstraintKeywords = map[string]bool{
+	"CONSTRAINT": true,
+	"NOT":        true,
+	"NULL":       true,
+	"PRIMARY":    true,
+	"UNIQUE":     true,
+	"CHECK":      true,
+	"REFERENCES": true,
+	"DEFAULT":    true,
+	"COLLATE":    true,
+	"GENERATED":  true,
+}
+
+// isColumnConstraintKeyword reports whether a token starts a column clause
+func isColumnConstraintKeyword(token sqlToken) bool {
+	return token.kind == sqlWord && sqlColumnConstraintKeywords[strings.ToUpper(token.text)]
+}
+
+// sqlTypes maps PostgreSQL type names to supported data types. Serial types
+// also make the column auto-increment.
+var sqlTypes = map[string]struct {
+	dataType      string
+	autoIncrement bool
+}{
+	"smallint":                    {"INT", false},
+	"int2":                        {"INT", false},
+	"int":                         {"INT", false},
+	"int4":                        {"INT", false},
+	"integer":                     {"INT", false},
+	"smallserial":                 {"INT", true},
+	"serial2":                     {"INT", true},
+	"serial":                      {"INT", true},
+	"serial4":                     {"INT", true},
+	"bigint":                      {"BIGINT", false},
+	"int8":                        {"BIGINT", false},
+	"bigserial":                   {"BIGINT", true},
+	"serial8":                     {"BIGINT", true},
+	"varchar":                     {"VARCHAR", false},
+	"character varying":           {"VARCHAR", false},
+	"char":                        {"VARCHAR", false},
+	"character":                   {"VARCHAR", false},
+	"bpchar":                      {"VARCHAR", false},
+	"text":                        {"TEXT", false},
+	"citext":                      {"TEXT", false},
+	"boolean":                     {"BOOLEAN", false},
+	"bool":                        {"BOOLEAN", false},
+	"timestamp":                   {"TIMESTAMP", false},
+	"timestamptz":                 {"TIMESTAMP", false},
+	"timestamp with time zone":    {"TIMESTAMP", false},
+	"timestamp without time zone": {"TIMESTAMP", false},
+	"date":                        {"DATE", false},
+	"time":                        {"TIME", false},
+	"timetz":                      {"TIME", false},
+	"time with time zone":         {"TIME", false},
+	"time without time zone":      {"TIME", false},
+	"numeric":                     {"DECIMAL", false},
+	"decimal":                     {"DECIMAL", false},
+	"real":                        {"FLOAT", false},
+	"float4":                      {"FLOAT", false},
+	"float":                       {"DOUBLE", false},
+	"float8":                      {"DOUBLE", false},
+	"double precision":            {"DOUBLE", false},
+	"json":                        {"JSON", false},
+	"jsonb":                       {"JSON", false},
+	"uuid":                        {"UUID", false},
+}
+
+// tokenizeSQL splits a DDL script into tokens, dropping whitespace and
+// comments
+func tokenizeSQL(script string) ([]sqlToken, error) {
+	var tokens []sqlToken
+	line := 1
+	for i := 0; i < len(script); {
+		c := script[i]
+		start, startLine := i, line
+		switch {
+		case c == '\n':
+			line++
+			i++
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f':
+			i++
+			continue
+		case strings.HasPrefix(script[i:], "--"):
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+			continue
+		case strings.HasPrefix(script[i:], "/*"):
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated comment on line %d", line)
+			}
+			line += strings.Count(script[i:i+2+end+2], "\n")
+			i += 2 + end + 2
+			continue
+		case c == '\'' || c == '"':
+			// Quotes are escaped by doubling them
+			i++
+			for {
+				end := strings.IndexByte(script[i:], c)
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated quote on line %d", line)
+				}
+				i += end + 1
+				if i < len(script) && script[i] == c {
+					i++
+					continue
+				}
+				break
+			}
+			line += strings.Count(script[start:i], "\n")
+			kind, text := sqlString, script[start+1:i-1]
+			if c == '"' {
+				kind = sqlQuoted
+			}
+			tokens = append(tokens, sqlToken{kind: kind, text: strings.ReplaceAll(text, string([]byte{c, c}), string(c)), start: start, end: i, line: startLine})
+			continue
+		case (c == 'E' || c == 'e') && strings.HasPrefix(script[i+1:], "'"):
+			// Escape string: a backslash escapes the next character, quotes included
+			i += 2
+		escaped:
+			for {
+				if i >= len(script) {
+					return nil, fmt.Errorf("unterminated quote on line %d", line)
+				}
+				switch {
+				case script[i] == '\\':
+					i += 2
+				case strings.HasPrefix(script[i:], "''"):
+					i += 2
+				case script[i] == '\'':
+					i++
+					break escaped
+				default:
+					i++
+				}
+			}
+			line += strings.Count(script[start:i], "\n")
+			tokens = append(tokens, sqlToken{kind: sqlString, text: script[start+2 : i-1], start: start, end: i, line: startLine})
+			continue
+		case c == '$':
+			// Dollar-quoted string: $tag$ ... $tag$
+			tagEnd := strings.IndexByte(script[i+1:], '$')
+			if tagEnd >= 0 && isSQLTag(script[i+1:i+1+tagEnd]) {
+				tag := script[i : i+tagEnd+2]
+				end := strings.Index(script[i+len(tag):], tag)
+				if end < 0 {
+					return nil, fmt.Errorf("unterminated dollar-quoted string on line %d", line)
+				}
+				i += len(tag) + end + len(tag)
+				line += strings.Count(script[start:i], "\n")
+				tokens = append(tokens, sqlToken{kind: sqlString, text: script[start+len(tag) : i-len(tag)], start: start, end: i, line: startLine})
+				continue
+			}
+			i++
+		case isSQLIdentifierStart(c):
+			for i < len(script) && isSQLIdentifierPart(script[i]) {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlWord, text: script[start:i], start: start, end: i, line: startLine})
+			continue
+		case c >= '0' && c <= '9':
+			for i < len(script) && (script[i] >= '0' && script[i] <= '9' || script[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, sqlToken{kind: sqlNumber, text: script[start:i], start: start, end: i, line: startLine})
+			continue
+		case strings.HasPrefix(script[i:], "::"):
+			i += 2
+		default:
+			i++
+		}
+		tokens = append(tokens, sqlToken{kind: sqlSymbol, text: script[start:i], start: start, end: i, line: startLine})
+	}
+	return tokens, nil
+}
+
+// isSQLTag reports whether tag can be the tag of a dollar-quoted string
+func isSQLTag(tag string) bool {
+	for i := 0; i < len(tag); i++ {
+		if !isSQLIdentifierPart(tag[i]) || (i == 0 && tag[i] >= '0' && tag[i] <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+func isSQLIdentifierStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isSQLIdentifierPart(c byte) bool {
+	return isSQLIdentifierStart(c) || c >= '0' && c <= '9' || c == '$'
+}
+
+// splitSQL splits tokens at the given symbol outside of parentheses
+func splitSQL(tokens []sqlToken, separator string) [][]sqlToken {
+	var parts [][]sqlToken
+	depth, start := 0, 0
+	for i, token := range tokens {
+		switch {
+		case token.is("("):
+			depth++
+		case token.is(")"):
+			depth--
+		case depth == 0 && token.is(separator):
+			parts = append(parts, tokens[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, tokens[start:])
+}
+
+// sqlParser reads the tokens of one statement or table element
+type sqlParser struct {
+	script string
+	tokens []sqlToken
+	pos    int
+}
+
+// done reports whether every token was read
+func (p *sqlParser) done() bool {
+	return p.pos >= len(p.tokens)
+}
+
+// peekIs reports whether the next tokens are the given keywords
+func (p *sqlParser) peekIs(keywords ...string) bool {
+	for i, keyword := range keywords {
+		if p.pos+i >= len(p.tokens) || !p.tokens[p.pos+i].is(keyword) {
+			return false
+		}
+	}
+	return true
+}
+
+// accept reads the given keywords when they are next
+func (p *sqlParser) accept(keywords ...string) bool {
+	if !p.peekIs(keywords...) {
+		return false
+	}
+	p.pos += len(keywords)
+	return true
+}
+
+// expect reads the given keywords, failing when they aren't next
+func (p *sqlParser) expect(keywords ...string) error {
+	if !p.accept(keywords...) {
+		return fmt.Errorf("expected %s near %s", strings.Join(keywords, " "), p.near())
+	}
+	return nil
+}
+
+// near describes the position of the parser for error messages
+func (p *sqlParser) near() string {
+	if p.done() {
+		return "the end of the statement"
+	}
+	return fmt.Sprintf("'%s'", p.tokens[p.pos].text)
+}
+
+// identifier reads an identifier. Unquoted identifiers are folded to lower
+// case like PostgreSQL does.
+func (p *sqlParser) identifier() (string, error) {
+	if p.done() {
+		return "", errors.New("expected an identifier at the end of the statement")
+	}
+	token := p.tokens[p.pos]
+	switch token.kind {
+	case sqlQuoted:
+		p.pos++
+		return token.text, nil
+	case sqlWord:
+		p.pos++
+		return strings.ToLower(token.text), nil
+	}
+	return "", fmt.Errorf("expected an identifier near %s", p.near())
+}
+
+// qualifiedName reads a possibly schema-qualified name and returns its last
+// part
+func (p *sqlParser) qualifiedName() (string, error) {
+	name, err := p.identifier()
+	for err == nil && p.accept(".") {
+		name, err = p.identifier()
+	}
+	return name, err
+}
+
+// identifierList reads a parenthesized list of identifiers
+func (p *sqlParser) identifierList() ([]string, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	var names []string
+	for {
+		name, err := p.identifier()
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+		if p.accept(")") {
+			return names, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// group reads a parenthesized group and returns its tokens without the
+// parentheses
+func (p *sqlParser) group() ([]sqlToken, error) {
+	if err := p.expect("("); err != nil {
+		return nil, err
+	}
+	start, depth := p.pos, 1
+	for ; !p.done(); p.pos++ {
+		if p.tokens[p.pos].is("(") {
+			depth++
+		} else if p.tokens[p.pos].is(")") {
+			depth--
+			if depth == 0 {
+				p.pos++
+				return p.tokens[start : p.pos-1], nil
+			}
+		}
+	}
+	return nil, errors.New("unbalanced parentheses")
+}
+
+// text returns the source of tokens as written
+func (p *sqlParser) text(tokens []sqlToken) string {
+	if len(tokens) == 0 {
+		return ""
+	}
+	return p.script[tokens[0].start:tokens[len(tokens)-1].end]
+}
+
+// sqlForeignKey is a foreign key read from DDL, resolved once every table is
+// known
+type sqlForeignKey struct {
+	line          int
+	name          string
+	sourceTable   string
+	sourceColumns []string
+	targetTable   string
+	targetColumns []string // Empty to reference the primary key
+	onDelete      string
+	onUpdate      string
+}
+
+// sqlImport holds the state of one DDL import
+type sqlImport struct {
+	service     *schemaImportService
+	script      string
+	tables      []*models.Table
+	byName      map[string]*models.Table
+	foreignKeys []sqlForeignKey
+	warnings    []models.ImportWarning
+}
+
+// ImportSQL builds a schema definition from PostgreSQL DDL. CREATE TABLE
+// statements become tables with their columns, primary keys, unique and
+// check constraints and foreign keys; ALTER TABLE statements adding
+// constraints or column defaults, as written by pg_dump, are applied to them.
+// Statements and table elements that can't be imported are skipped and
+// reported as warnings.
+func (s *schemaImportService) ImportSQL(document []byte) (*models.SchemaImportResponse, error) {
+	if len(document) > models.MaxDefinitionBytes {
+		return nil, fmt.Errorf("%w: document is %d bytes, the maximum is %d", ErrTooLarge, len(document), models.MaxDefinitionBytes)
+	}
+
+	script := string(document)
+	tokens, err := tokenizeSQL(script)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid SQL: %v", ErrValidation, err)
+	}
+
+	imp := &sqlImport{
+		service: s,
+		script:  script,
+		byName:  make(map[string]*models.Table),
+	}
+	// Semicolons only appear in strings and comments within a statement,
+	// so an unbalanced parenthesis doesn't swallow the next one
+	start := 0
+	for i := 0; i <= len(tokens); i++ {
+		if i < len(tokens) && !tokens[i].is(";") {
+			continue
+		}
+		if i > start {
+			imp.importStatement(tokens[start:i])
+		}
+		start = i + 1
+	}
+	if len(imp.tables) == 0 {
+		return nil, fmt.Errorf("%w: the script has no CREATE TABLE statements that could be imported", ErrValidation)
+	}
+
+	foreignKeys := imp.resolveForeignKeys()
+	sort.SliceStable(imp.warnings, func(i, j int) bool {
+		return imp.warnings[i].Line < imp.warnings[j].Line
+	})
+	tables := make([]models.Table, 0, len(imp.tables))
+	for _, table := range imp.tables {
+		tables = append(tables, *table)
+	}
+	return importResponse(tables, foreignKeys, imp.warnings), nil
+}
+
+// warn records an import warning at a line of the script
+func (imp *sqlImport) warn(line int, message string) {
+	imp.warnings = append(imp.warnings, models.ImportWarning{Line: line, Message: message})
+}
+
+// importStatement imports one statement, or reports why it was skipped
+func (imp *sqlImport) importStatement(statement []sqlToken) {
+	p := &sqlParser{script: imp.script, tokens: statement}
+	line := statement[0].line
+
+	var err error
+	switch {
+	case p.accept("CREATE"):
+		for _, modifier := range []string{"GLOBAL", "LOCAL", "TEMPORARY", "TEMP", "UNLOGGED"} {
+			p.accept(modifier)
+		}
+		if !p.accept("TABLE") {
+			imp.warn(line, fmt.Sprintf("Skipped %s: only CREATE TABLE and ALTER TABLE statements are imported", statementSummary(statement)))
+			return
+		}
+		err = imp.createTable(p)
+	case p.accept("ALTER", "TABLE"):
+		err = imp.alterTable(p)
+	default:
+		imp.warn(line, fmt.Sprintf("Skipped %s: only CREATE TABLE and ALTER TABLE statements are imported", statementSummary(statement)))
+		return
+	}
+	if err != nil {
+		imp.warn(line, fmt.Sprintf("Skipped %s: %v", statementSummary(statement), err))
+	}
+}
+
+// statementSummary names a statement by its leading keywords
+func statementSummary(statement []sqlToken) string {
+	var words []string
+	for _, token := range statement {
+		if token.kind != sqlWord || len(words) == 2 {
+			break
+		}
+		words = append(words, strings.ToUpper(token.text))
+	}
+	if len(words) == 0 {
+		return "statement"
+	}
+	return strings.Join(words, " ") + " statement"
+}
+
+// createTable imports a CREATE TABLE statement after its TABLE keyword
+func (imp *sqlImport) createTable(p *sqlParser) error {
+	p.accept("IF", "NOT", "EXISTS")
+	name, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+	if _, exists := imp.byName[name]; exists {
+		return fmt.Errorf("table '%s' is already defined", name)
+	}
+	if p.peekIs("OF") || p.peekIs("PARTITION", "OF") {
+		return fmt.Errorf("typed and partition tables are not supported")
+	}
+	body, err := p.group()
+	if err != nil {
+		return err
+	}
+
+	table := &models.Table{ID: uuid.New().String(), Name: name, Columns: []models.Column{}}
+	var constraints [][]sqlToken
+	for _, element := range splitSQL(body, ",") {
+		if len(element) == 0 {
+			continue
+		}
+		if isTableConstraint(element[0]) {
+			// Constraints may name columns defined after them
+			constraints = append(constraints, element)
+			continue
+		}
+		column, err := imp.column(table, element)
+		if err != nil {
+			imp.warn(element[0].line, fmt.Sprintf("Skipped column of table '%s': %v", name, err))
+			continue
+		}
+		table.Columns = append(table.Columns, column)
+	}
+	for _, element := range constraints {
+		ep := &sqlParser{script: imp.script, tokens: element}
+		if err := imp.tableConstraint(table, ep); err != nil {
+			imp.warn(element[0].line, fmt.Sprintf("Skipped constraint of table '%s': %v", name, err))
+		}
+	}
+
+	imp.tables = append(imp.tables, table)
+	imp.byName[name] = table
+	return nil
+}
+
+// isTableConstraint reports whether a table element is a constraint rather
+// than a column
+func isTableConstraint(first sqlToken) bool {
+	for _, keyword := range []string{"CONSTRAINT", "PRIMARY", "UNIQUE", "FOREIGN", "CHECK", "EXCLUDE", "LIKE"} {
+		if first.is(keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// column imports a column definition
+func (imp *sqlImport) column(table *models.Table, element []sqlToken) (models.Column, error) {
+	p := &sqlParser{script: imp.script, tokens: element}
+	name, err := p.identifier()
+	if err != nil {
+		return models.Column{}, err
+	}
+	column := models.Column{ID: uuid.New().String(), Name: name}
+	if err := imp.columnType(p, &column); err != nil {
+		return models.Column{}, fmt.Errorf("column '%s': %w", name, err)
+	}
+
+	for !p.done() {
+		line := p.tokens[p.pos].line
+		switch {
+		case p.accept("CONSTRAINT"):
+			if _, err := p.identifier(); err != nil {
+				return models.Column{}, err
+			}
+		case p.accept("NOT", "NULL"):
+			column.Nullable = new(bool)
+		case p.accept("NULL"):
+		case p.accept("PRIMARY", "KEY"):
+			column.PrimaryKey = true
+		case p.accept("UNIQUE"):
+			p.accept("NULLS", "NOT", "DISTINCT")
+			column.Unique = true
+		case p.accept("DEFAULT"):
+			// The expression runs until the next clause, and is at least
+			// one token so DEFAULT NULL is read
+			start := p.pos
+			for !p.done() && (p.pos == start || !isColumnConstraintKeyword(p.tokens[p.pos])) {
+				if p.tokens[p.pos].is("(") {
+					if _, err := p.group(); err != nil {
+						return models.Column{}, err
+					}
+					continue
+				}
+				p.pos++
+			}
+			setImportedDefault(&column, p.text(p.tokens[start:p.pos]))
+		case p.accept("CHECK"):
+			check, err := p.group()
+			if err != nil {
+				return models.Column{}, err
+			}
+			expression := p.text(check)
+			column.Check = &expression
+			p.accept("NO", "INHERIT")
+		case p.accept("REFERENCES"):
+			fk := sqlForeignKey{line: line, sourceTable: table.Name, sourceColumns: []string{name}}
+			if err := imp.references(p, &fk); err != nil {
+				return models.Column{}, err
+			}
+			imp.foreignKeys = append(imp.foreignKeys, fk)
+		case p.accept("COLLATE"):
+			if _, err := p.qualifiedName(); err != nil {
+				return models.Column{}, err
+			}
+		case p.accept("GENERATED"):
+			if p.accept("ALWAYS", "AS", "(") {
+				return models.Column{}, fmt.Errorf("column '%s': generated columns are not supported", name)
+			}
+			p.accept("ALWAYS")
+			p.accept("BY", "DEFAULT")
+			if err := p.expect("AS", "IDENTITY"); err != nil {
+				return models.Column{}, err
+			}
+			if p.peekIs("(") {
+				if _, err := p.group(); err != nil {
+					return models.Column{}, err
+				}
+			}
+			column.AutoIncrement = true
+		case p.accept("DEFERRABLE"), p.accept("NOT", "DEFERRABLE"), p.accept("INITIALLY", "DEFERRED"), p.accept("INITIALLY", "IMMEDIATE"):
+		default:
+			return models.Column{}, fmt.Errorf("column '%s': unsupported clause near %s", name, p.near())
+		}
+	}
+	return column, nil
+}
+
+// columnType reads the type of a column, with its length or precision and
+// array suffix, and maps it to a supported data type
+func (imp *sqlImport) columnType(p *sqlParser, column *models.Column) error {
+	var words []string
+	var args []sqlToken
+tokens:
+	for !p.done() {
+		token := p.tokens[p.pos]
+		switch {
+		case isColumnConstraintKeyword(token):
+			break tokens
+		case token.is("ARRAY"):
+			column.IsArray = true
+			p.pos++
+		case token.kind == sqlWord || token.kind == sqlQuoted:
+			words = append(words, strings.ToLower(token.text))
+			p.pos++
+		case token.is("."):
+			// Schema-qualified type
+			words = nil
+			p.pos++
+		case token.is("(") && args == nil:
+			group, err := p.group()
+			if err != nil {
+				return err
+			}
+			args = group
+		case token.is("["):
+			column.IsArray = true
+			for !p.done() && !p.tokens[p.pos].is("]") {
+				p.pos++
+			}
+			p.accept("]")
+		default:
+			break tokens
+		}
+	}
+
+	if len(words) == 0 {
+		return errors.New("missing type")
+	}
+
+	typeName := strings.Join(words, " ")
+	mapped, exists := sqlTypes[typeName]
+	if !exists {
+		column.DataType = "TEXT"
+		imp.warn(p.tokens[0].line, fmt.Sprintf("Column '%s' has unsupported type '%s', it is imported as TEXT", column.Name, typeName))
+		return nil
+	}
+	column.DataType = mapped.dataType
+	column.AutoIncrement = mapped.autoIncrement
+
+	var numbers []int
+	for _, part := range splitSQL(args, ",") {
+		if len(part) == 1 && part[0].kind == sqlNumber {
+			if n, err := strconv.Atoi(part[0].text); err == nil {
+				numbers = append(numbers, n)
+			}
+		}
+	}
+	switch {
+	case column.DataType == "VARCHAR" && len(numbers) > 0:
+		column.Length = &numbers[0]
+	case column.DataType == "VARCHAR" && (typeName == "char" || typeName == "character"):
+		length := 1
+		column.Length = &length
+	case column.DataType == "DECIMAL" && len(numbers) > 0:
+		column.Precision = &numbers[0]
+		if len(numbers) > 1 {
+			column.Scale = &numbers[1]
+		}
+	case typeName == "float" && len(numbers) > 0 && numbers[0] <= 24:
+		column.DataType = "FLOAT"
+	}
+	return nil
+}
+
+// setImportedDefault imports a default expression. Defaults drawing from a
+// sequence make the column auto-increment instead.
+func setImportedDefault(column *models.Column, expression string) {
+	switch {
+	case strings.Contains(strings.ToLower(expression), "nextval("):
+		column.AutoIncrement = true
+		column.DefaultExpression = ""
+	case strings.EqualFold(expression, "NULL"):
+	default:
+		column.DefaultExpression = expression
+	}
+}
+
+// tableConstraint imports a table constraint
+func (imp *sqlImport) tableConstraint(table *models.Table, p *sqlParser) error {
+	line := p.tokens[p.pos].line
+	var name string
+	if p.accept("CONSTRAINT") {
+		var err error
+		if name, err = p.identifier(); err != nil {
+			return err
+		}
+	}
+
+	switch {
+	case p.accept("PRIMARY", "KEY"):
+		columns, err := p.identifierList()
+		if err != nil {
+			return err
+		}
+		indexes, err := columnIndexes(table, columns)
+		if err != nil {
+			return err
+		}
+		for _, i := range indexes {
+			table.Columns[i].PrimaryKey = true
+		}
+	case p.accept("UNIQUE"):
+		p.accept("NULLS", "NOT", "DISTINCT")
+		columns, err := p.identifierList()
+		if err != nil {
+			return err
+		}
+		indexes, err := columnIndexes(table, columns)
+		if err != nil {
+			return err
+		}
+		if len(indexes) == 1 {
+			table.Columns[indexes[0]].Unique = true
+			return nil
+		}
+		if name == "" {
+			name = table.Name + "_" + strings.Join(columns, "_") + "_key"
+		}
+		index := models.Index{Name: name, Unique: true}
+		for _, i := range indexes {
+			index.Columns = append(index.Columns, table.Columns[i].ID)
+		}
+		table.Indexes = append(table.Indexes, index)
+	case p.accept("FOREIGN", "KEY"):
+		columns, err := p.identifierList()
+		if err != nil {
+			return err
+		}
+		fk := sqlForeignKey{line: line, name: name, sourceTable: table.Name, sourceColumns: columns}
+		if err := p.expect("REFERENCES"); err != nil {
+			return err
+		}
+		if err := imp.references(p, &fk); err != nil {
+			return err
+		}
+		imp.foreignKeys = append(imp.foreignKeys, fk)
+	case p.accept("CHECK"):
+		check, err := p.group()
+		if err != nil {
+			return err
+		}
+		return imp.tableCheck(table, p.text(check))
+	default:
+		return fmt.Errorf("unsupported constraint near %s", p.near())
+	}
+	return nil
+}
+
+// tableCheck attaches a table CHECK constraint to the single column it
+// mentions, since checks are stored on columns
+func (imp *sqlImport) tableCheck(table *models.Table, expression string) error {
+	tokens, err := tokenizeSQL(expression)
+	if err != nil {
+		return err
+	}
+	mentioned := -1
+	for i, column := range table.Columns {
+		for _, token := range tokens {
+			if (token.kind == sqlWord && strings.EqualFold(token.text, column.Name)) || (token.kind == sqlQuoted && token.text == column.Name) {
+				if mentioned >= 0 && mentioned != i {
+					return errors.New("CHECK constraints spanning several columns are not supported")
+				}
+				mentioned = i
+			}
+		}
+	}
+	if mentioned < 0 {
+		return errors.New("CHECK constraint mentions no column")
+	}
+	if table.Columns[mentioned].Check != nil {
+		return fmt.Errorf("column '%s' already has a CHECK constraint", table.Columns[mentioned].Name)
+	}
+	table.Columns[mentioned].Check = &expression
+	return nil
+}
+
+// columnIndexes returns the positions of the named columns of a table
+func columnIndexes(table *models.Table, names []string) ([]int, error) {
+	indexes := make([]int, 0, len(names))
+	for _, name := range names {
+		found := false
+		for i, column := range table.Columns {
+			if column.Name == name {
+				indexes = append(indexes, i)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("table '%s' has no column '%s'", table.Name, name)
+		}
+	}
+	return indexes, nil
+}
+
+// references reads the target and actions of a foreign key after its
+// REFERENCES keyword
+func (imp *sqlImport) references(p *sqlParser, fk *sqlForeignKey) error {
+	target, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+	fk.targetTable = target
+	if p.peekIs("(") {
+		if fk.targetColumns, err = p.identifierList(); err != nil {
+			return err
+		}
+	}
+
+	for {
+		switch {
+		case p.accept("ON", "DELETE"):
+			if fk.onDelete, err = foreignKeyAction(p); err != nil {
+				return err
+			}
+		case p.accept("ON", "UPDATE"):
+			if fk.onUpdate, err = foreignKeyAction(p); err != nil {
+				return err
+			}
+		case p.accept("MATCH"):
+			if _, err := p.identifier(); err != nil {
+				return err
+			}
+		case p.accept("DEFERRABLE"), p.accept("NOT", "DEFERRABLE"), p.accept("INITIALLY", "DEFERRED"), p.accept("INITIALLY", "IMMEDIATE"):
+		default:
+			return nil
+		}
+	}
+}
+
+// foreignKeyAction reads a referential action
+func foreignKeyAction(p *sqlParser) (string, error) {
+	for _, action := range []string{"CASCADE", "RESTRICT", "NO ACTION", "SET NULL", "SET DEFAULT"} {
+		if p.accept(strings.Fields(action)...) {
+			if p.peekIs("(") {
+				// Column list of SET NULL and SET DEFAULT
+				if _, err := p.group(); err != nil {
+					return "", err
+				}
+			}
+			return action, nil
+		}
+	}
+	return "", fmt.Errorf("unknown referential action near %s", p.near())
+}
+
+// alterTable imports the parts of an ALTER TABLE statement pg_dump uses to
+// add constraints and sequence defaults
+func (imp *sqlImport) alterTable(p *sqlParser) error {
+	p.accept("IF", "EXISTS")
+	p.accept("ONLY")
+	name, err := p.qualifiedName()
+	if err != nil {
+		return err
+	}
+	table, exists := imp.byName[name]
+	if !exists {
+		return fmt.Errorf("table '%s' is not defined before it is altered", name)
+	}
+
+	for _, action := range splitSQL(p.tokens[p.pos:], ",") {
+		ap := &sqlParser{script: imp.script, tokens: action}
+		switch {
+		case ap.accept("ADD"):
+			err = imp.addToTable(table, ap)
+		case ap.accept("ALTER"):
+			err = imp.alterColumn(table, ap)
+		default:
+			err = fmt.Errorf("unsupported ALTER TABLE action near %s", ap.near())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// addToTable imports ALTER TABLE ... ADD of a constraint or column
+func (imp *sqlImport) addToTable(table *models.Table, p *sqlParser) error {
+	if !p.done() && isTableConstraint(p.tokens[p.pos]) {
+		return imp.tableConstraint(table, p)
+	}
+
+	p.accept("COLUMN")
+	p.accept("IF", "NOT", "EXISTS")
+	if p.done() {
+		return errors.New("expected a column definition")
+	}
+	column, err := imp.column(table, p.tokens[p.pos:])
+	if err != nil {
+		return err
+	}
+	if _, err := columnIndexes(table, []string{column.Name}); err == nil {
+		return fmt.Errorf("table '%s' already has a column '%s'", table.Name, column.Name)
+	}
+	table.Columns = append(table.Columns, column)
+	return nil
+}
+
+// alterColumn imports ALTER COLUMN ... SET DEFAULT and SET/DROP NOT NULL
+func (imp *sqlImport) alterColumn(table *models.Table, p *sqlParser) error {
+	p.accept("COLUMN")
+	name, err := p.identifier()
+	if err != nil {
+		return err
+	}
+	indexes, err := columnIndexes(table, []string{name})
+	if err != nil {
+		return err
+	}
+	column := &table.Columns[indexes[0]]
+
+	switch {
+	case p.accept("SET", "DEFAULT"):
+		setImportedDefault(column, p.text(p.tokens[p.pos:]))
+	case p.accept("SET", "NOT", "NULL"):
+		column.Nullable = new(bool)
+	case p.accept("DROP", "NOT", "NULL"):
+		column.Nullable = nil
+	default:
+		return fmt.Errorf("unsupported ALTER COLUMN action near %s", p.near())
+	}
+	return nil
+}
+
+// resolveForeignKeys turns the foreign keys read from the script into
+// foreign keys between the imported tables, reporting those referencing
+// unknown tables or columns
+func (imp *sqlImport) resolveForeignKeys() []models.ForeignKey {
+	var foreignKeys []models.ForeignKey
+	for _, fk := range imp.foreignKeys {
+		source, target := imp.byName[fk.sourceTable], imp.byName[fk.targetTable]
+		if target == nil {
+			imp.warn(fk.line, fmt.Sprintf("Skipped foreign key of table '%s': table '%s' is not defined", fk.sourceTable, fk.targetTable))
+			continue
+		}
+
+		sourceIndexes, err := columnIndexes(source, fk.sourceColumns)
+		if err != nil {
+			imp.warn(fk.line, fmt.Sprintf("Skipped foreign key of table '%s': %v", fk.sourceTable, err))
+			continue
+		}
+		var targetIndexes []int
+		if len(fk.targetColumns) == 0 {
+			for i, column := range target.Columns {
+				if column.PrimaryKey {
+					targetIndexes = append(targetIndexes, i)
+				}
+			}
+		} else if targetIndexes, err = columnIndexes(target, fk.targetColumns); err != nil {
+			imp.warn(fk.line, fmt.Sprintf("Skipped foreign key of table '%s': %v", fk.sourceTable, err))
+			continue
+		}
+		if len(sourceIndexes) != len(targetIndexes) {
+			imp.warn(fk.line, fmt.Sprintf("Skipped foreign key of table '%s': it has %d columns but references %d", fk.sourceTable, len(sourceIndexes), len(targetIndexes)))
+			continue
+		}
+
+		foreignKey := imp.service.importForeignKey(source.ID, "", target.ID, "")
+		foreignKey.Name = fk.name
+		if fk.onDelete != "" {
+			foreignKey.OnDelete = fk.onDelete
+		}
+		if fk.onUpdate != "" {
+			foreignKey.OnUpdate = fk.onUpdate
+		}
+		if len(sourceIndexes) == 1 {
+			foreignKey.SourceColumnId = source.Columns[sourceIndexes[0]].ID
+			foreignKey.TargetColumnId = target.Columns[targetIndexes[0]].ID
+		} else {
+			for i := range sourceIndexes {
+				foreignKey.SourceColumnIds = append(foreignKey.SourceColumnIds, source.Columns[sourceIndexes[i]].ID)
+				foreignKey.TargetColumnIds = append(foreignKey.TargetColumnIds, target.Columns[targetIndexes[i]].ID)
+			}
+		}
+		foreignKeys = append(foreignKeys, foreignKey)
+	}
+	return foreignKeys
+}
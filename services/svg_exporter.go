@@ -0,0 +1,231 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"strconv"
+	"strings"
+
+	"vdt-dashboard-backend/models"
+)
+
+// Diagram layout in SVG user units
+const (
+	diagramMargin       = 40.0
+	diagramHeaderHeight = 28.0
+	diagramRowHeight    = 20.0
+	diagramCharWidth    = 7.0
+	diagramMinWidth     = 160.0
+	diagramPadding      = 10.0
+	diagramGridColumns  = 4
+	diagramGridGap      = 60.0
+)
+
+// SVGExporter renders a schema definition as an entity relationship diagram.
+// Tables are placed at their stored positions; tables without one are laid
+// out on a grid below the positioned tables. Output depends only on the
+// schema data.
+type SVGExporter struct{}
+
+// diagramBox is the rendered area of a table
+type diagramBox struct {
+	table   models.Table
+	columns []models.Column
+	x, y    float64
+	width   float64
+	height  float64
+}
+
+// rowCenter returns the vertical center of the row of columnID, or of the
+// header when the column isn't in the table
+func (b diagramBox) rowCenter(columnID string) float64 {
+	for i, column := range b.columns {
+		if column.ID == columnID {
+			return b.y + diagramHeaderHeight + diagramRowHeight*float64(i) + diagramRowHeight/2
+		}
+	}
+	return b.y + diagramHeaderHeight/2
+}
+
+// Export returns the SVG document for the schema tables and foreign keys
+func (e SVGExporter) Export(schemaData models.SchemaData) string {
+	foreignKeyColumns := make(map[string]bool)
+	for _, fk := range schemaData.ForeignKeys {
+		for _, id := range fk.SourceColumns() {
+			foreignKeyColumns[id] = true
+		}
+	}
+
+	boxes := layoutDiagram(schemaData.Tables, foreignKeyColumns)
+
+	minX, minY, maxX, maxY := 0.0, 0.0, 0.0, 0.0
+	for i, box := range boxes {
+		if i == 0 {
+			minX, minY, maxX, maxY = box.x, box.y, box.x+box.width, box.y+box.height
+			continue
+		}
+		minX, minY = math.Min(minX, box.x), math.Min(minY, box.y)
+		maxX, maxY = math.Max(maxX, box.x+box.width), math.Max(maxY, box.y+box.height)
+	}
+	minX, minY = minX-diagramMargin, minY-diagramMargin
+	width, height := maxX-minX+diagramMargin, maxY-minY+diagramMargin
+
+	var out strings.Builder
+	fmt.Fprintf(&out, `<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="%s %s %s %s" font-family="monospace" font-size="12">`+"\n",
+		svgNumber(width), svgNumber(height), svgNumber(minX), svgNumber(minY), svgNumber(width), svgNumber(height))
+	out.WriteString(`<defs><marker id="arrow" viewBox="0 0 10 10" refX="10" refY="5" markerWidth="8" markerHeight="8" orient="auto-start-reverse"><path d="M 0 0 L 10 5 L 0 10 z" fill="#555"/></marker></defs>` + "\n")
+	fmt.Fprintf(&out, `<rect x="%s" y="%s" width="%s" height="%s" fill="#ffffff"/>`+"\n",
+		svgNumber(minX), svgNumber(minY), svgNumber(width), svgNumber(height))
+
+	// Lines go first so tables are drawn over them
+	boxByTable := make(map[string]diagramBox)
+	for _, box := range boxes {
+		boxByTable[box.table.ID] = box
+	}
+	for _, fk := range schemaData.ForeignKeys {
+		source, sourceExists := boxByTable[fk.SourceTableId]
+		target, targetExists := boxByTable[fk.TargetTableId]
+		if !sourceExists || !targetExists {
+			continue
+		}
+		writeDiagramRelationship(&out, fk, source, target)
+	}
+
+	for _, box := range boxes {
+		writeDiagramTable(&out, box, foreignKeyColumns)
+	}
+
+	out.WriteString("</svg>\n")
+	return out.String()
+}
+
+// layoutDiagram sizes every table and places it at its stored position, or
+// on a grid below the positioned tables when it has none
+func layoutDiagram(tables []models.Table, foreignKeyColumns map[string]bool) []diagramBox {
+	boxes := make([]diagramBox, 0, len(tables))
+	var unpositioned []int
+	gridTop := 0.0
+	for _, table := range tables {
+		box := diagramBox{table: table, columns: orderedColumns(table.Columns)}
+		box.width = math.Max(diagramMinWidth, float64(len(table.Name))*diagramCharWidth+2*diagramPadding)
+		for _, column := range box.columns {
+			label := column.Name + "  " + diagramColumnType(column, foreignKeyColumns)
+			box.width = math.Max(box.width, float64(len(label))*diagramCharWidth+2*diagramPadding)
+		}
+		box.height = diagramHeaderHeight + diagramRowHeight*float64(len(box.columns))
+
+		if hasPosition(table) {
+			box.x, box.y = table.Position.X, table.Position.Y
+			gridTop = math.Max(gridTop, box.y+box.height+diagramGridGap)
+		} else {
+			unpositioned = append(unpositioned, len(boxes))
+		}
+		boxes = append(boxes, box)
+	}
+
+	// Each grid row is as tall as its tallest table
+	cellWidth := 0.0
+	for _, i := range unpositioned {
+		cellWidth = math.Max(cellWidth, boxes[i].width)
+	}
+	rowTop, rowHeight := gridTop, 0.0
+	for n, i := range unpositioned {
+		if n > 0 && n%diagramGridColumns == 0 {
+			rowTop += rowHeight + diagramGridGap
+			rowHeight = 0
+		}
+		boxes[i].x = float64(n%diagramGridColumns) * (cellWidth + diagramGridGap)
+		boxes[i].y = rowTop
+		rowHeight = math.Max(rowHeight, boxes[i].height)
+	}
+
+	return boxes
+}
+
+// hasPosition reports whether a table was placed in the editor. The zero
+// position is what tables without one decode to.
+func hasPosition(table models.Table) bool {
+	return table.Position.X != 0 || table.Position.Y != 0
+}
+
+// diagramColumnType returns the data type shown for a column, marking key
+// columns
+func diagramColumnType(column models.Column, foreignKeyColumns map[string]bool) string {
+	label := column.DataType
+	if column.IsArray {
+		label += "[]"
+	}
+	if column.PrimaryKey {
+		label += " PK"
+	}
+	if foreignKeyColumns[column.ID] {
+		label += " FK"
+	}
+	return label
+}
+
+// writeDiagramTable draws a table as a header and one row per column
+func writeDiagramTable(out *strings.Builder, box diagramBox, foreignKeyColumns map[string]bool) {
+	fmt.Fprintf(out, `<g id="table-%s">`+"\n", html.EscapeString(box.table.ID))
+	fmt.Fprintf(out, `<rect x="%s" y="%s" width="%s" height="%s" fill="#ffffff" stroke="#333" rx="4"/>`+"\n",
+		svgNumber(box.x), svgNumber(box.y), svgNumber(box.width), svgNumber(box.height))
+	fmt.Fprintf(out, `<rect x="%s" y="%s" width="%s" height="%s" fill="#e8eef7" stroke="#333" rx="4"/>`+"\n",
+		svgNumber(box.x), svgNumber(box.y), svgNumber(box.width), svgNumber(diagramHeaderHeight))
+	fmt.Fprintf(out, `<text x="%s" y="%s" font-weight="bold">%s</text>`+"\n",
+		svgNumber(box.x+diagramPadding), svgNumber(box.y+diagramHeaderHeight/2+4), html.EscapeString(box.table.Name))
+
+	for _, column := range box.columns {
+		y := box.rowCenter(column.ID) + 4
+		weight := ""
+		if column.PrimaryKey {
+			weight = ` font-weight="bold"`
+		}
+		fmt.Fprintf(out, `<text x="%s" y="%s"%s>%s</text>`+"\n",
+			svgNumber(box.x+diagramPadding), svgNumber(y), weight, html.EscapeString(column.Name))
+
+		fmt.Fprintf(out, `<text x="%s" y="%s" text-anchor="end" fill="#666">%s</text>`+"\n",
+			svgNumber(box.x+box.width-diagramPadding), svgNumber(y), html.EscapeString(diagramColumnType(column, foreignKeyColumns)))
+	}
+
+	out.WriteString("</g>\n")
+}
+
+// writeDiagramRelationship draws a foreign key as an arrow from the row of
+// its first source column to the row of its first target column
+func writeDiagramRelationship(out *strings.Builder, fk models.ForeignKey, source, target diagramBox) {
+	var sourceColumn, targetColumn string
+	if columns := fk.SourceColumns(); len(columns) > 0 {
+		sourceColumn = columns[0]
+	}
+	if columns := fk.TargetColumns(); len(columns) > 0 {
+		targetColumn = columns[0]
+	}
+
+	// Leave from the side facing the target; self references loop out right
+	x1, x2 := source.x+source.width, target.x
+	if target.x+target.width/2 < source.x+source.width/2 {
+		x1, x2 = source.x, target.x+target.width
+	}
+	if source.table.ID == target.table.ID {
+		x1, x2 = source.x+source.width, source.x+source.width
+	}
+	y1, y2 := source.rowCenter(sourceColumn), target.rowCenter(targetColumn)
+
+	var path string
+	if x1 == x2 {
+		loop := x1 + diagramGridGap/2
+		path = fmt.Sprintf("M %s %s H %s V %s H %s", svgNumber(x1), svgNumber(y1), svgNumber(loop), svgNumber(y2), svgNumber(x2))
+	} else {
+		middle := (x1 + x2) / 2
+		path = fmt.Sprintf("M %s %s H %s V %s H %s", svgNumber(x1), svgNumber(y1), svgNumber(middle), svgNumber(y2), svgNumber(x2))
+	}
+
+	fmt.Fprintf(out, `<path id="fk-%s" d="%s" fill="none" stroke="#555" marker-end="url(#arrow)"/>`+"\n",
+		html.EscapeString(fk.ID), path)
+}
+
+// svgNumber formats a coordinate without trailing zeros
+func svgNumber(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
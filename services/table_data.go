@@ -0,0 +1,304 @@
+package services
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"vdt-dashboard-backend/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm/logger"
+)
+
+// csvTimeLayouts and csvTimestampLayouts are the formats accepted for TIME
+// and TIMESTAMP values in uploaded CSV files
+var (
+	csvTimeLayouts      = []string{"15:04:05.999999999", "15:04"}
+	csvTimestampLayouts = []string{time.RFC3339Nano, "2006-01-02 15:04:05.999999999", "2006-01-02T15:04:05.999999999", "2006-01-02 15:04", "2006-01-02"}
+)
+
+// ImportTableData loads the rows of a CSV file into a table of the schema's
+// database. The header row names the columns, by their name in the definition
+// or their generated name, and every value is converted to its column's data
+// type. Rows that can't be converted or inserted are reported and skipped;
+// the other rows are kept.
+func (s *schemaService) ImportTableData(id, userID uuid.UUID, tableRef string, data []byte) (*models.TableDataImportResponse, error) {
+	schema, err := s.GetEditableSchema(id, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	var table *models.Table
+	for i, candidate := range schema.SchemaDefinition.Tables {
+		if candidate.ID == tableRef || candidate.Name == tableRef {
+			table = &schema.SchemaDefinition.Tables[i]
+			break
+		}
+	}
+	if table == nil {
+		return nil, fmt.Errorf("%w: table %s in schema %s", ErrNotFound, tableRef, id)
+	}
+	physical := convertTableIdentifiers(*table, identifierCase(schema.SchemaDefinition.IdentifierCase, s.config))
+
+	// The whole file is parsed before anything is inserted, so a malformed
+	// file leaves the table untouched
+	reader := csv.NewReader(bytes.NewReader(data))
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: CSV file is empty", ErrValidation)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid CSV header: %v", ErrValidation, err)
+	}
+	header[0] = strings.TrimPrefix(header[0], "\ufeff")
+
+	columns, err := csvColumns(*table, physical, header)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.TableDataImportResponse{
+		SchemaID:  schema.ID,
+		TableID:   table.ID,
+		TableName: table.Name,
+		Errors:    []models.TableDataRowError{},
+	}
+
+	var rows [][]any
+	var rowNumbers []int
+	for row := 1; ; row++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if errors.Is(err, csv.ErrFieldCount) {
+			response.Errors = append(response.Errors, models.TableDataRowError{
+				Row:     row,
+				Message: fmt.Sprintf("expected %d fields, got %d", len(header), len(record)),
+			})
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid CSV: %v", ErrValidation, err)
+		}
+
+		values, err := coerceCSVRecord(*table, columns, record)
+		if err != nil {
+			response.Errors = append(response.Errors, models.TableDataRowError{Row: row, Message: err.Error()})
+			continue
+		}
+		rows = append(rows, values)
+		rowNumbers = append(rowNumbers, row)
+	}
+
+	unlock, err := s.databaseManager.LockGeneration(schema.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+
+	names := make([]string, len(columns))
+	for i, column := range columns {
+		names[i] = physical.Columns[column].Name
+	}
+	rowErrors, err := s.databaseManager.InsertRows(schema.DatabaseName, physical.Name, names, rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert rows: %w", err)
+	}
+	for i, rowErr := range rowErrors {
+		if rowErr != nil {
+			response.Errors = append(response.Errors, models.TableDataRowError{Row: rowNumbers[i], Message: rowErr.Error()})
+			continue
+		}
+		response.RowsInserted++
+	}
+	sort.Slice(response.Errors, func(i, j int) bool {
+		return response.Errors[i].Row < response.Errors[j].Row
+	})
+
+	return response, nil
+}
+
+// csvColumns maps the fields of a CSV header to the indexes of the table
+// columns they name, rejecting names that aren't columns of the table
+func csvColumns(table, physical models.Table, header []string) ([]int, error) {
+	columns := make([]int, len(header))
+	seen := make(map[int]bool)
+	var unknown []string
+	for i, field := range header {
+		name := strings.TrimSpace(field)
+		columns[i] = -1
+		for j, column := range table.Columns {
+			if column.Name == name || physical.Columns[j].Name == name {
+				columns[i] = j
+				break
+			}
+		}
+		if columns[i] == -1 {
+			unknown = append(unknown, fmt.Sprintf("'%s'", name))
+			continue
+		}
+		if seen[columns[i]] {
+			return nil, fmt.Errorf("%w: column '%s' appears more than once in the CSV header", ErrValidation, name)
+		}
+		seen[columns[i]] = true
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: columns %s are not in table '%s'", ErrValidation, strings.Join(unknown, ", "), table.Name)
+	}
+	return columns, nil
+}
+
+// coerceCSVRecord converts the fields of a CSV record to the data types of
+// the columns they belong to
+func coerceCSVRecord(table models.Table, columns []int, record []string) ([]any, error) {
+	values := make([]any, len(record))
+	for i, field := range record {
+		column := table.Columns[columns[i]]
+		value, err := coerceCSVValue(column, field)
+		if err != nil {
+			return nil, fmt.Errorf("column '%s': %v", column.Name, err)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
+
+// coerceCSVValue converts a CSV field to the data type of column. Empty
+// fields are NULL, except in VARCHAR and TEXT columns where they are empty
+// strings. Array values are PostgreSQL array literals, checked by the
+// database on insert.
+func coerceCSVValue(column models.Column, field string) (any, error) {
+	dataType := strings.ToUpper(column.DataType)
+	if field == "" && dataType != "VARCHAR" && dataType != "TEXT" {
+		return nil, nil
+	}
+	if column.IsArray {
+		return field, nil
+	}
+
+	value := strings.TrimSpace(field)
+	switch dataType {
+	case "INT", "BIGINT":
+		bitSize := 64
+		if dataType == "INT" {
+			bitSize = 32
+		}
+		parsed, err := strconv.ParseInt(value, 10, bitSize)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a valid %s", field, dataType)
+		}
+		return parsed, nil
+	case "FLOAT", "DOUBLE":
+		bitSize := 64
+		if dataType == "FLOAT" {
+			bitSize = 32
+		}
+		parsed, err := strconv.ParseFloat(value, bitSize)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a valid %s", field, dataType)
+		}
+		return parsed, nil
+	case "DECIMAL":
+		// Passed on as text so no precision is lost
+		if _, ok := new(big.Rat).SetString(value); !ok {
+			return nil, fmt.Errorf("'%s' is not a valid DECIMAL", field)
+		}
+		return value, nil
+	case "BOOLEAN":
+		parsed, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a valid BOOLEAN", field)
+		}
+		return parsed, nil
+	case "DATE":
+		if _, err := time.Parse(time.DateOnly, value); err != nil {
+			return nil, fmt.Errorf("'%s' is not a valid DATE, expected YYYY-MM-DD", field)
+		}
+		return value, nil
+	case "TIME":
+		for _, layout := range csvTimeLayouts {
+			if _, err := time.Parse(layout, value); err == nil {
+				return value, nil
+			}
+		}
+		return nil, fmt.Errorf("'%s' is not a valid TIME, expected HH:MM[:SS]", field)
+	case "TIMESTAMP":
+		for _, layout := range csvTimestampLayouts {
+			if parsed, err := time.Parse(layout, value); err == nil {
+				return parsed.UTC(), nil
+			}
+		}
+		return nil, fmt.Errorf("'%s' is not a valid TIMESTAMP, expected RFC 3339 or YYYY-MM-DD HH:MM:SS", field)
+	case "UUID":
+		parsed, err := uuid.Parse(value)
+		if err != nil {
+			return nil, fmt.Errorf("'%s' is not a valid UUID", field)
+		}
+		return parsed.String(), nil
+	case "JSON":
+		if !json.Valid([]byte(field)) {
+			return nil, fmt.Errorf("'%s' is not valid JSON", field)
+		}
+		return field, nil
+	case "VARCHAR":
+		if column.Length != nil && utf8.RuneCountInString(field) > *column.Length {
+			return nil, fmt.Errorf("value is longer than %d characters", *column.Length)
+		}
+		return field, nil
+	default:
+		return field, nil
+	}
+}
+
+// InsertRows inserts rows into a table of a generated database using batched
+// multi-row INSERT statements. A failed batch is retried one row at a time,
+// so the returned slice holds the error of each row that couldn't be
+// inserted, and nil for each row that was.
+func (d *databaseManagerService) InsertRows(databaseName, table string, columns []string, rows [][]any) ([]error, error) {
+	db, release, err := d.openDatabase(databaseName, logger.Silent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer release()
+
+	quoted := make([]string, len(columns))
+	placeholders := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = quoteIdentifier(column)
+		placeholders[i] = "?"
+	}
+	insertPrefix := fmt.Sprintf("INSERT INTO %s (%s) VALUES ", quoteIdentifier(table), strings.Join(quoted, ", "))
+	rowPlaceholders := "(" + strings.Join(placeholders, ", ") + ")"
+
+	rowErrors := make([]error, len(rows))
+	for start := 0; start < len(rows); start += dataDumpBatchSize {
+		batch := rows[start:min(start+dataDumpBatchSize, len(rows))]
+
+		values := make([]string, len(batch))
+		var args []any
+		for i, row := range batch {
+			values[i] = rowPlaceholders
+			args = append(args, row...)
+		}
+		if err := db.Exec(insertPrefix+strings.Join(values, ", "), args...).Error; err == nil {
+			continue
+		}
+
+		for i, row := range batch {
+			rowErrors[start+i] = db.Exec(insertPrefix+rowPlaceholders, row...).Error
+		}
+	}
+
+	return rowErrors, nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"vdt-dashboard-backend/models"
+)
+
+// TypeScript representations of TIMESTAMP columns
+const (
+	TimestampAsString = "string"
+	TimestampAsDate   = "date"
+)
+
+// typeScriptIdentifier matches names usable as unquoted property names
+var typeScriptIdentifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+// TypeScriptExporter renders a schema definition as one TypeScript interface
+// per table. Output depends only on the schema data and options.
+type TypeScriptExporter struct {
+	// TimestampType is TimestampAsString (default) or TimestampAsDate
+	TimestampType string
+}
+
+// Export returns the TypeScript source for the schema tables, ordered by
+// interface name
+func (e TypeScriptExporter) Export(schemaData models.SchemaData) string {
+	foreignKeys := foreignKeyComments(schemaData)
+
+	tables := make([]models.Table, len(schemaData.Tables))
+	copy(tables, schemaData.Tables)
+	sort.SliceStable(tables, func(i, j int) bool {
+		return pascalCase(tables[i].Name) < pascalCase(tables[j].Name)
+	})
+
+	var out strings.Builder
+	out.WriteString("// Generated from schema definition. Do not edit by hand.\n")
+
+	for _, table := range tables {
+		fmt.Fprintf(&out, "\nexport interface %s {\n", pascalCase(table.Name))
+
+		for _, column := range orderedColumns(table.Columns) {
+			name := column.Name
+			if !typeScriptIdentifier.MatchString(name) {
+				name = fmt.Sprintf("%q", name)
+			}
+			if column.IsNullable() {
+				name += "?"
+			}
+
+			fmt.Fprintf(&out, "  %s: %s;", name, e.fieldType(column))
+			if comment, exists := foreignKeys[column.ID]; exists {
+				out.WriteString(" // FK -> " + comment)
+			}
+			out.WriteString("\n")
+		}
+
+		out.WriteString("}\n")
+	}
+
+	return out.String()
+}
+
+// fieldType maps a column data type to a TypeScript type
+func (e TypeScriptExporter) fieldType(column models.Column) string {
+	if column.IsArray {
+		element := column
+		element.IsArray = false
+		return e.fieldType(element) + "[]"
+	}
+
+	switch column.DataType {
+	case "INT", "BIGINT", "DECIMAL", "FLOAT", "DOUBLE":
+		return "number"
+	case "VARCHAR", "TEXT", "UUID", "DATE", "TIME":
+		return "string"
+	case "BOOLEAN":
+		return "boolean"
+	case "TIMESTAMP":
+		if e.TimestampType == TimestampAsDate {
+			return "Date"
+		}
+		return "string"
+	default:
+		return "unknown"
+	}
+}
+
+// foreignKeyComments maps source column IDs to their referenced "table.column"
+func foreignKeyComments(schemaData models.SchemaData) map[string]string {
+	tableNames := make(map[string]string)
+	columnNames := make(map[string]string)
+	for _, table := range schemaData.Tables {
+		tableNames[table.ID] = table.Name
+		for _, column := range table.Columns {
+			columnNames[column.ID] = column.Name
+		}
+	}
+
+	comments := make(map[string]string)
+	for _, fk := range schemaData.ForeignKeys {
+		sourceIDs, targetIDs := fk.SourceColumns(), fk.TargetColumns()
+		if len(sourceIDs) != len(targetIDs) {
+			continue
+		}
+		// Each column of a composite key points at its paired target column
+		for i, sourceID := range sourceIDs {
+			target := tableNames[fk.TargetTableId] + "." + columnNames[targetIDs[i]]
+			if existing, exists := comments[sourceID]; exists {
+				target = existing + ", " + target
+			}
+			comments[sourceID] = target
+		}
+	}
+	return comments
+}
+
+// pascalCase converts a table name such as "order_items" to "OrderItems"
+func pascalCase(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9')
+	})
+
+	var out strings.Builder
+	for _, part := range parts {
+		out.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+
+	result := out.String()
+	if result == "" || (result[0] >= '0' && result[0] <= '9') {
+		result = "Table" + result
+	}
+	return result
+}